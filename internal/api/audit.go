@@ -0,0 +1,314 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/cederikdotcom/hydraapi"
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+)
+
+// maxAuditBody is the largest JSON request body copied into the audit log.
+const maxAuditBody = 64 << 10
+
+// auditRedactedFields are request body fields holding credentials; their
+// values are replaced before a body is written to the audit log.
+var auditRedactedFields = map[string]bool{
+	"secret":        true,
+	"client_secret": true,
+	"token":         true,
+	"password":      true,
+	"private_key":   true,
+}
+
+// auditURLFields are request body fields holding URLs that may embed a
+// credential, like Slack and Discord webhook paths; only their scheme and
+// host are written to the audit log.
+var auditURLFields = map[string]bool{
+	"url": true,
+}
+
+// auditRecord lets handlers deep in the chain report the authenticated
+// caller back to the audit middleware, which only sees the outer request.
+type auditRecord struct {
+	id *identity
+}
+
+type auditKey struct{}
+
+// withIdentity attaches the caller to the request and to its audit record.
+func withIdentity(r *http.Request, id *identity) *http.Request {
+	if rec, ok := r.Context().Value(auditKey{}).(*auditRecord); ok {
+		rec.id = id
+	}
+	return r.WithContext(context.WithValue(r.Context(), identityKey{}, id))
+}
+
+// requireAdmin allows only the admin token and records it as the caller.
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return s.Auth.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+		next(w, withIdentity(r, &identity{Name: "admin"}))
+	})
+}
+
+// auditRecorder captures the status and, for failures, the error message
+// of a response.
+type auditRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *auditRecorder) WriteHeader(code int) {
+	if rw.status == 0 {
+		rw.status = code
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *auditRecorder) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	if rw.status >= 400 && rw.body.Len() < 4096 {
+		rw.body.Write(b)
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+func (rw *auditRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// auditWrites records every request to a write endpoint in the audit log.
+// Reads are not recorded.
+func (s *Server) auditWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Audit == nil || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var body []byte
+		if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct == "application/json" && r.Body != nil {
+			body, _ = io.ReadAll(io.LimitReader(r.Body, maxAuditBody+1))
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+			if len(body) > maxAuditBody || !json.Valid(body) {
+				body = nil
+			}
+		}
+
+		rec := &auditRecord{}
+		r = r.WithContext(context.WithValue(r.Context(), auditKey{}, rec))
+		rw := &auditRecorder{ResponseWriter: w}
+		start := time.Now()
+		next.ServeHTTP(rw, r)
+
+		e := &store.AuditEntry{
+			Time:    start,
+			Actor:   "anonymous",
+			Action:  r.Pattern,
+			Path:    r.URL.Path,
+			Status:  rw.status,
+			Outcome: "ok",
+		}
+		if e.Status == 0 {
+			e.Status = http.StatusOK
+		}
+		if e.Action == "" {
+			e.Action = r.Method + " (unmatched)"
+		}
+		if rec.id != nil {
+			e.Actor = rec.id.Name
+			if rec.id.Token != nil {
+				e.TokenID = rec.id.Token.ID
+			}
+		}
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			e.SourceIP = host
+		}
+		if body != nil {
+			e.Request = redactCredentials(body)
+		}
+		e.Project, e.Environment = auditTarget(r, body)
+		if e.Status >= 400 {
+			e.Outcome = "error"
+			var apiErr struct {
+				Error string `json:"error"`
+			}
+			if json.Unmarshal(rw.body.Bytes(), &apiErr) == nil {
+				e.Error = apiErr.Error
+			}
+			if e.Error == "" {
+				e.Error = http.StatusText(e.Status)
+			}
+		}
+
+		if err := s.Audit.Append(e); err != nil {
+			log.Printf("[audit] failed to record %s %s: %v", r.Method, r.URL.Path, err)
+		}
+	})
+}
+
+// redactCredentials returns body with the values of auditRedactedFields,
+// at any depth, replaced by "[redacted]" and those of auditURLFields cut
+// down to scheme and host. Bodies without such fields are returned
+// unchanged.
+func redactCredentials(body []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || !redactValue(v) {
+		return body
+	}
+	redacted, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return redacted
+}
+
+func redactValue(v any) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if auditRedactedFields[k] {
+				v[k] = "[redacted]"
+				changed = true
+			} else if s, ok := child.(string); ok && auditURLFields[k] {
+				if origin := urlOrigin(s); origin != s {
+					v[k] = origin
+					changed = true
+				}
+			} else if redactValue(child) {
+				changed = true
+			}
+		}
+	case []any:
+		for _, child := range v {
+			if redactValue(child) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// urlOrigin returns the scheme and host of raw, or "[redacted]" if it is not
+// an absolute URL.
+func urlOrigin(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "[redacted]"
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// auditTarget finds the project and environment a write acted on, from the
+// route or the JSON body.
+func auditTarget(r *http.Request, body []byte) (project, env string) {
+	project = r.PathValue("project")
+	env = r.PathValue("env")
+	if env == "" {
+		env = r.PathValue("channel")
+	}
+	if body != nil && (project == "" || env == "") {
+		var fields struct {
+			Project     string `json:"project"`
+			Environment string `json:"environment"`
+		}
+		json.Unmarshal(body, &fields)
+		if project == "" {
+			project = fields.Project
+		}
+		if env == "" {
+			env = fields.Environment
+		}
+	}
+	return project, env
+}
+
+// handleListAudit serves audit entries, oldest first. With format=jsonl the
+// entries are streamed one per line, as stored.
+func (s *Server) handleListAudit(w http.ResponseWriter, r *http.Request) {
+	if s.Audit == nil {
+		hydraapi.WriteError(w, http.StatusServiceUnavailable, "audit log not configured")
+		return
+	}
+
+	q := r.URL.Query()
+	filter := store.AuditFilter{
+		Actor:   q.Get("actor"),
+		Project: q.Get("project"),
+		Action:  q.Get("action"),
+		Outcome: q.Get("outcome"),
+		Limit:   100,
+	}
+	for name, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := q.Get(name); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid %s: %q (want RFC3339)", name, v))
+				return
+			}
+			*t = parsed
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit: %q", v))
+			return
+		}
+		filter.Limit = n
+	}
+
+	entries, err := s.Audit.Query(filter)
+	if err != nil {
+		log.Printf("[audit] query failed: %v", err)
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to read audit log")
+		return
+	}
+
+	if q.Get("format") == "jsonl" {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		for i := range entries {
+			enc.Encode(&entries[i])
+		}
+		return
+	}
+	if entries == nil {
+		entries = []store.AuditEntry{}
+	}
+	hydraapi.WriteJSON(w, http.StatusOK, entries)
+}
+
+// handleVerifyAudit checks the hash chain of the whole audit log.
+func (s *Server) handleVerifyAudit(w http.ResponseWriter, r *http.Request) {
+	if s.Audit == nil {
+		hydraapi.WriteError(w, http.StatusServiceUnavailable, "audit log not configured")
+		return
+	}
+
+	n, err := s.Audit.Verify()
+	result := map[string]any{"entries": n, "valid": err == nil}
+	if err != nil {
+		result["error"] = err.Error()
+	}
+	hydraapi.WriteJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/cederikdotcom/hydraapi"
+	"github.com/cederikdotcom/hydrarelease/internal/oidc"
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+)
+
+// identity is the authenticated caller of a request: the admin token handled
+// by hydraauth, a scoped API token from the token store, or a CI job's OIDC
+// token whose claims were mapped to scopes.
+type identity struct {
+	Name       string
+	Token      *store.APIToken   // nil for the admin token
+	Provenance map[string]string // verified OIDC claims recorded with builds
+}
+
+type identityKey struct{}
+
+// requestIdentity returns the caller attached by authenticate, or nil.
+func requestIdentity(r *http.Request) *identity {
+	id, _ := r.Context().Value(identityKey{}).(*identity)
+	return id
+}
+
+// authenticate accepts a scoped API token, an OIDC token or the admin token
+// and attaches the caller's identity to the request. Handlers must still
+// call authorize.
+func (s *Server) authenticate(next http.HandlerFunc) http.HandlerFunc {
+	admin := s.requireAdmin(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if ok && s.OIDC != nil && oidc.LooksLikeJWT(bearer) {
+			s.authenticateOIDC(w, r, bearer, next)
+			return
+		}
+		if !ok || s.Tokens == nil || !strings.HasPrefix(bearer, store.TokenPrefix) {
+			admin(w, r)
+			return
+		}
+		tok, err := s.Tokens.Authenticate(bearer)
+		if err != nil {
+			log.Printf("[auth] token lookup failed: %v", err)
+			hydraapi.WriteError(w, http.StatusInternalServerError, "failed to verify token")
+			return
+		}
+		if tok == nil {
+			hydraapi.WriteError(w, http.StatusUnauthorized, "invalid, expired or revoked token")
+			return
+		}
+		next(w, withIdentity(r, &identity{Name: "token:" + tok.Name, Token: tok}))
+	}
+}
+
+// authenticateOIDC verifies a CI job's ID token and grants it the scopes of
+// the matching OIDC rules.
+func (s *Server) authenticateOIDC(w http.ResponseWriter, r *http.Request, bearer string, next http.HandlerFunc) {
+	claims, err := s.OIDC.Verify(bearer)
+	if err != nil {
+		log.Printf("[auth] rejected OIDC token: %v", err)
+		hydraapi.WriteError(w, http.StatusUnauthorized, "invalid OIDC token: "+err.Error())
+		return
+	}
+	scopes := s.OIDC.Config.Scopes(claims)
+	if len(scopes) == 0 {
+		hydraapi.WriteError(w, http.StatusForbidden, fmt.Sprintf("no OIDC rule grants %s (ref %s) any access", claims.Repository, claims.Ref))
+		return
+	}
+	name := "oidc:" + claims.Repository
+	id := &identity{
+		Name:       name,
+		Token:      &store.APIToken{Name: name, Scopes: scopes},
+		Provenance: claims.Provenance(),
+	}
+	next(w, withIdentity(r, id))
+}
+
+// authorize checks that the caller may perform action on project/env and
+// writes a 403 if not. The admin token may do everything.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, action, project, env string) bool {
+	id := requestIdentity(r)
+	if id == nil {
+		hydraapi.WriteError(w, http.StatusUnauthorized, "authentication required")
+		return false
+	}
+	if id.Token == nil || id.Token.Allows(action, project, env) {
+		return true
+	}
+	target := project
+	if target == "" {
+		target = "any project"
+	}
+	if env != "" {
+		target += "/" + env
+	}
+	hydraapi.WriteError(w, http.StatusForbidden, fmt.Sprintf("token %s may not %s on %s", id.Token.Name, action, target))
+	return false
+}
+
+// requireAction authenticates and authorizes an action whose target is fixed
+// by the route, e.g. streaming events for all projects.
+func (s *Server) requireAction(action, project string, next http.HandlerFunc) http.HandlerFunc {
+	return s.authenticate(func(w http.ResponseWriter, r *http.Request) {
+		if s.authorize(w, r, action, project, "") {
+			next(w, r)
+		}
+	})
+}
+
+// actor names the caller for uploaded_by, released_by and similar fields.
+// Scoped and OIDC tokens are always recorded by name; only the admin token may still
+// label its actions through the request body.
+func actor(r *http.Request, claimed string) string {
+	id := requestIdentity(r)
+	if id == nil {
+		return claimed
+	}
+	if id.Token == nil && claimed != "" {
+		return claimed
+	}
+	return id.Name
+}
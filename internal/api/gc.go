@@ -0,0 +1,225 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cederikdotcom/hydraapi"
+	"github.com/cederikdotcom/hydramonitor"
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+)
+
+// gcResult reports the outcome of a garbage collection run.
+type gcResult struct {
+	DryRun           bool                    `json:"dry_run"`
+	Deleted          []store.BuildIndexEntry `json:"deleted"`
+	DeletedArtifacts []store.Artifact        `json:"deleted_artifacts"`
+	Failed           []string                `json:"failed,omitempty"`
+}
+
+// collectGarbage deletes builds that fall outside their project's retention
+// policy, then the artifacts no remaining build references. If project is
+// non-empty only that project's builds are considered; artifacts are always
+// checked against every build. With dryRun set, candidates are reported but
+// nothing is removed.
+func (s *Server) collectGarbage(project string, dryRun bool) (*gcResult, error) {
+	s.gcMu.Lock()
+	defer s.gcMu.Unlock()
+
+	cfg, err := s.Retention.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	projects := []string{project}
+	if project == "" {
+		projects, err = s.Builds.Projects()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &gcResult{DryRun: dryRun, Deleted: []store.BuildIndexEntry{}, DeletedArtifacts: []store.Artifact{}}
+	now := time.Now().UTC()
+
+	for _, p := range projects {
+		policy := cfg.PolicyFor(p)
+		if reg, err := s.Projects.Get(p); err != nil {
+			return nil, err
+		} else if reg != nil && reg.Retention != nil {
+			policy = *reg.Retention
+		}
+		if policy.IsZero() {
+			continue
+		}
+
+		builds, err := s.Builds.List(p)
+		if err != nil {
+			return nil, err
+		}
+		history, err := s.Releases.List(p)
+		if err != nil {
+			return nil, err
+		}
+		released := make(map[int]bool)
+		for _, r := range history {
+			released[r.BuildNumber] = true
+		}
+
+		for _, e := range store.ExpiredBuilds(builds, released, policy, now) {
+			if dryRun {
+				result.Deleted = append(result.Deleted, e)
+				continue
+			}
+			if err := s.deleteBuild(e.Project, e.BuildNumber); err != nil {
+				log.Printf("[gc] failed to delete %s/%d: %v", e.Project, e.BuildNumber, err)
+				result.Failed = append(result.Failed, fmt.Sprintf("%s/%d: %v", e.Project, e.BuildNumber, err))
+				continue
+			}
+			log.Printf("[gc] deleted build %s/%d", e.Project, e.BuildNumber)
+			result.Deleted = append(result.Deleted, e)
+		}
+	}
+
+	if err := s.collectArtifacts(result, now); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// collectArtifacts deletes content-addressed artifacts that no build
+// references any more, first from storage and then from the artifact index,
+// so a failed deletion is retried on the next run. In a dry run the builds
+// that would have been deleted count as gone.
+func (s *Server) collectArtifacts(result *gcResult, now time.Time) error {
+	if s.Artifacts == nil || s.Storage == nil {
+		return nil
+	}
+
+	gone := make(map[string]bool)
+	if result.DryRun {
+		for _, e := range result.Deleted {
+			gone[buildKey(e.Project, e.BuildNumber)] = true
+		}
+	}
+	projects, err := s.Builds.Projects()
+	if err != nil {
+		return err
+	}
+	referenced := make(map[string]bool)
+	for _, p := range projects {
+		builds, err := s.Builds.List(p)
+		if err != nil {
+			return err
+		}
+		for _, e := range builds {
+			if gone[buildKey(e.Project, e.BuildNumber)] {
+				continue
+			}
+			build, err := s.Builds.Get(e.Project, e.BuildNumber)
+			if err != nil {
+				return err
+			}
+			for _, f := range build.Files {
+				if strings.HasPrefix(f.MirrorPath, store.ArtifactMirrorPrefix) {
+					referenced[strings.TrimPrefix(f.MirrorPath, store.ArtifactMirrorPrefix)] = true
+				}
+			}
+		}
+	}
+
+	artifacts, err := s.Artifacts.List()
+	if err != nil {
+		return err
+	}
+	for _, a := range store.UnreferencedArtifacts(artifacts, referenced, now) {
+		if result.DryRun {
+			result.DeletedArtifacts = append(result.DeletedArtifacts, a)
+			continue
+		}
+		if err := s.Storage.Delete(a.MirrorPath()); err != nil {
+			log.Printf("[gc] failed to delete artifact %s: %v", a.SHA256, err)
+			result.Failed = append(result.Failed, fmt.Sprintf("artifact %s: %v", a.SHA256, err))
+			continue
+		}
+		if err := s.Artifacts.Delete(a.SHA256); err != nil {
+			log.Printf("[gc] failed to forget artifact %s: %v", a.SHA256, err)
+			result.Failed = append(result.Failed, fmt.Sprintf("artifact %s: %v", a.SHA256, err))
+			continue
+		}
+		log.Printf("[gc] deleted artifact %s (%d bytes)", a.SHA256, a.Size)
+		result.DeletedArtifacts = append(result.DeletedArtifacts, a)
+	}
+	return nil
+}
+
+// deleteBuild removes a build's stored files and then its metadata.
+// Metadata is only removed once every stored file is gone, so a failed run
+// is retried on the next collection.
+func (s *Server) deleteBuild(project string, number int) error {
+	build, err := s.Builds.Get(project, number)
+	if err != nil {
+		return err
+	}
+
+	if s.Storage != nil {
+		for _, f := range build.Files {
+			if f.MirrorPath == "" {
+				continue
+			}
+			target := buildFilePath(build, f)
+			if err := s.Storage.Delete(target); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := s.Builds.Delete(project, number); err != nil {
+		return err
+	}
+
+	s.emit(hydramonitor.Event{
+		Type: "build.deleted",
+		Data: map[string]any{
+			"district":     "",
+			"timestamp":    time.Now().UTC().Format("2006-01-02T15:04:05Z07:00"),
+			"project":      project,
+			"build_number": number,
+		},
+	})
+	return nil
+}
+
+// StartGC runs garbage collection every interval in a background goroutine.
+func (s *Server) StartGC(interval time.Duration) {
+	go func() {
+		for {
+			time.Sleep(interval)
+
+			result, err := s.collectGarbage("", false)
+			if err != nil {
+				log.Printf("[gc] run failed: %v", err)
+				continue
+			}
+			if len(result.Deleted) > 0 || len(result.DeletedArtifacts) > 0 || len(result.Failed) > 0 {
+				log.Printf("[gc] deleted %d builds and %d artifacts (%d failed)", len(result.Deleted), len(result.DeletedArtifacts), len(result.Failed))
+			}
+		}
+	}()
+}
+
+func (s *Server) handleBuildGC(w http.ResponseWriter, r *http.Request) {
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	project := r.URL.Query().Get("project")
+
+	result, err := s.collectGarbage(project, dryRun)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	hydraapi.WriteJSON(w, http.StatusOK, result)
+}
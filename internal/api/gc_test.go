@@ -0,0 +1,67 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cederikdotcom/hydrarelease/internal/storage"
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+)
+
+func TestCollectArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "files")
+	s := &Server{
+		Builds:    store.NewYAMLBuildStore(dir),
+		Artifacts: store.NewArtifactStore(dir),
+		Storage:   storage.NewLocal(root),
+	}
+	for _, hash := range []string{"used", "orphan"} {
+		if err := s.Storage.Put(store.ArtifactMirrorPrefix+hash, strings.NewReader(hash), -1); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		if _, err := s.Artifacts.Add(hash, int64(len(hash))); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	if _, err := s.Builds.Create(store.CreateParams{Project: "app", Files: []store.BuildFile{
+		{Path: "app", SHA256: "used", MirrorPath: store.ArtifactMirrorPrefix + "used"},
+	}}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// Fresh uploads are kept while their build may still be on the way.
+	result := &gcResult{}
+	if err := s.collectArtifacts(result, time.Now()); err != nil || len(result.DeletedArtifacts) != 0 {
+		t.Fatalf("collect within grace = %+v, %v; want nothing deleted", result.DeletedArtifacts, err)
+	}
+
+	later := time.Now().Add(48 * time.Hour)
+	result = &gcResult{DryRun: true}
+	if err := s.collectArtifacts(result, later); err != nil || len(result.DeletedArtifacts) != 1 {
+		t.Fatalf("dry run = %+v, %v; want orphan", result.DeletedArtifacts, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "artifacts", "orphan")); err != nil {
+		t.Errorf("dry run removed the file: %v", err)
+	}
+
+	result = &gcResult{}
+	if err := s.collectArtifacts(result, later); err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	if len(result.DeletedArtifacts) != 1 || result.DeletedArtifacts[0].SHA256 != "orphan" {
+		t.Errorf("deleted = %+v, want orphan", result.DeletedArtifacts)
+	}
+	if _, err := os.Stat(filepath.Join(root, "artifacts", "orphan")); !os.IsNotExist(err) {
+		t.Errorf("orphaned artifact still stored: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "artifacts", "used")); err != nil {
+		t.Errorf("referenced artifact removed: %v", err)
+	}
+	if a, _ := s.Artifacts.Get("orphan"); a != nil {
+		t.Error("orphaned artifact still indexed")
+	}
+}
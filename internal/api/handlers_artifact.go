@@ -0,0 +1,167 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cederikdotcom/hydraapi"
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+)
+
+var sha256Re = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// handleHeadArtifact reports whether an artifact was already uploaded, so
+// clients can skip it when resuming. A hit counts as a use, keeping the
+// artifact out of garbage collection until the client's build arrives.
+func (s *Server) handleHeadArtifact(w http.ResponseWriter, r *http.Request) {
+	hash := r.PathValue("sha256")
+	if !sha256Re.MatchString(hash) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	a, err := s.Artifacts.Touch(hash)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if a == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleUploadArtifact streams an artifact to storage under its SHA256,
+// verifying the content matches the hash in the URL before recording it.
+func (s *Server) handleUploadArtifact(w http.ResponseWriter, r *http.Request) {
+	hash := r.PathValue("sha256")
+	if !sha256Re.MatchString(hash) {
+		hydraapi.WriteError(w, http.StatusBadRequest, "artifact must be addressed by lowercase hex sha256")
+		return
+	}
+	if s.Storage == nil {
+		hydraapi.WriteError(w, http.StatusServiceUnavailable, "storage not configured")
+		return
+	}
+
+	existing, err := s.Artifacts.Touch(hash)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to read artifact index")
+		return
+	}
+	if existing != nil {
+		hydraapi.WriteJSON(w, http.StatusOK, existing)
+		return
+	}
+
+	// Upload to a key of its own and only link it to the content-addressed
+	// path once verified, so a corrupt concurrent upload of the same hash
+	// cannot overwrite or delete a good one.
+	storagePath := store.ArtifactMirrorPrefix + hash
+	incoming, err := incomingArtifactPath(hash)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to allocate upload path")
+		return
+	}
+	defer func() {
+		if err := s.Storage.Delete(incoming); err != nil {
+			log.Printf("artifact: %v", err)
+		}
+	}()
+
+	hasher := sha256.New()
+	counter := &countingReader{r: io.TeeReader(r.Body, hasher)}
+
+	if err := s.Storage.Put(incoming, counter, r.ContentLength); err != nil {
+		log.Printf("artifact: %s PUT %s: %v", s.Storage.Name(), incoming, err)
+		hydraapi.WriteError(w, http.StatusBadGateway, "failed to store upload")
+		return
+	}
+	s.Metrics.countUpload("artifact", counter.n)
+
+	actual := hex.EncodeToString(hasher.Sum(nil))
+	if actual != hash {
+		log.Printf("artifact: hash mismatch for %s (got %s), discarding it", hash, actual)
+		hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("content hash %s does not match %s", actual, hash))
+		return
+	}
+
+	// A concurrent upload may have finished first; its copy is identical.
+	if existing, err := s.Artifacts.Get(hash); err == nil && existing != nil {
+		hydraapi.WriteJSON(w, http.StatusOK, existing)
+		return
+	}
+	if err := s.Storage.Link(incoming, []string{storagePath}); err != nil {
+		log.Printf("artifact: %s LINK %s: %v", s.Storage.Name(), storagePath, err)
+		hydraapi.WriteError(w, http.StatusBadGateway, "failed to store upload")
+		return
+	}
+
+	a, err := s.Artifacts.Add(hash, counter.n)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to record artifact")
+		return
+	}
+
+	log.Printf("artifact: uploaded %s (%d bytes)", hash, counter.n)
+	hydraapi.WriteJSON(w, http.StatusCreated, a)
+}
+
+// incomingArtifactPath returns a unique storage path for an artifact upload
+// that has not been verified yet.
+func incomingArtifactPath(hash string) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%sincoming/%s-%s", store.ArtifactMirrorPrefix, hash, hex.EncodeToString(b)), nil
+}
+
+// verifyBuildArtifacts checks that every file pointing at a content-addressed
+// artifact refers to one that was uploaded with matching hash and size, and
+// marks those artifacts used. Callers hold gcMu shared until the build is
+// stored so garbage collection can't remove a verified artifact first.
+func (s *Server) verifyBuildArtifacts(files []store.BuildFile) error {
+	for _, f := range files {
+		if !strings.HasPrefix(f.MirrorPath, store.ArtifactMirrorPrefix) {
+			continue
+		}
+		hash := strings.TrimPrefix(f.MirrorPath, store.ArtifactMirrorPrefix)
+		if f.SHA256 != hash {
+			return fmt.Errorf("%s: sha256 %q does not match mirror_path %s", f.Path, f.SHA256, f.MirrorPath)
+		}
+		a, err := s.Artifacts.Touch(hash)
+		if err != nil {
+			return err
+		}
+		if a == nil {
+			return fmt.Errorf("%s: artifact %s has not been uploaded", f.Path, hash)
+		}
+		if a.Size != f.Size {
+			return fmt.Errorf("%s: size %d does not match uploaded artifact (%d bytes)", f.Path, f.Size, a.Size)
+		}
+	}
+	return nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -1,8 +1,8 @@
 package api
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -21,6 +21,14 @@ type createBuildRequest struct {
 	SourceRef  string            `json:"source_ref,omitempty"`
 	SourceMeta map[string]string `json:"source_meta,omitempty"`
 	Files      []store.BuildFile `json:"files"`
+	State      string            `json:"state,omitempty"` // "pending" while uploads continue; default "complete"
+}
+
+type buildStateRequest struct {
+	State           string `json:"state"`
+	Reason          string `json:"reason,omitempty"`
+	ChangedBy       string `json:"changed_by,omitempty"`
+	ConfirmRollback bool   `json:"confirm_rollback,omitempty"` // roll back environments where a yanked build is live
 }
 
 func (s *Server) handleCreateBuild(w http.ResponseWriter, r *http.Request) {
@@ -38,6 +46,39 @@ func (s *Server) handleCreateBuild(w http.ResponseWriter, r *http.Request) {
 		hydraapi.WriteError(w, http.StatusBadRequest, "at least one file is required")
 		return
 	}
+	if req.State != "" && req.State != store.BuildPending && req.State != store.BuildComplete {
+		hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid state: %q (new builds are pending or complete)", req.State))
+		return
+	}
+	if !s.authorize(w, r, store.ActionBuildSubmit, req.Project, "") {
+		return
+	}
+	req.UploadedBy = actor(r, req.UploadedBy)
+	if id := requestIdentity(r); id != nil && len(id.Provenance) > 0 {
+		// Verified claims win over whatever the job put in the request.
+		if req.SourceMeta == nil {
+			req.SourceMeta = make(map[string]string)
+		}
+		for k, v := range id.Provenance {
+			req.SourceMeta[k] = v
+		}
+	}
+	if err := s.checkProject(req.Project, ""); err != nil {
+		hydraapi.WriteError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	// Keep garbage collection from removing verified artifacts before the
+	// build that references them is stored.
+	s.gcMu.RLock()
+	defer s.gcMu.RUnlock()
+
+	// Pending builds are checked when they are marked complete.
+	if req.State != store.BuildPending {
+		if err := s.verifyBuildArtifacts(req.Files); err != nil {
+			hydraapi.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 
 	build, err := s.Builds.Create(store.CreateParams{
 		Project:    req.Project,
@@ -46,6 +87,7 @@ func (s *Server) handleCreateBuild(w http.ResponseWriter, r *http.Request) {
 		SourceRef:  req.SourceRef,
 		SourceMeta: req.SourceMeta,
 		Files:      req.Files,
+		State:      req.State,
 	})
 	if err != nil {
 		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to create build")
@@ -65,6 +107,7 @@ func (s *Server) handleCreateBuild(w http.ResponseWriter, r *http.Request) {
 		"uploaded_by":  build.UploadedBy,
 		"file_count":   len(build.Files),
 		"total_bytes":  totalBytes,
+		"state":        build.State,
 	}
 	if build.Source != "" {
 		eventData["source"] = build.Source
@@ -72,76 +115,141 @@ func (s *Server) handleCreateBuild(w http.ResponseWriter, r *http.Request) {
 	if build.SourceRef != "" {
 		eventData["source_ref"] = build.SourceRef
 	}
-	s.Monitor.Emit(hydramonitor.Event{
+	s.emit(hydramonitor.Event{
 		Type: "build.uploaded",
 		Data: eventData,
 	})
 
-	// Create mirror hardlinks for files with mirror_path (best-effort, non-blocking).
-	if s.MirrorURL != "" {
-		go s.linkMirrorFiles(build)
+	// Create storage hardlinks for files with mirror_path, retried via the outbox.
+	if s.Storage != nil && build.State == store.BuildComplete {
+		s.queueMirrorLinks(build)
 	}
 
-	hydraapi.WriteJSON(w, http.StatusCreated, build)
+	hydraapi.WriteJSON(w, http.StatusCreated, buildResponse{Build: build, MirrorLinks: s.mirrorLinkStatus(build.Project, build.BuildNumber)})
 }
 
-// linkMirrorFiles calls hydramirror's link endpoint for each file that has a mirror_path.
-// For each file, the source is the mirror_path (where the file was pushed during finalize)
-// and the target is a build-specific path.
-func (s *Server) linkMirrorFiles(build *store.Build) {
-	for _, f := range build.Files {
-		if f.MirrorPath == "" {
-			continue
-		}
-
-		target := fmt.Sprintf("builds/%s/%d/%s", build.Project, build.BuildNumber, f.Path)
+// buildResponse adds the state of the build's mirror links (pending, done
+// or failed), when it has any.
+type buildResponse struct {
+	*store.Build
+	MirrorLinks string `json:"mirror_links,omitempty"`
+}
 
-		body, _ := json.Marshal(map[string]any{
-			"source":  f.MirrorPath,
-			"targets": []string{target},
-		})
+type buildListEntry struct {
+	store.BuildIndexEntry
+	MirrorLinks string `json:"mirror_links,omitempty"`
+}
 
-		url := strings.TrimRight(s.MirrorURL, "/") + "/api/v1/link"
-		req, err := http.NewRequest("POST", url, bytes.NewReader(body))
-		if err != nil {
-			log.Printf("[mirror-link] failed to create request: %v", err)
-			continue
+// queueMirrorLinks queues a job linking each file that has a mirror_path into
+// storage. For each file, the source is the mirror_path (where the file was
+// pushed during finalize) and the target is a build-specific path.
+func (s *Server) queueMirrorLinks(build *store.Build) {
+	job := mirrorLinkJob{Project: build.Project, BuildNumber: build.BuildNumber}
+	for _, f := range build.Files {
+		if f.MirrorPath != "" {
+			job.Links = append(job.Links, mirrorLink{Source: f.MirrorPath, Target: buildFilePath(build, f)})
 		}
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", "Bearer "+s.MirrorToken)
+	}
+	if len(job.Links) == 0 {
+		return
+	}
+	s.enqueueJob(store.JobMirrorLink, buildKey(build.Project, build.BuildNumber), job)
+}
 
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			log.Printf("[mirror-link] failed to link %s -> %s: %v", f.MirrorPath, target, err)
+// linkMirrorFiles makes the links of a mirror link job, keeping the ones
+// that failed for the next attempt.
+func (s *Server) linkMirrorFiles(job *mirrorLinkJob) error {
+	if s.Storage == nil {
+		return fmt.Errorf("storage not configured")
+	}
+	var failed []mirrorLink
+	var firstErr error
+	for _, l := range job.Links {
+		if err := s.Storage.Link(l.Source, []string{l.Target}); err != nil {
+			log.Printf("[mirror-link] failed to link %s -> %s: %v", l.Source, l.Target, err)
+			failed = append(failed, l)
+			if firstErr == nil {
+				firstErr = err
+			}
 			continue
 		}
-		resp.Body.Close()
-
-		if resp.StatusCode == http.StatusOK {
-			log.Printf("[mirror-link] linked %s -> %s", f.MirrorPath, target)
-		} else {
-			log.Printf("[mirror-link] link %s -> %s returned %d", f.MirrorPath, target, resp.StatusCode)
-		}
+		log.Printf("[mirror-link] linked %s -> %s", l.Source, l.Target)
 	}
+	job.Links = failed
+	if firstErr != nil {
+		return fmt.Errorf("%d of the links failed: %w", len(failed), firstErr)
+	}
+	return nil
 }
 
+// handleListBuilds lists a project's builds newest first, a page at a time.
+// It filters on uploaded_by, source, source_ref, since/until (upload time)
+// and state.
 func (s *Server) handleListBuilds(w http.ResponseWriter, r *http.Request) {
-	project := r.URL.Query().Get("project")
+	q := r.URL.Query()
+	project := q.Get("project")
 	if project == "" {
 		hydraapi.WriteError(w, http.StatusBadRequest, "project query parameter is required")
 		return
 	}
 
+	// Yanked builds are hidden unless asked for; state=all lists everything.
+	state := q.Get("state")
+	if state != "" && state != "all" && !store.ValidBuildState(state) {
+		hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid state: %q", state))
+		return
+	}
+	page, err := parsePageParams(q)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	since, until, err := parseTimeRange(q)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	uploadedBy, source, sourceRef := q.Get("uploaded_by"), q.Get("source"), q.Get("source_ref")
+
 	builds, err := s.Builds.List(project)
 	if err != nil {
 		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to list builds")
 		return
 	}
 
-	if builds == nil {
-		builds = []store.BuildIndexEntry{}
+	matched := builds[:0]
+	for _, b := range builds {
+		switch {
+		case state == "" && b.State == store.BuildYanked,
+			state != "" && state != "all" && b.State != state,
+			uploadedBy != "" && b.UploadedBy != uploadedBy,
+			!inTimeRange(b.UploadedAt, since, until):
+			continue
+		}
+		if (source != "" || sourceRef != "") && !s.buildSourceMatches(b, source, sourceRef) {
+			continue
+		}
+		matched = append(matched, b)
+	}
+
+	entries, next := paginate(matched, func(b store.BuildIndexEntry) int { return b.BuildNumber }, page)
+	links := s.mirrorLinkStatuses()
+	result := make([]buildListEntry, 0, len(entries))
+	for _, b := range entries {
+		result = append(result, buildListEntry{BuildIndexEntry: b, MirrorLinks: links[buildKey(b.Project, b.BuildNumber)]})
 	}
-	hydraapi.WriteJSON(w, http.StatusOK, builds)
+	writePage(w, result, next)
+}
+
+// buildSourceMatches compares a build's source and source ref with the
+// filters, reading the build for index entries that predate those fields.
+func (s *Server) buildSourceMatches(e store.BuildIndexEntry, source, sourceRef string) bool {
+	if e.Source == "" && e.SourceRef == "" {
+		if b, err := s.Builds.Get(e.Project, e.BuildNumber); err == nil {
+			e.Source, e.SourceRef = b.Source, b.SourceRef
+		}
+	}
+	return (source == "" || e.Source == source) && (sourceRef == "" || e.SourceRef == sourceRef)
 }
 
 func (s *Server) handleGetBuild(w http.ResponseWriter, r *http.Request) {
@@ -160,5 +268,274 @@ func (s *Server) handleGetBuild(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hydraapi.WriteJSON(w, http.StatusOK, build)
+	hydraapi.WriteJSON(w, http.StatusOK, buildResponse{Build: build, MirrorLinks: s.mirrorLinkStatus(project, number)})
+}
+
+// handleSetBuildState moves a build through its lifecycle. Completing a
+// pending build verifies its artifacts. Yanking a build that is live
+// somewhere needs confirm_rollback, and then rolls those environments back
+// to the previous releasable build first.
+func (s *Server) handleSetBuildState(w http.ResponseWriter, r *http.Request) {
+	project := r.PathValue("project")
+	number, err := strconv.Atoi(r.PathValue("number"))
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusBadRequest, "invalid build number")
+		return
+	}
+
+	var req buildStateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		hydraapi.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if !store.ValidBuildState(req.State) {
+		hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid state: %q (must be complete, failed or yanked)", req.State))
+		return
+	}
+
+	build, err := s.Builds.Get(project, number)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	action := store.ActionBuildSubmit
+	if req.State == store.BuildYanked || build.State == store.BuildYanked {
+		action = store.ActionBuildYank
+	}
+	if !s.authorize(w, r, action, project, "") {
+		return
+	}
+	req.ChangedBy = actor(r, req.ChangedBy)
+
+	if err := build.CheckTransition(req.State); err != nil {
+		hydraapi.WriteError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if build.State == store.BuildPending && req.State == store.BuildComplete {
+		if err := s.verifyBuildArtifacts(build.Files); err != nil {
+			hydraapi.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	var rollbacks []store.RollbackRequest
+	if req.State == store.BuildYanked {
+		var ok bool
+		if rollbacks, ok = s.planYankRollbacks(w, r, build, req); !ok {
+			return
+		}
+	}
+	rolledBack, err := s.applyYankRollbacks(build, rollbacks)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	previous := build.State
+	build, err = s.Builds.SetState(project, number, store.StateChange{State: req.State, Reason: req.Reason, By: req.ChangedBy})
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidTransition) {
+			hydraapi.WriteError(w, http.StatusConflict, err.Error())
+			return
+		}
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to change build state")
+		return
+	}
+
+	if previous == store.BuildPending && build.State == store.BuildComplete && s.Storage != nil {
+		s.queueMirrorLinks(build)
+	}
+
+	eventData := map[string]any{
+		"district":       "",
+		"timestamp":      build.StateChangedAt.Format("2006-01-02T15:04:05Z07:00"),
+		"project":        build.Project,
+		"build_number":   build.BuildNumber,
+		"state":          build.State,
+		"previous_state": previous,
+		"changed_by":     build.StateChangedBy,
+	}
+	if build.StateReason != "" {
+		eventData["reason"] = build.StateReason
+	}
+	if len(rolledBack) > 0 {
+		envs := make([]string, 0, len(rolledBack))
+		for _, rel := range rolledBack {
+			envs = append(envs, rel.Environment)
+		}
+		eventData["rolled_back"] = envs
+	}
+	s.emit(hydramonitor.Event{
+		Type: "build.state-changed",
+		Data: eventData,
+	})
+
+	hydraapi.WriteJSON(w, http.StatusOK, buildStateResponse{
+		buildResponse: buildResponse{Build: build, MirrorLinks: s.mirrorLinkStatus(project, number)},
+		RolledBack:    rolledBack,
+	})
+}
+
+type buildStateResponse struct {
+	buildResponse
+	RolledBack []*store.Release `json:"rolled_back,omitempty"`
+}
+
+// planYankRollbacks works out the rollback of every environment where build
+// is live, skipping builds that cannot be released. Nothing is changed: it
+// writes a 409 if confirm_rollback is not set or any environment has no
+// earlier build to go back to.
+func (s *Server) planYankRollbacks(w http.ResponseWriter, r *http.Request, build *store.Build, req buildStateRequest) ([]store.RollbackRequest, bool) {
+	current, err := s.Releases.ListCurrentReleases()
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to load current releases")
+		return nil, false
+	}
+	var live []string
+	for _, rel := range current {
+		if rel.Project == build.Project && rel.BuildNumber == build.BuildNumber {
+			live = append(live, rel.Environment)
+		}
+	}
+	if len(live) == 0 {
+		return nil, true
+	}
+	if !req.ConfirmRollback {
+		hydraapi.WriteJSON(w, http.StatusConflict, map[string]any{
+			"error":   fmt.Sprintf("build %s/%d is live in %s; set confirm_rollback to roll back before yanking", build.Project, build.BuildNumber, strings.Join(live, ", ")),
+			"live_in": live,
+		})
+		return nil, false
+	}
+	for _, env := range live {
+		if !s.authorize(w, r, store.ActionRollback, build.Project, env) {
+			return nil, false
+		}
+	}
+
+	skip, err := s.unreleasableBuilds(build.Project)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to list builds")
+		return nil, false
+	}
+	skip = append(skip, build.BuildNumber)
+	history, err := s.Releases.List(build.Project)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to list releases")
+		return nil, false
+	}
+
+	var plan []store.RollbackRequest
+	var stuck []string
+	for _, env := range live {
+		target, ok := store.PreviousRelease(history, env, build.BuildNumber, skip)
+		if !ok {
+			stuck = append(stuck, env)
+			continue
+		}
+		plan = append(plan, store.RollbackRequest{
+			Project:      build.Project,
+			Environment:  env,
+			RolledBackBy: req.ChangedBy,
+			TargetBuild:  target.BuildNumber,
+		})
+	}
+	if len(stuck) > 0 {
+		hydraapi.WriteJSON(w, http.StatusConflict, map[string]any{
+			"error":   fmt.Sprintf("cannot yank build %s/%d: no earlier complete build to roll back to in %s", build.Project, build.BuildNumber, strings.Join(stuck, ", ")),
+			"live_in": live,
+		})
+		return nil, false
+	}
+	return plan, true
+}
+
+// applyYankRollbacks carries out the rollbacks planned by planYankRollbacks.
+func (s *Server) applyYankRollbacks(build *store.Build, plan []store.RollbackRequest) ([]*store.Release, error) {
+	var rolledBack []*store.Release
+	for _, req := range plan {
+		rel, err := s.Releases.Rollback(req)
+		if err != nil {
+			msg := fmt.Sprintf("rolling %s/%s back from build %d: %v", req.Project, req.Environment, build.BuildNumber, err)
+			if len(rolledBack) > 0 {
+				msg += fmt.Sprintf(" (%d other environments were already rolled back)", len(rolledBack))
+			}
+			return rolledBack, errors.New(msg)
+		}
+		s.SetLatest(rel)
+		s.emit(hydramonitor.Event{
+			Type: "release.rolled-back",
+			Data: map[string]any{
+				"district":       "",
+				"timestamp":      rel.ReleasedAt.Format("2006-01-02T15:04:05Z07:00"),
+				"project":        rel.Project,
+				"environment":    rel.Environment,
+				"from_build":     build.BuildNumber,
+				"to_build":       rel.BuildNumber,
+				"to_version":     rel.Version,
+				"rolled_back_by": req.RolledBackBy,
+				"reason":         fmt.Sprintf("build %d yanked", build.BuildNumber),
+			},
+		})
+		rolledBack = append(rolledBack, rel)
+	}
+	return rolledBack, nil
+}
+
+// unreleasableBuilds returns the numbers of a project's builds that are not
+// complete, which rollbacks must pass over.
+func (s *Server) unreleasableBuilds(project string) ([]int, error) {
+	builds, err := s.Builds.List(project)
+	if err != nil {
+		return nil, err
+	}
+	var result []int
+	for _, b := range builds {
+		if b.State != store.BuildComplete {
+			result = append(result, b.BuildNumber)
+		}
+	}
+	return result, nil
+}
+
+// checkReleasable returns an error unless the build may be released.
+func checkReleasable(b *store.Build) error {
+	if b.State != store.BuildComplete {
+		return fmt.Errorf("build %s/%d is %s; only complete builds can be released", b.Project, b.BuildNumber, b.State)
+	}
+	return nil
+}
+
+// handleBuildFile serves a build file that was linked into storage.
+func (s *Server) handleBuildFile(w http.ResponseWriter, r *http.Request) {
+	project := r.PathValue("project")
+	filePath := r.PathValue("path")
+
+	number, err := strconv.Atoi(r.PathValue("number"))
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusBadRequest, "invalid build number")
+		return
+	}
+	if s.Storage == nil {
+		hydraapi.WriteError(w, http.StatusServiceUnavailable, "storage not configured")
+		return
+	}
+
+	build, err := s.Builds.Get(project, number)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	for _, f := range build.Files {
+		if f.Path == filePath && f.MirrorPath != "" {
+			s.Storage.Serve(w, r, buildFilePath(build, f))
+			return
+		}
+	}
+	hydraapi.WriteError(w, http.StatusNotFound, fmt.Sprintf("build %s/%d has no stored file %q", project, number, filePath))
+}
+
+// buildFilePath is where a build file is linked in storage.
+func buildFilePath(build *store.Build, f store.BuildFile) string {
+	return fmt.Sprintf("builds/%s/%d/%s", build.Project, build.BuildNumber, f.Path)
 }
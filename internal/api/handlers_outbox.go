@@ -0,0 +1,50 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cederikdotcom/hydraapi"
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+)
+
+// handleListOutbox lists outbox jobs, newest first, optionally filtered by
+// kind, status and key.
+func (s *Server) handleListOutbox(w http.ResponseWriter, r *http.Request) {
+	if s.Outbox == nil {
+		hydraapi.WriteJSON(w, http.StatusOK, []store.OutboxJob{})
+		return
+	}
+	q := r.URL.Query()
+	jobs, err := s.Outbox.List(store.OutboxFilter{
+		Kind:   q.Get("kind"),
+		Status: q.Get("status"),
+		Key:    q.Get("key"),
+	})
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to list outbox")
+		return
+	}
+	hydraapi.WriteJSON(w, http.StatusOK, jobs)
+}
+
+// handleRetryOutboxJob makes a pending or failed job due immediately with
+// a fresh set of attempts.
+func (s *Server) handleRetryOutboxJob(w http.ResponseWriter, r *http.Request) {
+	if s.Outbox == nil {
+		hydraapi.WriteError(w, http.StatusServiceUnavailable, "outbox not configured")
+		return
+	}
+	id := r.PathValue("id")
+	job, err := s.Outbox.Retry(id)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if job == nil {
+		hydraapi.WriteError(w, http.StatusNotFound, fmt.Sprintf("no outbox job with id %s", id))
+		return
+	}
+	s.wakeOutbox()
+	hydraapi.WriteJSON(w, http.StatusOK, job)
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/cederikdotcom/hydraapi"
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+)
+
+// overviewEnvironments is the column order of the overview.
+var overviewEnvironments = []string{"dev", "staging", "production"}
+
+// overview is the response of GET /api/v1/overview.
+type overview struct {
+	GeneratedAt  time.Time         `json:"generated_at"`
+	Environments []string          `json:"environments"`
+	Projects     []projectOverview `json:"projects"`
+}
+
+// projectOverview summarizes one project. Releases holds the current release
+// per environment; environments without one are absent.
+type projectOverview struct {
+	Project     string                      `json:"project"`
+	DisplayName string                      `json:"display_name,omitempty"`
+	Registered  bool                        `json:"registered"`
+	Releases    map[string]*overviewRelease `json:"releases"`
+	// LatestBuild is the newest complete build; pending, failed and yanked
+	// builds can't be released and are skipped.
+	LatestBuild *store.BuildIndexEntry `json:"latest_build,omitempty"`
+	// UnreleasedBuilds counts complete builds newer than the one live in
+	// production (all complete builds if production has none).
+	UnreleasedBuilds int `json:"unreleased_builds"`
+}
+
+type overviewRelease struct {
+	BuildNumber    int       `json:"build_number"`
+	Version        string    `json:"version"`
+	ReleasedBy     string    `json:"released_by"`
+	ReleasedAt     time.Time `json:"released_at"`
+	RolloutPercent int       `json:"rollout_percent,omitempty"` // set during a staged rollout
+	RolloutHalted  bool      `json:"rollout_halted,omitempty"`
+}
+
+// handleOverview returns what is live everywhere: every project with builds,
+// releases or a registry entry, its current release per environment, its
+// latest complete build and how far production lags behind.
+func (s *Server) handleOverview(w http.ResponseWriter, r *http.Request) {
+	registered, err := s.Projects.List()
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to list projects")
+		return
+	}
+	withBuilds, err := s.Builds.Projects()
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to list builds")
+		return
+	}
+	current, err := s.Releases.ListCurrentReleases()
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to load current releases")
+		return
+	}
+
+	projects := make(map[string]*projectOverview)
+	get := func(name string) *projectOverview {
+		p, ok := projects[name]
+		if !ok {
+			p = &projectOverview{Project: name, Releases: map[string]*overviewRelease{}}
+			projects[name] = p
+		}
+		return p
+	}
+	for _, reg := range registered {
+		p := get(reg.Name)
+		p.DisplayName, p.Registered = reg.DisplayName, true
+	}
+	for _, name := range withBuilds {
+		get(name)
+	}
+	for _, rel := range current {
+		o := &overviewRelease{
+			BuildNumber: rel.BuildNumber,
+			Version:     rel.Version,
+			ReleasedBy:  rel.ReleasedBy,
+			ReleasedAt:  rel.ReleasedAt,
+		}
+		if rel.Rollout != nil {
+			o.RolloutPercent, o.RolloutHalted = rel.Rollout.Percent, rel.Rollout.Halted
+		}
+		get(rel.Project).Releases[rel.Environment] = o
+	}
+
+	result := overview{GeneratedAt: time.Now().UTC(), Environments: overviewEnvironments, Projects: []projectOverview{}}
+	for _, p := range projects {
+		builds, err := s.Builds.List(p.Project)
+		if err != nil {
+			hydraapi.WriteError(w, http.StatusInternalServerError, "failed to list builds")
+			return
+		}
+		var inProduction int
+		if prod := p.Releases["production"]; prod != nil {
+			inProduction = prod.BuildNumber
+		}
+		for i := range builds {
+			b := &builds[i]
+			if b.State != store.BuildComplete {
+				continue
+			}
+			p.LatestBuild = b
+			if b.BuildNumber > inProduction {
+				p.UnreleasedBuilds++
+			}
+		}
+		result.Projects = append(result.Projects, *p)
+	}
+	sort.Slice(result.Projects, func(i, j int) bool { return result.Projects[i].Project < result.Projects[j].Project })
+	hydraapi.WriteJSON(w, http.StatusOK, result)
+}
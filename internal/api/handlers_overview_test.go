@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+)
+
+func TestOverview(t *testing.T) {
+	dir := t.TempDir()
+	s := &Server{
+		Builds:   store.NewYAMLBuildStore(dir),
+		Releases: store.NewYAMLReleaseStore(dir),
+		Projects: store.NewProjectStore(dir),
+	}
+	if err := s.Projects.Create(&store.Project{Name: "idle", DisplayName: "Idle"}); err != nil {
+		t.Fatal(err)
+	}
+	// Builds 1-3 are complete, 4 is still pending.
+	for _, state := range []string{"", "", "", store.BuildPending} {
+		if _, err := s.Builds.Create(store.CreateParams{Project: "app", State: state}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range []store.PromoteRequest{
+		{Project: "app", Environment: "production", BuildNumber: 1, Version: "1.0.0"},
+		{Project: "app", Environment: "staging", BuildNumber: 3, Version: "1.2.0"},
+	} {
+		if _, err := s.Releases.Promote(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	s.handleOverview(w, httptest.NewRequest("GET", "/api/v1/overview", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var got overview
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Projects) != 2 || got.Projects[0].Project != "app" || got.Projects[1].Project != "idle" {
+		t.Fatalf("projects = %+v, want app and idle", got.Projects)
+	}
+
+	app := got.Projects[0]
+	if app.Registered || app.Releases["production"] == nil || app.Releases["production"].BuildNumber != 1 ||
+		app.Releases["staging"] == nil || app.Releases["staging"].Version != "1.2.0" || app.Releases["dev"] != nil {
+		t.Errorf("app releases = %+v", app.Releases)
+	}
+	if app.LatestBuild == nil || app.LatestBuild.BuildNumber != 3 {
+		t.Errorf("app latest build = %+v, want the newest complete build, 3", app.LatestBuild)
+	}
+	if app.UnreleasedBuilds != 2 {
+		t.Errorf("app unreleased builds = %d, want 2", app.UnreleasedBuilds)
+	}
+
+	idle := got.Projects[1]
+	if !idle.Registered || idle.DisplayName != "Idle" || len(idle.Releases) != 0 || idle.LatestBuild != nil || idle.UnreleasedBuilds != 0 {
+		t.Errorf("idle = %+v", idle)
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cederikdotcom/hydraapi"
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+)
+
+type setPipelineRequest struct {
+	Stages    []store.PipelineStage `json:"stages"`
+	UpdatedBy string                `json:"updated_by"`
+}
+
+// checkPipeline returns the unmet prerequisite for promoting build into env,
+// or nil if the project has no pipeline or the promotion satisfies it.
+func (s *Server) checkPipeline(project, env string, build int) (*store.PipelineViolation, error) {
+	pipeline, err := s.Pipelines.Get(project)
+	if err != nil || pipeline == nil {
+		return nil, err
+	}
+	history, err := s.Releases.List(project)
+	if err != nil {
+		return nil, err
+	}
+	return pipeline.Check(history, env, build, time.Now().UTC()), nil
+}
+
+func (s *Server) handleGetPipeline(w http.ResponseWriter, r *http.Request) {
+	project := r.PathValue("project")
+
+	pipeline, err := s.Pipelines.Get(project)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to load pipeline")
+		return
+	}
+	if pipeline == nil {
+		hydraapi.WriteError(w, http.StatusNotFound, fmt.Sprintf("no pipeline defined for %s", project))
+		return
+	}
+
+	hydraapi.WriteJSON(w, http.StatusOK, pipeline)
+}
+
+func (s *Server) handleSetPipeline(w http.ResponseWriter, r *http.Request) {
+	project := r.PathValue("project")
+
+	var req setPipelineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		hydraapi.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	for _, st := range req.Stages {
+		if !validEnvironments[st.Environment] {
+			hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid environment: %q (must be dev, staging, or production)", st.Environment))
+			return
+		}
+	}
+
+	pipeline := &store.Pipeline{
+		Project:   project,
+		Stages:    req.Stages,
+		UpdatedBy: actor(r, req.UpdatedBy),
+	}
+	if err := s.Pipelines.Set(pipeline); err != nil {
+		hydraapi.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	hydraapi.WriteJSON(w, http.StatusOK, pipeline)
+}
+
+func (s *Server) handleDeletePipeline(w http.ResponseWriter, r *http.Request) {
+	project := r.PathValue("project")
+
+	if err := s.Pipelines.Delete(project); err != nil {
+		hydraapi.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/cederikdotcom/hydraapi"
+	"github.com/cederikdotcom/hydramonitor"
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+)
+
+type createProjectRequest struct {
+	Name         string                 `json:"name"`
+	DisplayName  string                 `json:"display_name"`
+	HealthURL    string                 `json:"health_url"`
+	Environments []string               `json:"environments"`
+	Owners       []string               `json:"owners"`
+	Retention    *store.RetentionPolicy `json:"retention"`
+}
+
+type updateProjectRequest struct {
+	DisplayName  *string                `json:"display_name"`
+	HealthURL    *string                `json:"health_url"`
+	Environments *[]string              `json:"environments"`
+	Owners       *[]string              `json:"owners"`
+	Retention    *store.RetentionPolicy `json:"retention"`
+}
+
+// checkProject enforces the project registry for a build or promotion.
+// Registered projects may only be released to their allowed environments
+// (env is empty for builds); unregistered ones are refused in strict mode.
+func (s *Server) checkProject(project, env string) error {
+	p, err := s.Projects.Get(project)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		if s.StrictProjects {
+			return fmt.Errorf("project %s is not registered", project)
+		}
+		return nil
+	}
+	if env != "" && !p.AllowsEnvironment(env) {
+		return fmt.Errorf("project %s may not be released to %s (allowed: %v)", project, env, p.Environments)
+	}
+	return nil
+}
+
+func validateProjectFields(healthURL string, environments []string, retention *store.RetentionPolicy) error {
+	if healthURL != "" {
+		u, err := url.Parse(healthURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid health_url: %q", healthURL)
+		}
+	}
+	for _, env := range environments {
+		if !validEnvironments[env] {
+			return fmt.Errorf("invalid environment: %q (must be dev, staging, or production)", env)
+		}
+	}
+	if retention != nil && (retention.KeepLast < 0 || retention.KeepDays < 0) {
+		return fmt.Errorf("retention values must not be negative")
+	}
+	return nil
+}
+
+func (s *Server) emitProjectEvent(eventType string, p *store.Project) {
+	s.emit(hydramonitor.Event{
+		Type: eventType,
+		Data: map[string]any{
+			"district":  "",
+			"timestamp": time.Now().UTC().Format("2006-01-02T15:04:05Z07:00"),
+			"project":   p.Name,
+		},
+	})
+}
+
+func (s *Server) handleListProjects(w http.ResponseWriter, r *http.Request) {
+	projects, err := s.Projects.List()
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to list projects")
+		return
+	}
+	hydraapi.WriteJSON(w, http.StatusOK, projects)
+}
+
+func (s *Server) handleGetProject(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	p, err := s.Projects.Get(name)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to load project")
+		return
+	}
+	if p == nil {
+		hydraapi.WriteError(w, http.StatusNotFound, fmt.Sprintf("project %s is not registered", name))
+		return
+	}
+	hydraapi.WriteJSON(w, http.StatusOK, p)
+}
+
+func (s *Server) handleCreateProject(w http.ResponseWriter, r *http.Request) {
+	var req createProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		hydraapi.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if !validNameRe.MatchString(req.Name) {
+		hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid project name: %q", req.Name))
+		return
+	}
+	if err := validateProjectFields(req.HealthURL, req.Environments, req.Retention); err != nil {
+		hydraapi.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	p := &store.Project{
+		Name:         req.Name,
+		DisplayName:  req.DisplayName,
+		HealthURL:    req.HealthURL,
+		Environments: req.Environments,
+		Owners:       req.Owners,
+		Retention:    req.Retention,
+	}
+	if err := s.Projects.Create(p); err != nil {
+		hydraapi.WriteError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	s.emitProjectEvent("project.created", p)
+	hydraapi.WriteJSON(w, http.StatusCreated, p)
+}
+
+func (s *Server) handleUpdateProject(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	var req updateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		hydraapi.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	var healthURL string
+	if req.HealthURL != nil {
+		healthURL = *req.HealthURL
+	}
+	var environments []string
+	if req.Environments != nil {
+		environments = *req.Environments
+	}
+	if err := validateProjectFields(healthURL, environments, req.Retention); err != nil {
+		hydraapi.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	existing, err := s.Projects.Get(name)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to load project")
+		return
+	}
+	if existing == nil {
+		hydraapi.WriteError(w, http.StatusNotFound, fmt.Sprintf("project %s is not registered", name))
+		return
+	}
+
+	p, err := s.Projects.Update(name, store.ProjectUpdate{
+		DisplayName:  req.DisplayName,
+		HealthURL:    req.HealthURL,
+		Environments: req.Environments,
+		Owners:       req.Owners,
+		Retention:    req.Retention,
+	})
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to update project")
+		return
+	}
+
+	s.emitProjectEvent("project.updated", p)
+	hydraapi.WriteJSON(w, http.StatusOK, p)
+}
@@ -8,10 +8,13 @@ import (
 	"log"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/cederikdotcom/hydraapi"
 	"github.com/cederikdotcom/hydrarelease/internal/store"
+	"github.com/cederikdotcom/hydrarelease/pkg/updater/signing"
+	semver "github.com/cederikdotcom/hydrarelease/pkg/updater/version"
 )
 
 var validNameRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
@@ -26,6 +29,11 @@ func validatePublishParams(project, channel, version, binary string) error {
 	if !validNameRe.MatchString(version) {
 		return fmt.Errorf("invalid version: %q", version)
 	}
+	// Updaters refuse manifests whose version is not SemVer, so such a
+	// release would break update checks for the whole channel.
+	if _, err := semver.Parse(version); err != nil {
+		return err
+	}
 	if binary != "" {
 		if binary == "finalize" {
 			return fmt.Errorf("binary name %q is reserved", binary)
@@ -37,7 +45,7 @@ func validatePublishParams(project, channel, version, binary string) error {
 	return nil
 }
 
-// handleUploadBinary streams a binary upload directly to hydramirror,
+// handleUploadBinary streams a binary upload directly to storage,
 // computing the SHA256 hash on the fly for later use in finalize.
 func (s *Server) handleUploadBinary(w http.ResponseWriter, r *http.Request) {
 	project := r.PathValue("project")
@@ -50,61 +58,61 @@ func (s *Server) handleUploadBinary(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if s.MirrorURL == "" {
-		hydraapi.WriteError(w, http.StatusServiceUnavailable, "mirror not configured")
+	if s.Storage == nil {
+		hydraapi.WriteError(w, http.StatusServiceUnavailable, "storage not configured")
 		return
 	}
-
-	// Stream body to mirror while computing SHA256.
-	mirrorPath := fmt.Sprintf("releases/%s/%s/%s/%s", project, channel, version, binary)
-	url := strings.TrimRight(s.MirrorURL, "/") + "/api/v1/files/" + mirrorPath
-
-	hasher := sha256.New()
-	body := io.TeeReader(r.Body, hasher)
-
-	req, err := http.NewRequest("PUT", url, body)
-	if err != nil {
-		log.Printf("publish: create mirror request: %v", err)
-		hydraapi.WriteError(w, http.StatusInternalServerError, "internal error")
+	if !s.authorize(w, r, store.ActionPromote, project, channel) {
 		return
 	}
-	req.Header.Set("Authorization", "Bearer "+s.MirrorToken)
-
-	client := &http.Client{Timeout: 5 * 60 * 1000000000} // 5 minutes
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("publish: mirror PUT %s: %v", mirrorPath, err)
-		hydraapi.WriteError(w, http.StatusBadGateway, "failed to upload to mirror")
+	if err := s.checkProject(project, channel); err != nil {
+		hydraapi.WriteError(w, http.StatusForbidden, err.Error())
 		return
 	}
-	resp.Body.Close()
 
-	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		log.Printf("publish: mirror PUT %s returned %d", mirrorPath, resp.StatusCode)
-		hydraapi.WriteError(w, http.StatusBadGateway, fmt.Sprintf("mirror returned %d", resp.StatusCode))
+	// Stream body to storage while computing SHA256.
+	storagePath := fmt.Sprintf("releases/%s/%s/%s/%s", project, channel, version, binary)
+	hasher := sha256.New()
+	body := &countingReader{r: io.TeeReader(r.Body, hasher)}
+
+	if err := s.Storage.Put(storagePath, body, r.ContentLength); err != nil {
+		log.Printf("publish: %s PUT %s: %v", s.Storage.Name(), storagePath, err)
+		hydraapi.WriteError(w, http.StatusBadGateway, "failed to store upload")
 		return
 	}
+	s.Metrics.countUpload("publish", body.n)
 
 	// Store hash for finalize.
 	hash := hex.EncodeToString(hasher.Sum(nil))
-	sessionKey := project + "/" + channel + "/" + version
-
-	s.uploadMu.Lock()
-	if s.uploadSessions == nil {
-		s.uploadSessions = make(map[string]map[string]string)
-	}
-	if s.uploadSessions[sessionKey] == nil {
-		s.uploadSessions[sessionKey] = make(map[string]string)
+	if _, err := s.UploadSessions.AddFile(project, channel, version, binary, hash, body.n, s.uploadSessionTTL()); err != nil {
+		log.Printf("publish: failed to record upload session: %v", err)
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to record upload")
+		return
 	}
-	s.uploadSessions[sessionKey][binary] = hash
-	s.uploadMu.Unlock()
 
-	log.Printf("publish: uploaded %s/%s/%s/%s to mirror", project, channel, version, binary)
+	log.Printf("publish: uploaded %s/%s/%s/%s to %s storage", project, channel, version, binary, s.Storage.Name())
 	hydraapi.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok", "binary": binary})
 }
 
-// handleFinalize generates SHA256SUMS from tracked hashes, uploads it to mirror,
-// and updates the latest version tracking.
+// sha256Sums renders a SHA256SUMS file sorted by file name, matching
+// `sha256sum * > SHA256SUMS` so publishers can sign it locally.
+func sha256Sums(files map[string]string) string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sums strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&sums, "%s  %s\n", files[name], name)
+	}
+	return sums.String()
+}
+
+// handleFinalize generates SHA256SUMS from tracked hashes, uploads it to storage,
+// and updates the latest version tracking. The request body may carry a
+// detached signature over SHA256SUMS, which is published as SHA256SUMS.sig.
 func (s *Server) handleFinalize(w http.ResponseWriter, r *http.Request) {
 	project := r.PathValue("project")
 	channel := r.PathValue("channel")
@@ -115,72 +123,104 @@ func (s *Server) handleFinalize(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if s.MirrorURL == "" {
-		hydraapi.WriteError(w, http.StatusServiceUnavailable, "mirror not configured")
+	if s.Storage == nil {
+		hydraapi.WriteError(w, http.StatusServiceUnavailable, "storage not configured")
 		return
 	}
-
-	// Get tracked hashes from upload session.
-	sessionKey := project + "/" + channel + "/" + version
-
-	s.uploadMu.Lock()
-	files := s.uploadSessions[sessionKey]
-	delete(s.uploadSessions, sessionKey)
-	s.uploadMu.Unlock()
-
-	if len(files) == 0 {
-		hydraapi.WriteError(w, http.StatusBadRequest, "no files uploaded for this version; upload binaries first")
+	if !s.authorize(w, r, store.ActionPromote, project, channel) {
 		return
 	}
 
-	// Generate SHA256SUMS content.
-	var sums strings.Builder
-	for name, hash := range files {
-		fmt.Fprintf(&sums, "%s  %s\n", hash, name)
+	minVersion := r.URL.Query().Get("min_version")
+	if minVersion != "" {
+		v, err := semver.Parse(minVersion)
+		if err != nil {
+			hydraapi.WriteError(w, http.StatusBadRequest, "min_version: "+err.Error())
+			return
+		}
+		minVersion = v.String()
 	}
 
-	// Upload SHA256SUMS to mirror.
-	sumsContent := sums.String()
-	mirrorPath := fmt.Sprintf("releases/%s/%s/%s/SHA256SUMS", project, channel, version)
-	url := strings.TrimRight(s.MirrorURL, "/") + "/api/v1/files/" + mirrorPath
-
-	req, err := http.NewRequest("PUT", url, strings.NewReader(sumsContent))
+	signature, err := io.ReadAll(io.LimitReader(r.Body, 64<<10))
 	if err != nil {
-		log.Printf("publish: create SHA256SUMS request: %v", err)
-		hydraapi.WriteError(w, http.StatusInternalServerError, "internal error")
+		hydraapi.WriteError(w, http.StatusBadRequest, "reading signature: "+err.Error())
 		return
 	}
-	req.Header.Set("Authorization", "Bearer "+s.MirrorToken)
 
-	client := &http.Client{Timeout: 30 * 1000000000} // 30 seconds
-	resp, err := client.Do(req)
+	// Get tracked hashes from upload session.
+	session, err := s.UploadSessions.Get(project, channel, version)
 	if err != nil {
-		log.Printf("publish: mirror PUT SHA256SUMS: %v", err)
-		hydraapi.WriteError(w, http.StatusBadGateway, "failed to upload SHA256SUMS to mirror")
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to load upload session")
+		return
+	}
+	if session == nil || len(session.Files) == 0 {
+		hydraapi.WriteError(w, http.StatusBadRequest, "no files uploaded for this version; upload binaries first")
 		return
 	}
-	resp.Body.Close()
+	files := session.Files
+
+	// Refuse to flip latest on an incomplete release, e.g. one of the
+	// GOOS/GOARCH binaries failed to upload. The session is kept for a retry.
+	if expect := r.URL.Query().Get("expect"); expect != "" {
+		if missing := missingFiles(files, strings.Split(expect, ",")); len(missing) > 0 {
+			hydraapi.WriteJSON(w, http.StatusConflict, map[string]any{
+				"error":   fmt.Sprintf("missing expected files: %s", strings.Join(missing, ", ")),
+				"missing": missing,
+			})
+			return
+		}
+	}
+
+	sumsContent := sha256Sums(files)
+
+	// With trusted keys configured, only correctly signed releases are
+	// published. The session is kept so a corrected signature can be retried.
+	if len(s.TrustedKeys) > 0 {
+		if len(signature) == 0 {
+			hydraapi.WriteError(w, http.StatusBadRequest, "release must be signed: send SHA256SUMS.sig as the request body")
+			return
+		}
+		if err := signing.Verify([]byte(sumsContent), signature, s.TrustedKeys); err != nil {
+			hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("signature rejected: %v", err))
+			return
+		}
+	}
 
-	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		log.Printf("publish: mirror PUT SHA256SUMS returned %d", resp.StatusCode)
-		hydraapi.WriteError(w, http.StatusBadGateway, fmt.Sprintf("mirror returned %d", resp.StatusCode))
+	// Upload SHA256SUMS (and its signature) to storage.
+	prefix := fmt.Sprintf("releases/%s/%s/%s/", project, channel, version)
+	if err := s.putFile(prefix+"SHA256SUMS", sumsContent); err != nil {
+		log.Printf("publish: PUT SHA256SUMS: %v", err)
+		hydraapi.WriteError(w, http.StatusBadGateway, "failed to store SHA256SUMS")
 		return
 	}
+	if len(signature) > 0 {
+		if err := s.putFile(prefix+signing.SignatureFile, string(signature)); err != nil {
+			log.Printf("publish: PUT %s: %v", signing.SignatureFile, err)
+			hydraapi.WriteError(w, http.StatusBadGateway, "failed to store SHA256SUMS.sig")
+			return
+		}
+	}
 
 	// Persist to ReleaseStore so latest survives restarts.
 	cleanVersion := strings.TrimPrefix(version, "v")
-	_, err = s.Releases.Promote(store.PromoteRequest{
+	rel, err := s.Releases.Promote(store.PromoteRequest{
 		Project:     project,
 		Environment: channel,
 		Version:     cleanVersion,
-		ReleasedBy:  "publish-api",
+		ReleasedBy:  actor(r, "publish-api"),
+		MinVersion:  minVersion,
+		Files:       releaseFiles(version, session),
 	})
 	if err != nil {
+		// The session is kept so finalize can be retried.
 		log.Printf("publish: warning: failed to persist release to store: %v", err)
+		rel = &store.Release{Project: project, Environment: channel, Version: cleanVersion}
+	} else if err := s.UploadSessions.Delete(project, channel, version); err != nil {
+		log.Printf("publish: warning: failed to remove upload session: %v", err)
 	}
 
 	// Update latest version tracking.
-	s.SetLatest(project, channel, cleanVersion, 0)
+	s.SetLatest(rel)
 
 	// Resolve referenced issues if any were passed.
 	if issuesParam := r.URL.Query().Get("issues"); issuesParam != "" {
@@ -188,6 +228,31 @@ func (s *Server) handleFinalize(w http.ResponseWriter, r *http.Request) {
 		s.resolveIssues(issueIDs, cleanVersion, project)
 	}
 
-	log.Printf("publish: finalized %s/%s/%s (%d files)", project, channel, version, len(files))
-	hydraapi.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok", "version": cleanVersion, "channel": channel})
+	log.Printf("publish: finalized %s/%s/%s (%d files, signed=%t)", project, channel, version, len(files), len(signature) > 0)
+	hydraapi.WriteJSON(w, http.StatusOK, map[string]any{"status": "ok", "version": cleanVersion, "channel": channel, "signed": len(signature) > 0})
+}
+
+// releaseFiles lists a finalized session's files for the release manifest.
+func releaseFiles(version string, session *store.UploadSession) []store.ReleaseFile {
+	names := make([]string, 0, len(session.Files))
+	for name := range session.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]store.ReleaseFile, 0, len(names))
+	for _, name := range names {
+		result = append(result, store.ReleaseFile{
+			Name:   name,
+			Path:   version + "/" + name,
+			Size:   session.Sizes[name],
+			SHA256: session.Files[name],
+		})
+	}
+	return result
+}
+
+// putFile stores a small file.
+func (s *Server) putFile(storagePath, content string) error {
+	return s.Storage.Put(storagePath, strings.NewReader(content), int64(len(content)))
 }
@@ -3,26 +3,38 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
+	"strings"
 
 	"github.com/cederikdotcom/hydraapi"
 	"github.com/cederikdotcom/hydramonitor"
 	"github.com/cederikdotcom/hydrarelease/internal/store"
+	semver "github.com/cederikdotcom/hydrarelease/pkg/updater/version"
 )
 
 type promoteRequest struct {
-	Project      string `json:"project"`
-	Environment  string `json:"environment"`
-	BuildNumber  int    `json:"build_number"`
-	Version      string `json:"version"`
-	ReleasedBy   string `json:"released_by"`
-	ReleaseNotes string `json:"release_notes"`
+	Project         string `json:"project"`
+	Environment     string `json:"environment"`
+	FromEnvironment string `json:"from_environment,omitempty"`
+	BuildNumber     int    `json:"build_number"`
+	Version         string `json:"version"`
+	ReleasedBy      string `json:"released_by"`
+	ReleaseNotes    string `json:"release_notes"`
+	Override        bool   `json:"override,omitempty"`
+	OverrideReason  string `json:"override_reason,omitempty"`
+	RolloutPercent  int    `json:"rollout_percent,omitempty"`
+	MinVersion      string `json:"min_version,omitempty"`
 }
 
 type rollbackRequest struct {
 	Project      string `json:"project"`
 	Environment  string `json:"environment"`
 	RolledBackBy string `json:"rolled_back_by"`
+	TargetBuild  int    `json:"target_build,omitempty"`
+	Steps        int    `json:"steps,omitempty"`
+	Force        bool   `json:"force,omitempty"`
+	Version      string `json:"version,omitempty"`
 }
 
 var validEnvironments = map[string]bool{
@@ -46,6 +58,45 @@ func (s *Server) handlePromoteRelease(w http.ResponseWriter, r *http.Request) {
 		hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid environment: %q (must be dev, staging, or production)", req.Environment))
 		return
 	}
+	if !s.authorize(w, r, store.ActionPromote, req.Project, req.Environment) {
+		return
+	}
+	req.ReleasedBy = actor(r, req.ReleasedBy)
+
+	if err := s.checkProject(req.Project, req.Environment); err != nil {
+		hydraapi.WriteError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	// Promotion by reference copies the release currently live in another environment.
+	if req.FromEnvironment != "" {
+		if !validEnvironments[req.FromEnvironment] {
+			hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid from_environment: %q (must be dev, staging, or production)", req.FromEnvironment))
+			return
+		}
+		if req.FromEnvironment == req.Environment {
+			hydraapi.WriteError(w, http.StatusBadRequest, "from_environment must differ from environment")
+			return
+		}
+		if req.BuildNumber != 0 || req.Version != "" {
+			hydraapi.WriteError(w, http.StatusBadRequest, "build_number and version cannot be combined with from_environment")
+			return
+		}
+		source, err := s.Releases.Get(req.Project, req.FromEnvironment)
+		if err != nil {
+			hydraapi.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		req.BuildNumber = source.BuildNumber
+		req.Version = source.Version
+		if req.ReleaseNotes == "" {
+			req.ReleaseNotes = source.ReleaseNotes
+		}
+		if req.MinVersion == "" {
+			req.MinVersion = source.MinVersion
+		}
+	}
+
 	if req.BuildNumber <= 0 {
 		hydraapi.WriteError(w, http.StatusBadRequest, "build_number must be positive")
 		return
@@ -54,20 +105,79 @@ func (s *Server) handlePromoteRelease(w http.ResponseWriter, r *http.Request) {
 		hydraapi.WriteError(w, http.StatusBadRequest, "version is required")
 		return
 	}
+	// Versions copied from another environment were validated when first promoted.
+	if req.FromEnvironment == "" {
+		v, err := semver.Parse(req.Version)
+		if err != nil {
+			hydraapi.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		req.Version = v.String()
+	}
+	if req.MinVersion != "" {
+		v, err := semver.Parse(req.MinVersion)
+		if err != nil {
+			hydraapi.WriteError(w, http.StatusBadRequest, "min_version: "+err.Error())
+			return
+		}
+		req.MinVersion = v.String()
+	}
+	if req.RolloutPercent < 0 || req.RolloutPercent > 100 {
+		hydraapi.WriteError(w, http.StatusBadRequest, "rollout_percent must be between 0 and 100")
+		return
+	}
+
+	// Keep garbage collection from deleting the build between the check
+	// below and the promotion.
+	s.gcMu.RLock()
+	defer s.gcMu.RUnlock()
 
-	// Verify the build exists.
-	if _, err := s.Builds.Get(req.Project, req.BuildNumber); err != nil {
+	// Verify the build exists and may be released.
+	build, err := s.Builds.Get(req.Project, req.BuildNumber)
+	if err != nil {
 		hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("build %s/%d not found", req.Project, req.BuildNumber))
 		return
 	}
+	if err := checkReleasable(build); err != nil {
+		hydraapi.WriteError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	// Enforce the project's promotion pipeline.
+	violation, err := s.checkPipeline(req.Project, req.Environment, req.BuildNumber)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to check promotion pipeline")
+		return
+	}
+	var overrideReason string
+	if violation != nil {
+		if !req.Override {
+			hydraapi.WriteJSON(w, http.StatusConflict, map[string]any{
+				"error":       "promotion pipeline: " + violation.Error(),
+				"requirement": violation,
+			})
+			return
+		}
+		if req.OverrideReason == "" {
+			hydraapi.WriteError(w, http.StatusBadRequest, "override_reason is required when overriding the promotion pipeline")
+			return
+		}
+		overrideReason = req.OverrideReason
+		log.Printf("[pipeline] OVERRIDE: %q promoted %s/%d to %s despite: %s (reason: %s)",
+			req.ReleasedBy, req.Project, req.BuildNumber, req.Environment, violation.Error(), overrideReason)
+	}
 
 	rel, err := s.Releases.Promote(store.PromoteRequest{
-		Project:      req.Project,
-		Environment:  req.Environment,
-		BuildNumber:  req.BuildNumber,
-		Version:      req.Version,
-		ReleasedBy:   req.ReleasedBy,
-		ReleaseNotes: req.ReleaseNotes,
+		Project:        req.Project,
+		Environment:    req.Environment,
+		BuildNumber:    req.BuildNumber,
+		Version:        req.Version,
+		ReleasedBy:     req.ReleasedBy,
+		ReleaseNotes:   req.ReleaseNotes,
+		PromotedFrom:   req.FromEnvironment,
+		OverrideReason: overrideReason,
+		RolloutPercent: req.RolloutPercent,
+		MinVersion:     req.MinVersion,
 	})
 	if err != nil {
 		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to promote release")
@@ -75,21 +185,38 @@ func (s *Server) handlePromoteRelease(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update latest version tracking for updater polling.
-	s.SetLatest(rel.Project, rel.Environment, rel.Version, rel.BuildNumber)
+	s.SetLatest(rel)
 
 	// Emit SSE event.
-	s.Monitor.Emit(hydramonitor.Event{
+	eventData := map[string]any{
+		"district":     "",
+		"timestamp":    rel.ReleasedAt.Format("2006-01-02T15:04:05Z07:00"),
+		"project":      rel.Project,
+		"environment":  rel.Environment,
+		"build_number": rel.BuildNumber,
+		"version":      rel.Version,
+		"released_by":  rel.ReleasedBy,
+	}
+	if rel.PromotedFrom != "" {
+		eventData["promoted_from"] = rel.PromotedFrom
+	}
+	if rel.Rollout != nil {
+		eventData["rollout_percent"] = rel.Rollout.Percent
+	}
+	if violation != nil {
+		eventData["override_reason"] = rel.OverrideReason
+		eventData["overridden_requirement"] = violation
+	}
+	s.emit(hydramonitor.Event{
 		Type: "release.promoted",
-		Data: map[string]any{
-			"district":     "",
-			"timestamp":    rel.ReleasedAt.Format("2006-01-02T15:04:05Z07:00"),
-			"project":      rel.Project,
-			"environment":  rel.Environment,
-			"build_number": rel.BuildNumber,
-			"version":      rel.Version,
-			"released_by":  rel.ReleasedBy,
-		},
+		Data: eventData,
 	})
+	if violation != nil {
+		s.emit(hydramonitor.Event{
+			Type: "release.pipeline-overridden",
+			Data: eventData,
+		})
+	}
 
 	hydraapi.WriteJSON(w, http.StatusCreated, rel)
 }
@@ -109,6 +236,51 @@ func (s *Server) handleRollbackRelease(w http.ResponseWriter, r *http.Request) {
 		hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid environment: %q", req.Environment))
 		return
 	}
+	if !s.authorize(w, r, store.ActionRollback, req.Project, req.Environment) {
+		return
+	}
+	req.RolledBackBy = actor(r, req.RolledBackBy)
+	if req.TargetBuild < 0 || req.Steps < 0 {
+		hydraapi.WriteError(w, http.StatusBadRequest, "target_build and steps must be positive")
+		return
+	}
+	if req.TargetBuild > 0 && req.Steps > 0 {
+		hydraapi.WriteError(w, http.StatusBadRequest, "target_build and steps are mutually exclusive")
+		return
+	}
+
+	if req.Version != "" {
+		v, err := semver.Parse(req.Version)
+		if err != nil {
+			hydraapi.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		req.Version = v.String()
+	}
+
+	// Keep garbage collection from deleting the target between the checks
+	// below and the rollback.
+	s.gcMu.RLock()
+	defer s.gcMu.RUnlock()
+
+	// Verify an explicit target still exists and may be released; a step
+	// rollback passes over builds that may not.
+	if req.TargetBuild > 0 {
+		build, err := s.Builds.Get(req.Project, req.TargetBuild)
+		if err != nil {
+			hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("build %s/%d not found", req.Project, req.TargetBuild))
+			return
+		}
+		if err := checkReleasable(build); err != nil {
+			hydraapi.WriteError(w, http.StatusConflict, err.Error())
+			return
+		}
+	}
+	skip, err := s.unreleasableBuilds(req.Project)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to list builds")
+		return
+	}
 
 	// Get current release before rollback for the SSE event.
 	current, err := s.Releases.Get(req.Project, req.Environment)
@@ -117,17 +289,26 @@ func (s *Server) handleRollbackRelease(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rel, err := s.Releases.Rollback(req.Project, req.Environment, req.RolledBackBy)
+	rel, err := s.Releases.Rollback(store.RollbackRequest{
+		Project:      req.Project,
+		Environment:  req.Environment,
+		RolledBackBy: req.RolledBackBy,
+		TargetBuild:  req.TargetBuild,
+		Steps:        req.Steps,
+		Force:        req.Force,
+		Version:      req.Version,
+		Skip:         skip,
+	})
 	if err != nil {
 		hydraapi.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Update latest version tracking for updater polling.
-	s.SetLatest(rel.Project, rel.Environment, rel.Version, rel.BuildNumber)
+	s.SetLatest(rel)
 
 	// Emit SSE event.
-	s.Monitor.Emit(hydramonitor.Event{
+	s.emit(hydramonitor.Event{
 		Type: "release.rolled-back",
 		Data: map[string]any{
 			"district":       "",
@@ -135,7 +316,12 @@ func (s *Server) handleRollbackRelease(w http.ResponseWriter, r *http.Request) {
 			"project":        rel.Project,
 			"environment":    rel.Environment,
 			"from_build":     current.BuildNumber,
+			"from_version":   current.Version,
 			"to_build":       rel.BuildNumber,
+			"to_version":     rel.Version,
+			"target_build":   req.TargetBuild,
+			"steps":          req.Steps,
+			"forced":         req.Force,
 			"rolled_back_by": req.RolledBackBy,
 		},
 	})
@@ -143,23 +329,63 @@ func (s *Server) handleRollbackRelease(w http.ResponseWriter, r *http.Request) {
 	hydraapi.WriteJSON(w, http.StatusOK, rel)
 }
 
+// handleListReleases lists a project's release history newest first, a page
+// at a time. It filters on environment, released_by, version_prefix and
+// since/until (release time).
 func (s *Server) handleListReleases(w http.ResponseWriter, r *http.Request) {
-	project := r.URL.Query().Get("project")
+	q := r.URL.Query()
+	project := q.Get("project")
 	if project == "" {
 		hydraapi.WriteError(w, http.StatusBadRequest, "project query parameter is required")
 		return
 	}
 
+	env := q.Get("environment")
+	if env != "" && !validEnvironments[env] {
+		hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid environment: %q", env))
+		return
+	}
+	page, err := parsePageParams(q)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	since, until, err := parseTimeRange(q)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	releasedBy, versionPrefix := q.Get("released_by"), strings.TrimPrefix(q.Get("version_prefix"), "v")
+
 	releases, err := s.Releases.List(project)
 	if err != nil {
 		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to list releases")
 		return
 	}
 
-	if releases == nil {
-		releases = []store.ReleaseIndexEntry{}
+	// History is append-only, so an entry's position is a stable cursor key.
+	type positioned struct {
+		pos   int
+		entry store.ReleaseIndexEntry
+	}
+	var matched []positioned
+	for i, e := range releases {
+		switch {
+		case env != "" && e.Environment != env,
+			releasedBy != "" && e.ReleasedBy != releasedBy,
+			versionPrefix != "" && !strings.HasPrefix(e.Version, versionPrefix),
+			!inTimeRange(e.ReleasedAt, since, until):
+			continue
+		}
+		matched = append(matched, positioned{pos: i + 1, entry: e})
+	}
+
+	entries, next := paginate(matched, func(p positioned) int { return p.pos }, page)
+	result := make([]store.ReleaseIndexEntry, 0, len(entries))
+	for _, p := range entries {
+		result = append(result, p.entry)
 	}
-	hydraapi.WriteJSON(w, http.StatusOK, releases)
+	writePage(w, result, next)
 }
 
 func (s *Server) handleGetRelease(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cederikdotcom/hydraapi"
+	"github.com/cederikdotcom/hydramonitor"
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+)
+
+type createTokenRequest struct {
+	Name      string             `json:"name"`
+	Scopes    []store.TokenScope `json:"scopes"`
+	ExpiresIn string             `json:"expires_in,omitempty"` // e.g. "720h"; empty never expires
+	CreatedBy string             `json:"created_by"`
+}
+
+// createTokenResponse carries the plaintext token, which is only ever
+// returned here.
+type createTokenResponse struct {
+	Token string `json:"token"`
+	*store.APIToken
+}
+
+func (s *Server) handleCreateToken(w http.ResponseWriter, r *http.Request) {
+	var req createTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		hydraapi.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	var ttl time.Duration
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || d <= 0 {
+			hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid expires_in: %q", req.ExpiresIn))
+			return
+		}
+		ttl = d
+	}
+	for _, sc := range req.Scopes {
+		for _, env := range sc.Environments {
+			if !validEnvironments[env] {
+				hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid environment: %q (must be dev, staging, or production)", env))
+				return
+			}
+		}
+	}
+
+	plaintext, tok, err := s.Tokens.Create(req.Name, req.Scopes, req.CreatedBy, ttl)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.emit(hydramonitor.Event{
+		Type: "token.created",
+		Data: map[string]any{
+			"district":  "",
+			"timestamp": tok.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+			"id":        tok.ID,
+			"name":      tok.Name,
+		},
+	})
+
+	hydraapi.WriteJSON(w, http.StatusCreated, createTokenResponse{Token: plaintext, APIToken: tok})
+}
+
+func (s *Server) handleListTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := s.Tokens.List()
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to list tokens")
+		return
+	}
+	hydraapi.WriteJSON(w, http.StatusOK, tokens)
+}
+
+func (s *Server) handleRevokeToken(w http.ResponseWriter, r *http.Request) {
+	tok, err := s.Tokens.Revoke(r.PathValue("id"))
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	s.emit(hydramonitor.Event{
+		Type: "token.revoked",
+		Data: map[string]any{
+			"district":  "",
+			"timestamp": time.Now().UTC().Format("2006-01-02T15:04:05Z07:00"),
+			"id":        tok.ID,
+			"name":      tok.Name,
+		},
+	})
+
+	hydraapi.WriteJSON(w, http.StatusOK, tok)
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cederikdotcom/hydraapi"
+	"github.com/cederikdotcom/hydramonitor"
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+)
+
+// updateReportStatuses are the statuses updaters report; each becomes an
+// event type, and so a webhook delivery.
+var updateReportStatuses = map[string]bool{
+	"reverted": true,
+	"failed":   true,
+}
+
+// Anonymous clients may send this many update reports per window, so that a
+// single source cannot flood webhooks or push real reports out of the store.
+const (
+	updateReportLimit  = 20
+	updateReportWindow = time.Hour
+)
+
+// handleCreateUpdateReport records a client's report that an update was
+// reverted. It is unauthenticated, like latest.json, since fleet machines
+// hold no API token, and rate limited per client IP instead.
+func (s *Server) handleCreateUpdateReport(w http.ResponseWriter, r *http.Request) {
+	if !s.reportLimit.allow(r, updateReportLimit, updateReportWindow) {
+		hydraapi.WriteError(w, http.StatusTooManyRequests, "too many update reports; try again later")
+		return
+	}
+
+	var report store.UpdateReport
+	if err := json.NewDecoder(io.LimitReader(r.Body, 16<<10)).Decode(&report); err != nil {
+		hydraapi.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if !validNameRe.MatchString(report.Project) {
+		hydraapi.WriteError(w, http.StatusBadRequest, "invalid project name")
+		return
+	}
+	if !validNameRe.MatchString(report.ToVersion) || (report.FromVersion != "" && !validNameRe.MatchString(report.FromVersion)) {
+		hydraapi.WriteError(w, http.StatusBadRequest, "invalid version")
+		return
+	}
+	if report.Status == "" {
+		report.Status = "reverted"
+	}
+	if !updateReportStatuses[report.Status] {
+		hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid status: %q (must be reverted or failed)", report.Status))
+		return
+	}
+	if len(report.Reason) > 500 {
+		report.Reason = report.Reason[:500]
+	}
+
+	if err := s.UpdateReports.Add(&report); err != nil {
+		log.Printf("update-report: failed to store report: %v", err)
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to store report")
+		return
+	}
+
+	log.Printf("update-report: %s %s -> %s %s on %s: %s",
+		report.Project, report.FromVersion, report.ToVersion, report.Status, report.ClientID, report.Reason)
+
+	s.emit(hydramonitor.Event{
+		Type: "update." + report.Status,
+		Data: map[string]any{
+			"district":     "",
+			"timestamp":    time.Now().UTC().Format("2006-01-02T15:04:05Z07:00"),
+			"project":      report.Project,
+			"channel":      report.Channel,
+			"from_version": report.FromVersion,
+			"to_version":   report.ToVersion,
+			"client_id":    report.ClientID,
+			"reason":       report.Reason,
+		},
+	})
+
+	hydraapi.WriteJSON(w, http.StatusCreated, report)
+}
+
+func (s *Server) handleListUpdateReports(w http.ResponseWriter, r *http.Request) {
+	reports, err := s.UpdateReports.List(r.URL.Query().Get("project"), r.URL.Query().Get("version"))
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to list update reports")
+		return
+	}
+
+	hydraapi.WriteJSON(w, http.StatusOK, reports)
+}
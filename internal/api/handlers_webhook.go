@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/cederikdotcom/hydraapi"
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+)
+
+type createWebhookRequest struct {
+	URL       string   `json:"url"`
+	Secret    string   `json:"secret,omitempty"` // generated when empty
+	Projects  []string `json:"projects,omitempty"`
+	Events    []string `json:"events,omitempty"`
+	CreatedBy string   `json:"created_by,omitempty"`
+}
+
+// createWebhookResponse carries the signing secret, which is only returned
+// when the webhook is created.
+type createWebhookResponse struct {
+	Secret string `json:"secret"`
+	*store.Webhook
+}
+
+func (s *Server) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	if s.Webhooks == nil {
+		hydraapi.WriteError(w, http.StatusServiceUnavailable, "webhooks not configured")
+		return
+	}
+
+	var req createWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		hydraapi.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid url: %q", req.URL))
+		return
+	}
+	for _, p := range req.Projects {
+		if !validNameRe.MatchString(p) {
+			hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid project name: %q", p))
+			return
+		}
+	}
+
+	h := &store.Webhook{
+		URL:       req.URL,
+		Secret:    req.Secret,
+		Projects:  req.Projects,
+		Events:    req.Events,
+		CreatedBy: req.CreatedBy,
+	}
+	if err := s.Webhooks.Create(h); err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to create webhook")
+		return
+	}
+	hydraapi.WriteJSON(w, http.StatusCreated, createWebhookResponse{Secret: h.Secret, Webhook: h})
+}
+
+func (s *Server) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	if s.Webhooks == nil {
+		hydraapi.WriteJSON(w, http.StatusOK, []store.Webhook{})
+		return
+	}
+	hooks, err := s.Webhooks.List()
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to list webhooks")
+		return
+	}
+	hydraapi.WriteJSON(w, http.StatusOK, hooks)
+}
+
+func (s *Server) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if s.Webhooks == nil {
+		hydraapi.WriteError(w, http.StatusServiceUnavailable, "webhooks not configured")
+		return
+	}
+	id := r.PathValue("id")
+	if err := s.Webhooks.Delete(id); err != nil {
+		hydraapi.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	hydraapi.WriteJSON(w, http.StatusOK, map[string]string{"status": "deleted", "id": id})
+}
+
+// handleListWebhookDeliveries shows the delivery log of a webhook, newest
+// first, including pending retries and their last error.
+func (s *Server) handleListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if s.Webhooks == nil {
+		hydraapi.WriteError(w, http.StatusServiceUnavailable, "webhooks not configured")
+		return
+	}
+	id := r.PathValue("id")
+
+	h, err := s.Webhooks.Get(id)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to load webhook")
+		return
+	}
+	if h == nil {
+		hydraapi.WriteError(w, http.StatusNotFound, fmt.Sprintf("no webhook with id %s", id))
+		return
+	}
+
+	deliveries, err := s.Webhooks.Deliveries(id)
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to list deliveries")
+		return
+	}
+	if status := r.URL.Query().Get("status"); status != "" {
+		filtered := deliveries[:0]
+		for _, d := range deliveries {
+			if d.Status == status {
+				filtered = append(filtered, d)
+			}
+		}
+		deliveries = filtered
+	}
+	hydraapi.WriteJSON(w, http.StatusOK, deliveries)
+}
@@ -6,61 +6,75 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/cederikdotcom/hydrarelease/internal/store"
 )
 
-// resolveIssues resolves the given issue IDs via the issue tracker API.
-// It runs asynchronously and logs results without affecting the caller.
+// resolveIssues queues resolution of the given issue IDs via the issue
+// tracker API. Each issue is retried through the outbox without affecting
+// the caller.
 func (s *Server) resolveIssues(issueIDs []string, version, project string) {
 	if s.IssueTrackerURL == "" || s.IssueTrackerToken == "" {
 		log.Printf("issues: skipping resolution (issue tracker not configured)")
 		return
 	}
 
-	go func() {
-		client := &http.Client{Timeout: 10 * time.Second}
-		apiBase := strings.TrimRight(s.IssueTrackerURL, "/") + "/api/v1"
+	for _, id := range issueIDs {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		s.enqueueJob(store.JobIssueResolve, id, issueResolveJob{Issue: id, Project: project, Version: version})
+	}
+}
 
-		for _, id := range issueIDs {
-			// PATCH status to resolved.
-			patchBody := `{"status":"resolved"}`
-			req, err := http.NewRequest("PATCH", fmt.Sprintf("%s/issues/%s", apiBase, id), strings.NewReader(patchBody))
-			if err != nil {
-				log.Printf("issues: failed to create PATCH request for issue %s: %v", id, err)
-				continue
-			}
-			req.Header.Set("Authorization", "Bearer "+s.IssueTrackerToken)
-			req.Header.Set("Content-Type", "application/json")
+// resolveIssue sets an issue to resolved and comments on it with the
+// release. Once the status is set, retries only post the comment.
+func (s *Server) resolveIssue(job *issueResolveJob) error {
+	if s.IssueTrackerURL == "" || s.IssueTrackerToken == "" {
+		return fmt.Errorf("issue tracker not configured")
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	apiBase := strings.TrimRight(s.IssueTrackerURL, "/") + "/api/v1"
 
-			resp, err := client.Do(req)
-			if err != nil {
-				log.Printf("issues: failed to resolve issue %s: %v", id, err)
-				continue
-			}
-			resp.Body.Close()
+	if !job.Resolved {
+		// PATCH status to resolved.
+		err := s.issueRequest(client, "PATCH", fmt.Sprintf("%s/issues/%s", apiBase, job.Issue), `{"status":"resolved"}`)
+		s.Metrics.countIssueCall("resolve", err)
+		if err != nil {
+			return fmt.Errorf("resolving issue %s: %w", job.Issue, err)
+		}
+		job.Resolved = true
+	}
 
-			if resp.StatusCode >= 300 {
-				log.Printf("issues: resolve issue %s returned %d", id, resp.StatusCode)
-				continue
-			}
+	// POST comment.
+	comment := fmt.Sprintf(`{"author":"hydrarelease","text":"Resolved in %s %s"}`, job.Project, job.Version)
+	err := s.issueRequest(client, "POST", fmt.Sprintf("%s/issues/%s/comments", apiBase, job.Issue), comment)
+	s.Metrics.countIssueCall("comment", err)
+	if err != nil {
+		return fmt.Errorf("commenting on issue %s: %w", job.Issue, err)
+	}
 
-			// POST comment.
-			comment := fmt.Sprintf(`{"author":"hydrarelease","text":"Resolved in %s %s"}`, project, version)
-			req, err = http.NewRequest("POST", fmt.Sprintf("%s/issues/%s/comments", apiBase, id), strings.NewReader(comment))
-			if err != nil {
-				log.Printf("issues: failed to create comment request for issue %s: %v", id, err)
-				continue
-			}
-			req.Header.Set("Authorization", "Bearer "+s.IssueTrackerToken)
-			req.Header.Set("Content-Type", "application/json")
+	log.Printf("issues: resolved HYDRA-%s", job.Issue)
+	return nil
+}
 
-			resp, err = client.Do(req)
-			if err != nil {
-				log.Printf("issues: failed to comment on issue %s: %v", id, err)
-				continue
-			}
-			resp.Body.Close()
+func (s *Server) issueRequest(client *http.Client, method, url, body string) error {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.IssueTrackerToken)
+	req.Header.Set("Content-Type", "application/json")
 
-			log.Printf("issues: resolved HYDRA-%s", id)
-		}
-	}()
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
 }
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/cederikdotcom/hydraapi"
+)
+
+// Listing defaults for GET /api/v1/builds and /api/v1/releases. Requests
+// with neither limit nor cursor get every item, as before pagination
+// existed; a cursor without a limit gets defaultPageSize.
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// nextCursorHeader carries the cursor of the next page; it is absent on the
+// last page. Bodies stay plain JSON arrays so older clients keep working.
+const nextCursorHeader = "X-Next-Cursor"
+
+// pageParams are the paging and sorting parameters of a listing. Items are
+// identified by an increasing integer key (build number, history position)
+// and the cursor is the key of the last item on the previous page.
+type pageParams struct {
+	Limit     int // 0 returns everything
+	Oldest    bool
+	After     int
+	HasCursor bool
+}
+
+func parsePageParams(q url.Values) (pageParams, error) {
+	var p pageParams
+	if q.Get("cursor") != "" {
+		p.Limit = defaultPageSize
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxPageSize {
+			return p, fmt.Errorf("invalid limit: %q (0-%d)", v, maxPageSize)
+		}
+		p.Limit = n
+	}
+	switch q.Get("order") {
+	case "", "newest":
+	case "oldest":
+		p.Oldest = true
+	default:
+		return p, fmt.Errorf("invalid order: %q (must be newest or oldest)", q.Get("order"))
+	}
+	if v := q.Get("cursor"); v != "" {
+		key, err := decodeCursor(v)
+		if err != nil {
+			return p, fmt.Errorf("invalid cursor: %q", v)
+		}
+		p.After, p.HasCursor = key, true
+	}
+	return p, nil
+}
+
+func encodeCursor(key int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(key)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(b))
+}
+
+// parseTimeRange reads the since (inclusive) and until (exclusive) RFC3339
+// parameters; unset bounds are zero.
+func parseTimeRange(q url.Values) (since, until time.Time, err error) {
+	for name, t := range map[string]*time.Time{"since": &since, "until": &until} {
+		if v := q.Get(name); v != "" {
+			parsed, perr := time.Parse(time.RFC3339, v)
+			if perr != nil {
+				return since, until, fmt.Errorf("invalid %s: %q (want RFC3339)", name, v)
+			}
+			*t = parsed
+		}
+	}
+	return since, until, nil
+}
+
+func inTimeRange(t, since, until time.Time) bool {
+	return (since.IsZero() || !t.Before(since)) && (until.IsZero() || t.Before(until))
+}
+
+// paginate orders items (given oldest first) as requested, skips up to the
+// cursor and returns one page plus the cursor of the next, if any.
+func paginate[T any](items []T, key func(T) int, p pageParams) ([]T, string) {
+	ordered := make([]T, 0, len(items))
+	for i := range items {
+		item := items[i]
+		if !p.Oldest {
+			item = items[len(items)-1-i]
+		}
+		if p.HasCursor && ((p.Oldest && key(item) <= p.After) || (!p.Oldest && key(item) >= p.After)) {
+			continue
+		}
+		ordered = append(ordered, item)
+	}
+	if p.Limit == 0 || len(ordered) <= p.Limit {
+		return ordered, ""
+	}
+	page := ordered[:p.Limit]
+	return page, encodeCursor(key(page[len(page)-1]))
+}
+
+// writePage writes a page of a listing with its next cursor.
+func writePage[T any](w http.ResponseWriter, page []T, next string) {
+	if next != "" {
+		w.Header().Set(nextCursorHeader, next)
+	}
+	hydraapi.WriteJSON(w, http.StatusOK, page)
+}
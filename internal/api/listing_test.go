@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestParsePageParams(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    pageParams
+		wantErr bool
+	}{
+		{"", pageParams{}, false},
+		{"limit=10&order=oldest", pageParams{Limit: 10, Oldest: true}, false},
+		{"cursor=" + encodeCursor(7), pageParams{Limit: defaultPageSize, After: 7, HasCursor: true}, false},
+		{"limit=0&cursor=" + encodeCursor(7), pageParams{After: 7, HasCursor: true}, false},
+		{"limit=1001", pageParams{}, true},
+		{"limit=-1", pageParams{}, true},
+		{"order=sideways", pageParams{}, true},
+		{"cursor=%25%25", pageParams{}, true},
+	}
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		got, err := parsePageParams(q)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePageParams(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parsePageParams(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	for _, key := range []int{0, 1, 1234567} {
+		if got, err := decodeCursor(encodeCursor(key)); err != nil || got != key {
+			t.Errorf("decodeCursor(encodeCursor(%d)) = %d, %v", key, got, err)
+		}
+	}
+	if _, err := decodeCursor("bm90LWEtbnVtYmVy"); err == nil {
+		t.Error("decodeCursor accepted a non-numeric cursor")
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	key := func(n int) int { return n }
+
+	// Walk every page in both orders; the last page has no cursor.
+	for _, oldest := range []bool{false, true} {
+		var got []int
+		p := pageParams{Limit: 2, Oldest: oldest}
+		for pages := 0; ; pages++ {
+			if pages > len(items) {
+				t.Fatalf("oldest=%v: pagination does not terminate", oldest)
+			}
+			page, next := paginate(items, key, p)
+			got = append(got, page...)
+			if next == "" {
+				if len(page) != 1 {
+					t.Errorf("oldest=%v: last page = %v, want one item", oldest, page)
+				}
+				break
+			}
+			after, err := decodeCursor(next)
+			if err != nil {
+				t.Fatalf("decodeCursor(%q): %v", next, err)
+			}
+			p.After, p.HasCursor = after, true
+		}
+		want := []int{5, 4, 3, 2, 1}
+		if oldest {
+			want = items
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("oldest=%v: pages = %v, want %v", oldest, got, want)
+		}
+	}
+
+	if page, next := paginate(items, key, pageParams{}); len(page) != 5 || next != "" {
+		t.Errorf("no limit = %v, %q; want everything without a cursor", page, next)
+	}
+	if page, next := paginate(items, key, pageParams{Limit: 5}); len(page) != 5 || next != "" {
+		t.Errorf("exact limit = %v, %q; want everything without a cursor", page, next)
+	}
+	if page, _ := paginate(items, key, pageParams{Limit: 2, After: 1, HasCursor: true}); len(page) != 0 {
+		t.Errorf("newest-first past the oldest item = %v, want empty", page)
+	}
+}
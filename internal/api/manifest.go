@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+	"github.com/cederikdotcom/hydrarelease/pkg/updater"
+)
+
+// releaseManifest builds the latest.json document a client should see,
+// listing every file of the chosen release with its platform and hash.
+func (s *Server) releaseManifest(r *http.Request, rel *store.Release, clientID string) updater.Manifest {
+	m := latestForClient(rel, clientID)
+	m.Schema = updater.ManifestSchema
+
+	// The previous version of a staged rollout only has build files to offer.
+	var files []store.ReleaseFile
+	if m.Version == rel.Version && m.BuildNumber == rel.BuildNumber {
+		m.ReleasedAt = rel.ReleasedAt
+		m.ReleaseNotes = rel.ReleaseNotes
+		m.MinVersion = rel.MinVersion
+		files = rel.Files
+	}
+
+	base := requestBaseURL(r)
+	if m.BuildNumber > 0 {
+		build, err := s.Builds.Get(rel.Project, m.BuildNumber)
+		if err != nil {
+			return m
+		}
+		for _, f := range build.Files {
+			a := manifestArtifact(f.Path, f.Size, f.SHA256)
+			if f.MirrorPath != "" {
+				a.URL = base + "/api/v1/builds/" + url.PathEscape(rel.Project) + "/" + strconv.Itoa(m.BuildNumber) + "/files/" + f.Path
+			}
+			m.Artifacts = append(m.Artifacts, a)
+		}
+		return m
+	}
+	for _, f := range files {
+		a := manifestArtifact(f.Name, f.Size, f.SHA256)
+		a.URL = base + "/" + rel.Project + "/" + rel.Environment + "/" + f.Path
+		m.Artifacts = append(m.Artifacts, a)
+	}
+	return m
+}
+
+func manifestArtifact(name string, size int64, sha256 string) updater.ManifestArtifact {
+	goos, goarch := updater.PlatformFromName(path.Base(name))
+	return updater.ManifestArtifact{
+		Name:   name,
+		OS:     goos,
+		Arch:   goarch,
+		Size:   size,
+		SHA256: sha256,
+	}
+}
+
+// requestBaseURL returns the scheme and host the client used to reach us,
+// honouring X-Forwarded-Proto from a TLS-terminating proxy.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,329 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cederikdotcom/hydrarelease/internal/metrics"
+	"github.com/cederikdotcom/hydrarelease/internal/storage"
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+)
+
+// Metrics holds the server's Prometheus metrics. Labels are limited to
+// route patterns, known project/channel pairs and fixed operation names so
+// that cardinality stays bounded. A nil *Metrics records nothing.
+type Metrics struct {
+	Registry *metrics.Registry
+
+	httpRequests  *metrics.CounterVec
+	httpDuration  *metrics.HistogramVec
+	latestPolls   *metrics.CounterVec
+	downloads     *metrics.CounterVec
+	storeDuration *metrics.HistogramVec
+	storeErrors   *metrics.CounterVec
+	storageCalls  *metrics.CounterVec
+	storageTime   *metrics.HistogramVec
+	issueCalls    *metrics.CounterVec
+	uploadBytes   *metrics.CounterVec
+	webhookSends  *metrics.CounterVec
+	outboxRuns    *metrics.CounterVec
+}
+
+// NewMetrics registers the server metrics in a new registry.
+func NewMetrics() *Metrics {
+	reg := metrics.NewRegistry()
+	return &Metrics{
+		Registry: reg,
+		httpRequests: reg.Counter("hydrarelease_http_requests_total",
+			"HTTP requests by route pattern and status code.", "route", "code"),
+		httpDuration: reg.Histogram("hydrarelease_http_request_duration_seconds",
+			"HTTP request latency by route pattern.", metrics.DefaultBuckets, "route"),
+		latestPolls: reg.Counter("hydrarelease_latest_json_requests_total",
+			"latest.json requests answered, by project and channel.", "project", "channel"),
+		downloads: reg.Counter("hydrarelease_file_downloads_total",
+			"Release file downloads (redirects or local serves), by project and channel.", "project", "channel"),
+		storeDuration: reg.Histogram("hydrarelease_store_operation_duration_seconds",
+			"Metadata store operation latency.", metrics.DefaultBuckets, "store", "op"),
+		storeErrors: reg.Counter("hydrarelease_store_operation_errors_total",
+			"Metadata store operations that returned an error, including lookups of missing records.", "store", "op"),
+		storageCalls: reg.Counter("hydrarelease_storage_operations_total",
+			"File storage (hydramirror or local) operations by outcome.", "backend", "op", "outcome"),
+		storageTime: reg.Histogram("hydrarelease_storage_operation_duration_seconds",
+			"File storage operation latency.", metrics.DefaultBuckets, "backend", "op"),
+		issueCalls: reg.Counter("hydrarelease_issue_tracker_requests_total",
+			"Issue tracker calls made while resolving issues, by outcome.", "op", "outcome"),
+		uploadBytes: reg.Counter("hydrarelease_upload_bytes_total",
+			"Bytes received through artifact and legacy publish uploads.", "kind"),
+		webhookSends: reg.Counter("hydrarelease_webhook_deliveries_total",
+			"Webhook delivery attempts by outcome.", "outcome"),
+		outboxRuns: reg.Counter("hydrarelease_outbox_attempts_total",
+			"Outbox job attempts (mirror links, issue resolution) by kind and outcome.", "kind", "outcome"),
+	}
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+func (m *Metrics) observeStore(name, op string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.storeDuration.Observe(time.Since(start).Seconds(), name, op)
+	if err != nil {
+		m.storeErrors.Inc(name, op)
+	}
+}
+
+func (m *Metrics) countLatest(project, channel string) {
+	if m != nil {
+		m.latestPolls.Inc(project, channel)
+	}
+}
+
+func (m *Metrics) countDownload(project, channel string) {
+	if m != nil {
+		m.downloads.Inc(project, channel)
+	}
+}
+
+func (m *Metrics) countIssueCall(op string, err error) {
+	if m != nil {
+		m.issueCalls.Inc(op, outcome(err))
+	}
+}
+
+func (m *Metrics) countWebhookDelivery(err error) {
+	if m != nil {
+		m.webhookSends.Inc(outcome(err))
+	}
+}
+
+func (m *Metrics) countOutboxJob(kind string, err error) {
+	if m != nil {
+		m.outboxRuns.Inc(kind, outcome(err))
+	}
+}
+
+func (m *Metrics) countUpload(kind string, n int64) {
+	if m != nil && n > 0 {
+		m.uploadBytes.Add(float64(n), kind)
+	}
+}
+
+// RegisterGauges adds gauges computed from the server's stores at scrape time.
+func (m *Metrics) RegisterGauges(s *Server) {
+	m.Registry.GaugeFunc("hydrarelease_builds", "Builds currently stored.", func() (float64, bool) {
+		n, _, err := s.Builds.Stats()
+		return float64(n), err == nil
+	})
+	m.Registry.GaugeFunc("hydrarelease_projects", "Projects with at least one build.", func() (float64, bool) {
+		_, n, err := s.Builds.Stats()
+		return float64(n), err == nil
+	})
+	m.Registry.GaugeFunc("hydrarelease_releases", "Release promotions recorded.", func() (float64, bool) {
+		n, err := s.Releases.Stats()
+		return float64(n), err == nil
+	})
+	for _, status := range []string{store.JobPending, store.JobFailed} {
+		m.Registry.GaugeFunc("hydrarelease_outbox_"+status+"_jobs", "Outbox jobs currently "+status+".", func() (float64, bool) {
+			if s.Outbox == nil {
+				return 0, false
+			}
+			jobs, err := s.Outbox.List(store.OutboxFilter{Status: status})
+			return float64(len(jobs)), err == nil
+		})
+	}
+}
+
+// metricsRecorder captures the response status for request metrics.
+type metricsRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rw *metricsRecorder) WriteHeader(code int) {
+	if rw.status == 0 {
+		rw.status = code
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *metricsRecorder) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+// Flush keeps SSE streaming working through the recorder.
+func (rw *metricsRecorder) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (rw *metricsRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// instrumentHTTP counts requests and their latency per route pattern.
+func (m *Metrics) instrumentHTTP(next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &metricsRecorder{ResponseWriter: w}
+		start := time.Now()
+		next.ServeHTTP(rw, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		status := rw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		m.httpRequests.Inc(route, strconv.Itoa(status))
+		m.httpDuration.Observe(time.Since(start).Seconds(), route)
+	})
+}
+
+// BuildStore wraps b so its operations are timed.
+func (m *Metrics) BuildStore(b store.BuildStore) store.BuildStore {
+	return &instrumentedBuildStore{next: b, m: m}
+}
+
+type instrumentedBuildStore struct {
+	next store.BuildStore
+	m    *Metrics
+}
+
+func (s *instrumentedBuildStore) Create(p store.CreateParams) (b *store.Build, err error) {
+	defer func(start time.Time) { s.m.observeStore("builds", "create", start, err) }(time.Now())
+	return s.next.Create(p)
+}
+
+func (s *instrumentedBuildStore) Get(project string, number int) (b *store.Build, err error) {
+	defer func(start time.Time) { s.m.observeStore("builds", "get", start, err) }(time.Now())
+	return s.next.Get(project, number)
+}
+
+func (s *instrumentedBuildStore) List(project string) (l []store.BuildIndexEntry, err error) {
+	defer func(start time.Time) { s.m.observeStore("builds", "list", start, err) }(time.Now())
+	return s.next.List(project)
+}
+
+func (s *instrumentedBuildStore) Projects() (p []string, err error) {
+	defer func(start time.Time) { s.m.observeStore("builds", "projects", start, err) }(time.Now())
+	return s.next.Projects()
+}
+
+func (s *instrumentedBuildStore) SetState(project string, number int, c store.StateChange) (b *store.Build, err error) {
+	defer func(start time.Time) { s.m.observeStore("builds", "set_state", start, err) }(time.Now())
+	return s.next.SetState(project, number, c)
+}
+
+func (s *instrumentedBuildStore) Delete(project string, number int) (err error) {
+	defer func(start time.Time) { s.m.observeStore("builds", "delete", start, err) }(time.Now())
+	return s.next.Delete(project, number)
+}
+
+func (s *instrumentedBuildStore) Stats() (builds, projects int, err error) {
+	defer func(start time.Time) { s.m.observeStore("builds", "stats", start, err) }(time.Now())
+	return s.next.Stats()
+}
+
+// ReleaseStore wraps r so its operations are timed.
+func (m *Metrics) ReleaseStore(r store.ReleaseStore) store.ReleaseStore {
+	return &instrumentedReleaseStore{next: r, m: m}
+}
+
+type instrumentedReleaseStore struct {
+	next store.ReleaseStore
+	m    *Metrics
+}
+
+func (s *instrumentedReleaseStore) Promote(req store.PromoteRequest) (rel *store.Release, err error) {
+	defer func(start time.Time) { s.m.observeStore("releases", "promote", start, err) }(time.Now())
+	return s.next.Promote(req)
+}
+
+func (s *instrumentedReleaseStore) Rollback(req store.RollbackRequest) (rel *store.Release, err error) {
+	defer func(start time.Time) { s.m.observeStore("releases", "rollback", start, err) }(time.Now())
+	return s.next.Rollback(req)
+}
+
+func (s *instrumentedReleaseStore) SetRollout(req store.RolloutRequest) (rel *store.Release, err error) {
+	defer func(start time.Time) { s.m.observeStore("releases", "set_rollout", start, err) }(time.Now())
+	return s.next.SetRollout(req)
+}
+
+func (s *instrumentedReleaseStore) Get(project, env string) (rel *store.Release, err error) {
+	defer func(start time.Time) { s.m.observeStore("releases", "get", start, err) }(time.Now())
+	return s.next.Get(project, env)
+}
+
+func (s *instrumentedReleaseStore) List(project string) (l []store.ReleaseIndexEntry, err error) {
+	defer func(start time.Time) { s.m.observeStore("releases", "list", start, err) }(time.Now())
+	return s.next.List(project)
+}
+
+func (s *instrumentedReleaseStore) ListCurrentReleases() (l []store.Release, err error) {
+	defer func(start time.Time) { s.m.observeStore("releases", "list_current", start, err) }(time.Now())
+	return s.next.ListCurrentReleases()
+}
+
+func (s *instrumentedReleaseStore) Stats() (n int, err error) {
+	defer func(start time.Time) { s.m.observeStore("releases", "stats", start, err) }(time.Now())
+	return s.next.Stats()
+}
+
+// Storage wraps st so hydramirror or local disk calls are counted and timed.
+func (m *Metrics) Storage(st storage.Storage) storage.Storage {
+	return &instrumentedStorage{next: st, m: m}
+}
+
+type instrumentedStorage struct {
+	next storage.Storage
+	m    *Metrics
+}
+
+func (s *instrumentedStorage) observe(op string, start time.Time, err error) {
+	if s.m == nil {
+		return
+	}
+	backend := s.next.Name()
+	s.m.storageCalls.Inc(backend, op, outcome(err))
+	s.m.storageTime.Observe(time.Since(start).Seconds(), backend, op)
+}
+
+func (s *instrumentedStorage) Put(path string, r io.Reader, size int64) (err error) {
+	defer func(start time.Time) { s.observe("put", start, err) }(time.Now())
+	return s.next.Put(path, r, size)
+}
+
+func (s *instrumentedStorage) Delete(path string) (err error) {
+	defer func(start time.Time) { s.observe("delete", start, err) }(time.Now())
+	return s.next.Delete(path)
+}
+
+func (s *instrumentedStorage) Link(source string, targets []string) (err error) {
+	defer func(start time.Time) { s.observe("link", start, err) }(time.Now())
+	return s.next.Link(source, targets)
+}
+
+func (s *instrumentedStorage) Serve(w http.ResponseWriter, r *http.Request, path string) {
+	defer func(start time.Time) { s.observe("serve", start, nil) }(time.Now())
+	s.next.Serve(w, r, path)
+}
+
+func (s *instrumentedStorage) Name() string {
+	return s.next.Name()
+}
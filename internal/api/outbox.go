@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+)
+
+// outboxMaxAttempts is how often an outbox job is tried before it is marked
+// failed; with retryBackoff that spans about three hours.
+const outboxMaxAttempts = 10
+
+// retryBackoff returns the wait after the given number of failed attempts:
+// 30s, 1m, 2m, ... capped at one hour.
+func retryBackoff(attempts int) time.Duration {
+	d := 30 * time.Second << (attempts - 1)
+	if d <= 0 || d > time.Hour {
+		return time.Hour
+	}
+	return d
+}
+
+// mirrorLinkJob is the payload of a store.JobMirrorLink job. Links holds the
+// links that have not been made yet.
+type mirrorLinkJob struct {
+	Project     string       `json:"project"`
+	BuildNumber int          `json:"build_number"`
+	Links       []mirrorLink `json:"links"`
+}
+
+type mirrorLink struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// issueResolveJob is the payload of a store.JobIssueResolve job.
+type issueResolveJob struct {
+	Issue    string `json:"issue"`
+	Project  string `json:"project"`
+	Version  string `json:"version"`
+	Resolved bool   `json:"resolved,omitempty"` // status already set; only the comment is left
+}
+
+func buildKey(project string, number int) string {
+	return fmt.Sprintf("%s/%d", project, number)
+}
+
+// enqueueJob adds a job to the outbox and wakes the worker. Without an
+// outbox the job is tried once in the background.
+func (s *Server) enqueueJob(kind, key string, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("[outbox] failed to encode %s %s: %v", kind, key, err)
+		return
+	}
+	job := &store.OutboxJob{Kind: kind, Key: key, Payload: string(data)}
+
+	if s.Outbox == nil {
+		go func() {
+			if err := s.runJob(job); err != nil {
+				log.Printf("[outbox] %s %s failed: %v", kind, key, err)
+			}
+		}()
+		return
+	}
+	if err := s.Outbox.Enqueue(job); err != nil {
+		log.Printf("[outbox] failed to queue %s %s: %v", kind, key, err)
+		return
+	}
+	s.wakeOutbox()
+}
+
+func (s *Server) wakeOutbox() {
+	select {
+	case s.outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// StartOutbox runs queued jobs in the background, checking for due retries
+// every interval and immediately after new jobs.
+func (s *Server) StartOutbox(interval time.Duration) {
+	s.outboxWake = make(chan struct{}, 1)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.runOutbox()
+			select {
+			case <-ticker.C:
+			case <-s.outboxWake:
+			}
+		}
+	}()
+}
+
+func (s *Server) runOutbox() {
+	due, err := s.Outbox.Due(time.Now())
+	if err != nil {
+		log.Printf("[outbox] failed to read queue: %v", err)
+		return
+	}
+	for i := range due {
+		j := &due[i]
+		err := s.runJob(j)
+		j.Attempts++
+		j.LastError = ""
+		now := time.Now().UTC()
+		switch {
+		case err == nil:
+			j.Status = store.JobDone
+			j.DoneAt = &now
+		case j.Attempts >= outboxMaxAttempts:
+			j.Status = store.JobFailed
+			j.LastError = err.Error()
+			log.Printf("[outbox] giving up on %s %s after %d attempts: %v", j.Kind, j.Key, j.Attempts, err)
+		default:
+			j.LastError = err.Error()
+			j.NextAttempt = now.Add(retryBackoff(j.Attempts))
+		}
+		s.Metrics.countOutboxJob(j.Kind, err)
+		if err := s.Outbox.Update(j); err != nil {
+			log.Printf("[outbox] failed to record %s %s: %v", j.Kind, j.Key, err)
+		}
+	}
+}
+
+// runJob makes one attempt at a job, rewriting its payload to record
+// partial progress.
+func (s *Server) runJob(j *store.OutboxJob) error {
+	switch j.Kind {
+	case store.JobMirrorLink:
+		return runPayload(j, s.linkMirrorFiles)
+	case store.JobIssueResolve:
+		return runPayload(j, s.resolveIssue)
+	default:
+		return fmt.Errorf("unknown job kind %q", j.Kind)
+	}
+}
+
+func runPayload[T any](j *store.OutboxJob, fn func(*T) error) error {
+	var p T
+	if err := json.Unmarshal([]byte(j.Payload), &p); err != nil {
+		return fmt.Errorf("decoding %s payload: %w", j.Kind, err)
+	}
+	err := fn(&p)
+	if data, mErr := json.Marshal(p); mErr == nil {
+		j.Payload = string(data)
+	}
+	return err
+}
+
+// mirrorLinkStatuses returns the state of the latest mirror link job per
+// build key, or nil without an outbox.
+func (s *Server) mirrorLinkStatuses() map[string]string {
+	if s.Outbox == nil {
+		return nil
+	}
+	statuses, err := s.Outbox.Statuses(store.JobMirrorLink)
+	if err != nil {
+		log.Printf("[outbox] failed to load mirror link states: %v", err)
+		return nil
+	}
+	return statuses
+}
+
+// mirrorLinkStatus returns the state of the latest mirror link job of a
+// build, or "" if it has none.
+func (s *Server) mirrorLinkStatus(project string, number int) string {
+	if s.Outbox == nil {
+		return ""
+	}
+	jobs, err := s.Outbox.List(store.OutboxFilter{Kind: store.JobMirrorLink, Key: buildKey(project, number)})
+	if err != nil || len(jobs) == 0 {
+		return ""
+	}
+	return jobs[0].Status
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ipLimiter counts requests per client IP in fixed windows. The zero value
+// is ready to use.
+type ipLimiter struct {
+	mu     sync.Mutex
+	start  time.Time      // start of the current window
+	counts map[string]int // requests per IP in the current window
+}
+
+// allow reports whether another request from r's client IP fits within
+// limit per window, and counts it if so.
+func (l *ipLimiter) allow(r *http.Request, limit int, window time.Duration) bool {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.counts == nil || now.Sub(l.start) >= window {
+		l.start = now
+		l.counts = make(map[string]int)
+	}
+	if l.counts[ip] >= limit {
+		return false
+	}
+	l.counts[ip]++
+	return true
+}
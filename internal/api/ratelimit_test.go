@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIPLimiter(t *testing.T) {
+	var l ipLimiter
+	a := httptest.NewRequest("POST", "/", nil)
+	a.RemoteAddr = "10.0.0.1:1234"
+	b := httptest.NewRequest("POST", "/", nil)
+	b.RemoteAddr = "10.0.0.2:1234"
+
+	for i := 0; i < 3; i++ {
+		if !l.allow(a, 3, time.Hour) {
+			t.Fatalf("request %d refused within the limit", i+1)
+		}
+	}
+	if l.allow(a, 3, time.Hour) {
+		t.Error("request over the limit allowed")
+	}
+	if !l.allow(b, 3, time.Hour) {
+		t.Error("other client refused")
+	}
+	if !l.allow(a, 3, 0) {
+		t.Error("request refused after the window elapsed")
+	}
+}
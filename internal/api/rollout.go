@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"time"
+
+	"github.com/cederikdotcom/hydraapi"
+	"github.com/cederikdotcom/hydramonitor"
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+	"github.com/cederikdotcom/hydrarelease/pkg/updater"
+)
+
+type rolloutRequest struct {
+	Project     string `json:"project"`
+	Environment string `json:"environment"`
+	Percent     int    `json:"percent"`
+	Halt        bool   `json:"halt,omitempty"`
+	UpdatedBy   string `json:"updated_by"`
+}
+
+// rolloutBucket maps a client to a stable bucket in [0, 100) for a release.
+// The release identity is part of the hash so raising the percentage only
+// ever adds clients, while each release samples a different cohort.
+func rolloutBucket(rel *store.Release, clientID string) int {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s/%s/%d/%s/%s", rel.Project, rel.Environment, rel.BuildNumber, rel.Version, clientID)
+	return int(h.Sum32() % 100)
+}
+
+// latestForClient picks the version a client should see. Clients outside a
+// staged rollout, including those that send no client ID, get the previous
+// version. Rolled-back releases and the previous version of a rollout are
+// flagged so clients that already moved past them downgrade.
+func latestForClient(rel *store.Release, clientID string) updater.Manifest {
+	current := updater.Manifest{Version: rel.Version, BuildNumber: rel.BuildNumber, Rollback: rel.RolledBackFrom > 0}
+	if rel.Rollout == nil || rel.PreviousVersion == "" {
+		return current
+	}
+	if clientID != "" && rel.Rollout.ServesNew(rolloutBucket(rel, clientID)) {
+		return current
+	}
+	return updater.Manifest{Version: rel.PreviousVersion, BuildNumber: rel.PreviousBuildNumber, Rollback: true}
+}
+
+func (s *Server) handleSetRollout(w http.ResponseWriter, r *http.Request) {
+	var req rolloutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		hydraapi.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.Project == "" {
+		hydraapi.WriteError(w, http.StatusBadRequest, "project is required")
+		return
+	}
+	if !validEnvironments[req.Environment] {
+		hydraapi.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid environment: %q", req.Environment))
+		return
+	}
+	if !s.authorize(w, r, store.ActionPromote, req.Project, req.Environment) {
+		return
+	}
+	req.UpdatedBy = actor(r, req.UpdatedBy)
+	if !req.Halt && (req.Percent < 1 || req.Percent > 100) {
+		hydraapi.WriteError(w, http.StatusBadRequest, "percent must be between 1 and 100; use halt to stop serving the release")
+		return
+	}
+
+	rel, err := s.Releases.SetRollout(store.RolloutRequest{
+		Project:     req.Project,
+		Environment: req.Environment,
+		Percent:     req.Percent,
+		Halt:        req.Halt,
+		UpdatedBy:   req.UpdatedBy,
+	})
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.SetLatest(rel)
+
+	eventType := "release.rollout-updated"
+	percent := 100
+	if rel.Rollout != nil {
+		percent = rel.Rollout.Percent
+		if rel.Rollout.Halted {
+			eventType = "release.rollout-halted"
+		}
+	}
+	s.emit(hydramonitor.Event{
+		Type: eventType,
+		Data: map[string]any{
+			"district":         "",
+			"timestamp":        time.Now().UTC().Format("2006-01-02T15:04:05Z07:00"),
+			"project":          rel.Project,
+			"environment":      rel.Environment,
+			"build_number":     rel.BuildNumber,
+			"version":          rel.Version,
+			"previous_version": rel.PreviousVersion,
+			"percent":          percent,
+			"updated_by":       req.UpdatedBy,
+		},
+	})
+
+	hydraapi.WriteJSON(w, http.StatusOK, rel)
+}
@@ -1,11 +1,11 @@
 package api
 
 import (
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
-	"strings"
 	"sync"
 	"time"
 
@@ -13,61 +13,85 @@ import (
 	"github.com/cederikdotcom/hydraauth"
 	"github.com/cederikdotcom/hydramonitor"
 	"github.com/cederikdotcom/hydrarelease/docs"
+	"github.com/cederikdotcom/hydrarelease/internal/oidc"
+	"github.com/cederikdotcom/hydrarelease/internal/storage"
 	"github.com/cederikdotcom/hydrarelease/internal/store"
+	"github.com/cederikdotcom/hydrarelease/pkg/updater"
 )
 
-// latestInfo holds the latest version info for a project/channel.
-type latestInfo struct {
-	Version     string `json:"version"`
-	BuildNumber int    `json:"build_number,omitempty"`
-}
-
 // Server holds all dependencies for HTTP handlers.
 type Server struct {
-	Builds      *store.BuildStore
-	Releases    *store.ReleaseStore
-	Auth        *hydraauth.Auth
-	Monitor     *hydramonitor.Monitor
-	Version     string
-	MirrorURL         string // hydramirror URL for file storage and redirects
-	MirrorToken       string // bearer token for hydramirror
-	IssueTrackerURL   string // hydraissue URL for issue resolution
-	IssueTrackerToken string // bearer token for hydraissue
+	Builds            store.BuildStore
+	Releases          store.ReleaseStore
+	Retention         *store.RetentionStore
+	Pipelines         *store.PipelineStore
+	UpdateReports     *store.UpdateReportStore
+	Artifacts         *store.ArtifactStore
+	Projects          *store.ProjectStore
+	Tokens            *store.TokenStore // scoped API tokens; the admin token is handled by Auth
+	OIDC              *oidc.Verifier    // accepts CI ID tokens when set
+	Audit             *store.AuditLog   // records every write request
+	Metrics           *Metrics          // Prometheus metrics served on /metrics
+	Webhooks          *store.WebhookStore
+	Outbox            *store.OutboxStore // retried mirror links and issue resolution
+	StrictProjects    bool               // refuse builds and promotions of unregistered projects
+	UploadSessions    *store.UploadSessionStore
+	UploadSessionTTL  time.Duration      // how long a legacy publish may sit between uploads and finalize
+	AutoUpdate        *updater.AutoCheck // self-update loop, reported in health
+	Auth              *hydraauth.Auth
+	Monitor           *hydramonitor.Monitor
+	Version           string
+	Storage           storage.Storage // where release and build files live
+	IssueTrackerURL   string          // hydraissue URL for issue resolution
+	IssueTrackerToken string          // bearer token for hydraissue
+	// TrustedKeys, when set, makes finalize refuse releases without a valid
+	// SHA256SUMS signature from one of these keys.
+	TrustedKeys []ed25519.PublicKey
 
 	latestMu sync.RWMutex
-	latest   map[string]latestInfo // key: "project/channel"
+	latest   map[string]*store.Release // key: "project/channel"
+
+	// gcMu serializes garbage collection runs, which hold it exclusively.
+	// Promotions and rollbacks hold it shared so a build they have checked
+	// can't be collected before the release records it.
+	gcMu sync.RWMutex
+
+	// webhookWake nudges the delivery loop when events are queued.
+	webhookWake chan struct{}
 
-	// uploadSessions tracks SHA256 hashes for in-progress legacy publishes.
-	uploadMu       sync.Mutex
-	uploadSessions map[string]map[string]string // key: "project/channel/version" → filename → sha256
+	// reportLimit throttles anonymous update reports per client IP.
+	reportLimit ipLimiter
+
+	// outboxWake nudges the outbox worker when jobs are queued or retried.
+	outboxWake chan struct{}
 }
 
-// SetLatest updates the latest version for a project/channel.
-func (s *Server) SetLatest(project, channel, version string, buildNumber int) {
+// SetLatest updates the release served as latest for its project/channel.
+func (s *Server) SetLatest(rel *store.Release) {
 	s.latestMu.Lock()
 	defer s.latestMu.Unlock()
 	if s.latest == nil {
-		s.latest = make(map[string]latestInfo)
+		s.latest = make(map[string]*store.Release)
 	}
-	s.latest[project+"/"+channel] = latestInfo{Version: version, BuildNumber: buildNumber}
+	s.latest[rel.Project+"/"+rel.Environment] = rel
 }
 
-// GetLatest returns the latest version for a project/channel.
+// GetLatest returns the current release for a project/channel.
 // It checks the in-memory map first, then falls back to the ReleaseStore.
-func (s *Server) GetLatest(project, channel string) (latestInfo, bool) {
+func (s *Server) GetLatest(project, channel string) (*store.Release, bool) {
 	s.latestMu.RLock()
-	info, ok := s.latest[project+"/"+channel]
+	rel, ok := s.latest[project+"/"+channel]
 	s.latestMu.RUnlock()
 	if ok {
-		return info, true
+		return rel, true
 	}
 
 	// Fall back to ReleaseStore.
 	rel, err := s.Releases.Get(project, channel)
 	if err != nil {
-		return latestInfo{}, false
+		return nil, false
 	}
-	return latestInfo{Version: rel.Version, BuildNumber: rel.BuildNumber}, true
+	return rel, true
 }
 
 // InitLatest pre-populates the latest map from all current releases in the store.
@@ -78,8 +102,8 @@ func (s *Server) InitLatest() {
 		log.Printf("Warning: failed to load current releases: %v", err)
 		return
 	}
-	for _, rel := range releases {
-		s.SetLatest(rel.Project, rel.Environment, rel.Version, rel.BuildNumber)
+	for i := range releases {
+		s.SetLatest(&releases[i])
 	}
 	if len(releases) > 0 {
 		log.Printf("Pre-populated latest map with %d releases", len(releases))
@@ -95,6 +119,11 @@ func (s *Server) Handler(publishToken string, startTime time.Time) http.Handler
 		"hydrarelease", s.Version, "", startTime, s.healthExtra,
 	))
 
+	// Prometheus metrics.
+	if s.Metrics != nil {
+		mux.Handle("GET /metrics", s.Metrics.Registry)
+	}
+
 	// Runbook
 	mux.HandleFunc("GET /api/v1/runbook", func(w http.ResponseWriter, r *http.Request) {
 		data, _ := docs.Files.ReadFile("runbooks/runbook.md")
@@ -103,60 +132,123 @@ func (s *Server) Handler(publishToken string, startTime time.Time) http.Handler
 	})
 
 	// SSE events.
-	mux.HandleFunc("GET /api/v1/events", s.Auth.RequireAuth(s.Monitor.HandleEvents))
+	mux.HandleFunc("GET /api/v1/events", s.requireAction(store.ActionEventsRead, "*", s.Monitor.HandleEvents))
+
+	// Cross-project overview.
+	mux.HandleFunc("GET /api/v1/overview", s.handleOverview)
 
 	// Build endpoints.
-	mux.HandleFunc("POST /api/v1/builds", s.Auth.RequireAuth(s.handleCreateBuild))
+	mux.HandleFunc("POST /api/v1/builds", s.authenticate(s.handleCreateBuild))
+	mux.HandleFunc("POST /api/v1/builds/gc", s.requireAdmin(s.handleBuildGC))
 	mux.HandleFunc("GET /api/v1/builds", s.handleListBuilds)
 	mux.HandleFunc("GET /api/v1/builds/{project}/{number}", s.handleGetBuild)
+	mux.HandleFunc("POST /api/v1/builds/{project}/{number}/state", s.authenticate(s.handleSetBuildState))
+	mux.HandleFunc("GET /api/v1/builds/{project}/{number}/files/{path...}", s.handleBuildFile)
+
+	// Scoped API tokens (admin token only).
+	mux.HandleFunc("POST /api/v1/tokens", s.requireAdmin(s.handleCreateToken))
+	mux.HandleFunc("GET /api/v1/tokens", s.requireAdmin(s.handleListTokens))
+	mux.HandleFunc("DELETE /api/v1/tokens/{id}", s.requireAdmin(s.handleRevokeToken))
+
+	// Audit log (admin token only).
+	mux.HandleFunc("GET /api/v1/audit", s.requireAdmin(s.handleListAudit))
+	mux.HandleFunc("GET /api/v1/audit/verify", s.requireAdmin(s.handleVerifyAudit))
+
+	// Webhook subscriptions (admin token only).
+	mux.HandleFunc("POST /api/v1/webhooks", s.requireAdmin(s.handleCreateWebhook))
+	mux.HandleFunc("GET /api/v1/webhooks", s.requireAdmin(s.handleListWebhooks))
+	mux.HandleFunc("DELETE /api/v1/webhooks/{id}", s.requireAdmin(s.handleDeleteWebhook))
+	mux.HandleFunc("GET /api/v1/webhooks/{id}/deliveries", s.requireAdmin(s.handleListWebhookDeliveries))
+
+	// Outbox of retried side effects (admin token only).
+	mux.HandleFunc("GET /api/v1/outbox", s.requireAdmin(s.handleListOutbox))
+	mux.HandleFunc("POST /api/v1/outbox/{id}/retry", s.requireAdmin(s.handleRetryOutboxJob))
+
+	// Project registry.
+	mux.HandleFunc("GET /api/v1/projects", s.handleListProjects)
+	mux.HandleFunc("GET /api/v1/projects/{name}", s.handleGetProject)
+	mux.HandleFunc("POST /api/v1/projects", s.requireAdmin(s.handleCreateProject))
+	mux.HandleFunc("PATCH /api/v1/projects/{name}", s.requireAdmin(s.handleUpdateProject))
+
+	// Content-addressed artifact uploads (build submit).
+	mux.HandleFunc("HEAD /api/v1/artifacts/{sha256}", s.requireAction(store.ActionBuildSubmit, "", s.handleHeadArtifact))
+	mux.HandleFunc("PUT /api/v1/artifacts/{sha256}", s.requireAction(store.ActionBuildSubmit, "", s.handleUploadArtifact))
 
 	// Release endpoints.
-	mux.HandleFunc("POST /api/v1/releases", s.Auth.RequireAuth(s.handlePromoteRelease))
-	mux.HandleFunc("POST /api/v1/releases/rollback", s.Auth.RequireAuth(s.handleRollbackRelease))
+	mux.HandleFunc("POST /api/v1/releases", s.authenticate(s.handlePromoteRelease))
+	mux.HandleFunc("POST /api/v1/releases/rollback", s.authenticate(s.handleRollbackRelease))
+	mux.HandleFunc("POST /api/v1/releases/rollout", s.authenticate(s.handleSetRollout))
 	mux.HandleFunc("GET /api/v1/releases", s.handleListReleases)
 	mux.HandleFunc("GET /api/v1/releases/{project}/{env}", s.handleGetRelease)
 
+	// Promotion pipeline endpoints.
+	mux.HandleFunc("GET /api/v1/pipelines/{project}", s.handleGetPipeline)
+	mux.HandleFunc("PUT /api/v1/pipelines/{project}", s.requireAdmin(s.handleSetPipeline))
+	mux.HandleFunc("DELETE /api/v1/pipelines/{project}", s.requireAdmin(s.handleDeletePipeline))
+
+	// Update reports (posted by pkg/updater after reverting a bad update).
+	mux.HandleFunc("POST /api/v1/update-reports", s.handleCreateUpdateReport)
+	mux.HandleFunc("GET /api/v1/update-reports", s.handleListUpdateReports)
+
 	// Legacy publish endpoints (backward compat for existing CI).
 	if publishToken != "" {
 		mux.HandleFunc("POST /api/v1/publish/{project}/{channel}/{version}/finalize",
-			s.Auth.RequireAuth(s.handleFinalize))
+			s.authenticate(s.handleFinalize))
 		mux.HandleFunc("POST /api/v1/publish/{project}/{channel}/{version}/{binary}",
-			s.Auth.RequireAuth(s.handleUploadBinary))
+			s.authenticate(s.handleUploadBinary))
+		mux.HandleFunc("GET /api/v1/publish/sessions", s.requireAdmin(s.handleListUploadSessions))
+		mux.HandleFunc("DELETE /api/v1/publish/sessions/{project}/{channel}/{version}",
+			s.requireAdmin(s.handleAbortUploadSession))
 	}
 
-	// File serving via redirects to hydramirror.
+	// File serving: redirects to hydramirror, or served from local disk.
 	mux.HandleFunc("GET /{project}/{channel}/latest.json", s.handleLatestJSON)
-	mux.HandleFunc("GET /{project}/{channel}/{version}/{file}", s.handleFileRedirect)
+	mux.HandleFunc("GET /{project}/{channel}/{version}/{file}", s.handleFile)
 
-	return mux
+	return s.Metrics.instrumentHTTP(s.auditWrites(mux))
 }
 
 // handleLatestJSON serves latest.json from the in-memory latest map or ReleaseStore.
+// During a staged rollout the version depends on the client ID the updater sends.
+// The document is an updater.Manifest; clients that predate it read only the
+// version fields.
 func (s *Server) handleLatestJSON(w http.ResponseWriter, r *http.Request) {
 	project := r.PathValue("project")
 	channel := r.PathValue("channel")
 
-	info, ok := s.GetLatest(project, channel)
+	rel, ok := s.GetLatest(project, channel)
 	if !ok {
 		hydraapi.WriteError(w, http.StatusNotFound, fmt.Sprintf("no release found for %s/%s", project, channel))
 		return
 	}
 
+	s.Metrics.countLatest(project, channel)
+	info := s.releaseManifest(r, rel, r.Header.Get(updater.ClientIDHeader))
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(info)
 }
 
-// handleFileRedirect returns a 302 redirect to hydramirror for file downloads.
-func (s *Server) handleFileRedirect(w http.ResponseWriter, r *http.Request) {
+// handleFile serves a release file from storage. With hydramirror this is a
+// 302 redirect; local storage serves the file itself, with Range support.
+func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
 	project := r.PathValue("project")
 	channel := r.PathValue("channel")
 	version := r.PathValue("version")
 	file := r.PathValue("file")
 
-	mirrorPath := fmt.Sprintf("releases/%s/%s/%s/%s", project, channel, version, file)
-	redirectURL := strings.TrimRight(s.MirrorURL, "/") + "/api/v1/files/" + mirrorPath
+	if s.Storage == nil {
+		hydraapi.WriteError(w, http.StatusServiceUnavailable, "storage not configured")
+		return
+	}
+
+	// Only count projects with a release so that labels stay bounded.
+	if _, ok := s.GetLatest(project, channel); ok {
+		s.Metrics.countDownload(project, channel)
+	}
 
-	http.Redirect(w, r, redirectURL, http.StatusFound)
+	storagePath := fmt.Sprintf("releases/%s/%s/%s/%s", project, channel, version, file)
+	s.Storage.Serve(w, r, storagePath)
 }
 
 func (s *Server) healthExtra() map[string]any {
@@ -173,5 +265,13 @@ func (s *Server) healthExtra() map[string]any {
 		extra["release_count"] = releaseCount
 	}
 
+	if s.Storage != nil {
+		extra["storage"] = s.Storage.Name()
+	}
+
+	if s.AutoUpdate != nil {
+		extra["auto_update"] = s.AutoUpdate.Status()
+	}
+
 	return extra
 }
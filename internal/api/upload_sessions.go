@@ -0,0 +1,78 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cederikdotcom/hydraapi"
+)
+
+// defaultUploadSessionTTL applies when Server.UploadSessionTTL is unset.
+const defaultUploadSessionTTL = 24 * time.Hour
+
+func (s *Server) uploadSessionTTL() time.Duration {
+	if s.UploadSessionTTL > 0 {
+		return s.UploadSessionTTL
+	}
+	return defaultUploadSessionTTL
+}
+
+// missingFiles returns the names in expected that have no uploaded file.
+func missingFiles(files map[string]string, expected []string) []string {
+	var missing []string
+	for _, name := range expected {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := files[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// StartUploadSessionExpiry removes expired upload sessions every interval.
+// Files already pushed to hydramirror for an expired session are left in place.
+func (s *Server) StartUploadSessionExpiry(interval time.Duration) {
+	go func() {
+		for {
+			time.Sleep(interval)
+
+			expired, err := s.UploadSessions.Expire(time.Now())
+			if err != nil {
+				log.Printf("[upload-sessions] expiry failed: %v", err)
+				continue
+			}
+			for _, u := range expired {
+				log.Printf("[upload-sessions] expired %s (%d files, never finalized)", u.Key(), len(u.Files))
+			}
+		}
+	}()
+}
+
+func (s *Server) handleListUploadSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := s.UploadSessions.List()
+	if err != nil {
+		hydraapi.WriteError(w, http.StatusInternalServerError, "failed to list upload sessions")
+		return
+	}
+
+	hydraapi.WriteJSON(w, http.StatusOK, sessions)
+}
+
+func (s *Server) handleAbortUploadSession(w http.ResponseWriter, r *http.Request) {
+	project := r.PathValue("project")
+	channel := r.PathValue("channel")
+	version := r.PathValue("version")
+
+	if err := s.UploadSessions.Delete(project, channel, version); err != nil {
+		hydraapi.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	log.Printf("publish: aborted upload session %s/%s/%s", project, channel, version)
+	hydraapi.WriteJSON(w, http.StatusOK, map[string]string{"status": "aborted"})
+}
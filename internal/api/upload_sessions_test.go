@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cederikdotcom/hydrarelease/internal/storage"
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+)
+
+func TestFinalizeAndAbortRemoveSession(t *testing.T) {
+	dir := t.TempDir()
+	s := &Server{
+		Releases:       store.NewYAMLReleaseStore(dir),
+		UploadSessions: store.NewUploadSessionStore(dir),
+		Storage:        storage.NewLocal(filepath.Join(dir, "files")),
+	}
+	for _, version := range []string{"1.0.0", "1.1.0"} {
+		if _, err := s.UploadSessions.AddFile("app", "production", version, "app-linux-amd64", "aa", 1, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	serve := func(h http.HandlerFunc, target, version string) int {
+		r := httptest.NewRequest("POST", target, nil)
+		r.SetPathValue("project", "app")
+		r.SetPathValue("channel", "production")
+		r.SetPathValue("version", version)
+		w := httptest.NewRecorder()
+		h(w, withIdentity(r, &identity{Name: "admin"}))
+		return w.Code
+	}
+
+	// A finalize refused for missing files keeps the session for a retry.
+	if code := serve(s.handleFinalize, "/?expect=app-linux-amd64,app-darwin-arm64", "1.0.0"); code != http.StatusConflict {
+		t.Fatalf("incomplete finalize = %d, want 409", code)
+	}
+	if u, _ := s.UploadSessions.Get("app", "production", "1.0.0"); u == nil {
+		t.Fatal("refused finalize dropped the session")
+	}
+
+	if code := serve(s.handleFinalize, "/", "1.0.0"); code != http.StatusOK {
+		t.Fatalf("finalize = %d, want 200", code)
+	}
+	if u, _ := s.UploadSessions.Get("app", "production", "1.0.0"); u != nil {
+		t.Errorf("session kept after finalize: %+v", u)
+	}
+
+	if code := serve(s.handleAbortUploadSession, "/", "1.1.0"); code != http.StatusOK {
+		t.Fatalf("abort = %d, want 200", code)
+	}
+	if u, _ := s.UploadSessions.Get("app", "production", "1.1.0"); u != nil {
+		t.Errorf("session kept after abort: %+v", u)
+	}
+	if code := serve(s.handleAbortUploadSession, "/", "1.1.0"); code != http.StatusNotFound {
+		t.Errorf("second abort = %d, want 404", code)
+	}
+}
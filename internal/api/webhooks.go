@@ -0,0 +1,175 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cederikdotcom/hydramonitor"
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+)
+
+// webhookMaxAttempts is how often a delivery is tried before it is marked
+// failed; with retryBackoff that spans about an hour.
+const webhookMaxAttempts = 8
+
+// webhookPayload is the JSON body POSTed to webhooks. The delivery ID is
+// sent in the X-Hydrarelease-Delivery header.
+type webhookPayload struct {
+	Event     string         `json:"event"`
+	Timestamp time.Time      `json:"timestamp"`
+	Data      map[string]any `json:"data"`
+}
+
+// signWebhook returns the X-Hydrarelease-Signature value for payload.
+func signWebhook(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// emit publishes an event to SSE subscribers and queues it for every
+// webhook subscribed to it.
+func (s *Server) emit(e hydramonitor.Event) {
+	s.Monitor.Emit(e)
+	s.queueWebhooks(e)
+}
+
+func (s *Server) queueWebhooks(e hydramonitor.Event) {
+	if s.Webhooks == nil {
+		return
+	}
+	hooks, err := s.Webhooks.List()
+	if err != nil {
+		log.Printf("[webhooks] failed to load webhooks: %v", err)
+		return
+	}
+
+	project, _ := e.Data["project"].(string)
+	data := make(map[string]any, len(e.Data))
+	for k, v := range e.Data {
+		if k != "district" {
+			data[k] = v
+		}
+	}
+
+	var deliveries []*store.WebhookDelivery
+	for _, h := range hooks {
+		if h.Matches(e.Type, project) {
+			deliveries = append(deliveries, &store.WebhookDelivery{WebhookID: h.ID, Event: e.Type})
+		}
+	}
+	if len(deliveries) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(webhookPayload{Event: e.Type, Timestamp: time.Now().UTC(), Data: data})
+	if err != nil {
+		log.Printf("[webhooks] failed to encode %s: %v", e.Type, err)
+		return
+	}
+	for _, d := range deliveries {
+		d.Payload = string(payload)
+	}
+	if err := s.Webhooks.Enqueue(deliveries); err != nil {
+		log.Printf("[webhooks] failed to queue %s: %v", e.Type, err)
+		return
+	}
+
+	select {
+	case s.webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+// StartWebhooks delivers queued webhook events in the background, checking
+// for due retries every interval and immediately after new events.
+func (s *Server) StartWebhooks(interval time.Duration) {
+	s.webhookWake = make(chan struct{}, 1)
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-s.webhookWake:
+			}
+			s.deliverWebhooks(client)
+		}
+	}()
+}
+
+func (s *Server) deliverWebhooks(client *http.Client) {
+	due, err := s.Webhooks.Due(time.Now())
+	if err != nil {
+		log.Printf("[webhooks] failed to read queue: %v", err)
+		return
+	}
+	for i := range due {
+		d := &due[i]
+		h, err := s.Webhooks.Get(d.WebhookID)
+		if err != nil {
+			log.Printf("[webhooks] failed to load webhook %s: %v", d.WebhookID, err)
+			continue
+		}
+		if h == nil {
+			continue
+		}
+
+		status, err := postWebhook(client, h, d, s.Version)
+		d.Attempts++
+		d.LastStatus = status
+		d.LastError = ""
+		now := time.Now().UTC()
+		switch {
+		case err == nil:
+			d.Status = store.DeliveryDelivered
+			d.DeliveredAt = &now
+		case d.Attempts >= webhookMaxAttempts:
+			d.Status = store.DeliveryFailed
+			d.LastError = err.Error()
+			log.Printf("[webhooks] giving up on %s to %s after %d attempts: %v", d.Event, h.URL, d.Attempts, err)
+		default:
+			d.LastError = err.Error()
+			d.NextAttempt = now.Add(retryBackoff(d.Attempts))
+		}
+		s.Metrics.countWebhookDelivery(err)
+		if err := s.Webhooks.UpdateDelivery(d); err != nil {
+			log.Printf("[webhooks] failed to record delivery %s: %v", d.ID, err)
+		}
+	}
+}
+
+// postWebhook sends one delivery and returns the HTTP status, if any.
+func postWebhook(client *http.Client, h *store.Webhook, d *store.WebhookDelivery, version string) (int, error) {
+	req, err := http.NewRequest("POST", h.URL, bytes.NewReader([]byte(d.Payload)))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "hydrarelease/"+version)
+	req.Header.Set("X-Hydrarelease-Event", d.Event)
+	req.Header.Set("X-Hydrarelease-Delivery", d.ID)
+	req.Header.Set("X-Hydrarelease-Signature", signWebhook(h.Secret, d.Payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("%s returned %d", h.URL, resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditServer  string
+	auditToken   string
+	auditActor   string
+	auditProject string
+	auditAction  string
+	auditOutcome string
+	auditSince   string
+	auditUntil   string
+	auditLimit   int
+	auditOutput  string
+	auditJSON    bool
+)
+
+// auditQuery builds the /api/v1/audit query from the filter flags.
+func auditQuery(limit int, format string) string {
+	q := url.Values{}
+	for k, v := range map[string]string{
+		"actor":   auditActor,
+		"project": auditProject,
+		"action":  auditAction,
+		"outcome": auditOutcome,
+		"since":   auditSince,
+		"until":   auditUntil,
+		"format":  format,
+	} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	q.Set("limit", strconv.Itoa(limit))
+	return "/api/v1/audit?" + q.Encode()
+}
+
+func auditFiltered() bool {
+	return auditActor != "" || auditProject != "" || auditAction != "" || auditOutcome != "" || auditSince != "" || auditUntil != ""
+}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspect the audit log of write requests",
+}
+
+var auditListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recent audit entries",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := resolveToken(auditToken)
+		if token == "" {
+			return fmt.Errorf("auth token required: use --token or HYDRARELEASE_AUTH_TOKEN env")
+		}
+
+		resp, err := doJSON(auditServer, token, "GET", auditQuery(auditLimit, ""), nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("audit query failed (%d)", resp.StatusCode)
+		}
+		var entries []store.AuditEntry
+		if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+			return fmt.Errorf("parsing audit entries: %w", err)
+		}
+
+		if auditJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(entries)
+		}
+
+		if len(entries) == 0 {
+			fmt.Println("No audit entries.")
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "SEQ\tTIME\tACTOR\tACTION\tTARGET\tSTATUS\tERROR\n")
+		for _, e := range entries {
+			target := e.Project
+			if e.Environment != "" {
+				target += "/" + e.Environment
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%d\t%s\n",
+				e.Seq, e.Time.Local().Format("2006-01-02 15:04:05"), e.Actor, e.Action, orDash(target), e.Status, orDash(e.Error))
+		}
+		return tw.Flush()
+	},
+}
+
+var auditExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the audit log as JSON lines",
+	Long: `Downloads audit entries as JSON lines, one entry per line as stored
+on the server. Without filters the whole log is exported and its hash chain
+is verified while downloading:
+
+  hydrarelease audit export --output audit-2026-10.jsonl
+  hydrarelease audit export --project hydraguard --since 2026-10-01T00:00:00Z`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := resolveToken(auditToken)
+		if token == "" {
+			return fmt.Errorf("auth token required: use --token or HYDRARELEASE_AUTH_TOKEN env")
+		}
+
+		resp, err := doJSON(auditServer, token, "GET", auditQuery(0, "jsonl"), nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("audit export failed (%d)", resp.StatusCode)
+		}
+
+		var out io.Writer = os.Stdout
+		if auditOutput != "" {
+			f, err := os.Create(auditOutput)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+
+		verify := !auditFiltered()
+		var n int64
+		prev := ""
+		sc := bufio.NewScanner(resp.Body)
+		sc.Buffer(make([]byte, 64*1024), 1<<20)
+		for sc.Scan() {
+			line := sc.Bytes()
+			if verify {
+				var e store.AuditEntry
+				if err := json.Unmarshal(line, &e); err != nil {
+					return fmt.Errorf("parsing audit entry %d: %w", n+1, err)
+				}
+				if err := store.VerifyAuditLink(&e, prev, n+1); err != nil {
+					return fmt.Errorf("audit chain broken: %w", err)
+				}
+				prev = e.Hash
+			}
+			n++
+			if _, err := out.Write(append(line, '\n')); err != nil {
+				return err
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return fmt.Errorf("reading audit export: %w", err)
+		}
+
+		if verify {
+			fmt.Fprintf(os.Stderr, "Exported %d entries; hash chain verified.\n", n)
+		} else {
+			fmt.Fprintf(os.Stderr, "Exported %d entries (filtered; hash chain not checked).\n", n)
+		}
+		return nil
+	},
+}
+
+func init() {
+	auditCmd.PersistentFlags().StringVar(&auditServer, "server", "https://releases.experiencenet.com", "release server URL")
+	auditCmd.PersistentFlags().StringVar(&auditToken, "token", "", "admin bearer token (or HYDRARELEASE_AUTH_TOKEN env)")
+	auditCmd.PersistentFlags().StringVar(&auditActor, "actor", "", "only entries by this actor (e.g. admin, token:ci-app)")
+	auditCmd.PersistentFlags().StringVar(&auditProject, "project", "", "only entries for this project")
+	auditCmd.PersistentFlags().StringVar(&auditAction, "action", "", "only this route, e.g. \"POST /api/v1/releases\"")
+	auditCmd.PersistentFlags().StringVar(&auditOutcome, "outcome", "", "only ok or error entries")
+	auditCmd.PersistentFlags().StringVar(&auditSince, "since", "", "only entries at or after this time (RFC3339)")
+	auditCmd.PersistentFlags().StringVar(&auditUntil, "until", "", "only entries before this time (RFC3339)")
+
+	auditListCmd.Flags().IntVar(&auditLimit, "limit", 50, "number of newest entries to show (0 for all)")
+	auditListCmd.Flags().BoolVar(&auditJSON, "json", false, "output as JSON")
+	auditExportCmd.Flags().StringVar(&auditOutput, "output", "", "file to write (default stdout)")
+
+	auditCmd.AddCommand(auditListCmd, auditExportCmd)
+	rootCmd.AddCommand(auditCmd)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"text/tabwriter"
@@ -20,6 +21,19 @@ var (
 	buildSourceRef  string
 	buildNumber     int
 	buildJSON       bool
+	buildGCDryRun   bool
+	buildParallel   int
+	buildRetries    int
+	buildNoUpload   bool
+	buildPending    bool
+	buildState      string
+	buildReason     string
+	buildRollback   bool
+	buildSince      string
+	buildUntil      string
+	buildLimit      int
+	buildCursor     string
+	buildOrder      string
 )
 
 var buildCmd = &cobra.Command{
@@ -29,8 +43,13 @@ var buildCmd = &cobra.Command{
 
 var buildSubmitCmd = &cobra.Command{
 	Use:   "submit [flags] <file> [file...]",
-	Short: "Submit a new build",
-	Args:  cobra.MinimumNArgs(1),
+	Short: "Upload files and submit a new build",
+	Long: `Hashes every file, uploads it to the release server (which stores it on
+hydramirror by SHA256), and registers the build once all uploads are verified.
+
+Files the server already has are skipped, so rerunning the same command after
+a partial failure resumes where it left off.`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		token := resolveToken(buildToken)
 		if token == "" {
@@ -40,22 +59,50 @@ var buildSubmitCmd = &cobra.Command{
 			return fmt.Errorf("--project is required")
 		}
 
+		if buildParallel < 1 {
+			buildParallel = 1
+		}
+
+		artifacts, err := hashFiles(args, buildParallel)
+		if err != nil {
+			return err
+		}
+		seen := make(map[string]bool)
+		for _, a := range artifacts {
+			if seen[a.Name] {
+				return fmt.Errorf("duplicate file name %s", a.Name)
+			}
+			seen[a.Name] = true
+		}
+
+		if !buildNoUpload {
+			up := &uploader{
+				server:   buildServer,
+				token:    token,
+				parallel: buildParallel,
+				retries:  buildRetries,
+				quiet:    buildJSON,
+			}
+			if failed := up.uploadAll(artifacts); len(failed) > 0 {
+				return fmt.Errorf("%d of %d uploads failed (%s); rerun the same command to resume",
+					len(failed), len(artifacts), strings.Join(failed, ", "))
+			}
+		}
+
 		// Build the file list for the request.
 		type fileEntry struct {
-			Path   string `json:"path"`
-			SHA256 string `json:"sha256"`
-			Size   int64  `json:"size"`
+			Path       string `json:"path"`
+			SHA256     string `json:"sha256"`
+			Size       int64  `json:"size"`
+			MirrorPath string `json:"mirror_path,omitempty"`
 		}
 		var files []fileEntry
-		for _, path := range args {
-			info, err := os.Stat(path)
-			if err != nil {
-				return fmt.Errorf("stat %s: %w", path, err)
+		for _, a := range artifacts {
+			f := fileEntry{Path: a.Name, SHA256: a.SHA256, Size: a.Size}
+			if !buildNoUpload {
+				f.MirrorPath = "artifacts/" + a.SHA256
 			}
-			files = append(files, fileEntry{
-				Path: info.Name(),
-				Size: info.Size(),
-			})
+			files = append(files, f)
 		}
 
 		body := map[string]any{
@@ -69,6 +116,9 @@ var buildSubmitCmd = &cobra.Command{
 		if buildSourceRef != "" {
 			body["source_ref"] = buildSourceRef
 		}
+		if buildPending {
+			body["state"] = "pending"
+		}
 
 		resp, err := doJSON(buildServer, token, "POST", "/api/v1/builds", body)
 		if err != nil {
@@ -89,7 +139,7 @@ var buildSubmitCmd = &cobra.Command{
 			return enc.Encode(result)
 		}
 
-		fmt.Printf("Build %s/#%.0f submitted\n", buildProject, result["build_number"])
+		fmt.Printf("Build %s/#%.0f submitted (%v)\n", buildProject, result["build_number"], result["state"])
 		return nil
 	},
 }
@@ -102,14 +152,28 @@ var buildListCmd = &cobra.Command{
 			return fmt.Errorf("--project is required")
 		}
 
-		url := fmt.Sprintf("%s/api/v1/builds?project=%s",
-			strings.TrimRight(buildServer, "/"), buildProject)
-
-		resp, err := http.Get(url)
+		q := url.Values{"project": {buildProject}, "limit": {fmt.Sprint(buildLimit)}}
+		setQuery(q, map[string]string{
+			"state":       buildState,
+			"uploaded_by": buildUploadedBy,
+			"source":      buildSource,
+			"source_ref":  buildSourceRef,
+			"since":       buildSince,
+			"until":       buildUntil,
+			"order":       buildOrder,
+			"cursor":      buildCursor,
+		})
+
+		resp, err := http.Get(strings.TrimRight(buildServer, "/") + "/api/v1/builds?" + q.Encode())
 		if err != nil {
 			return fmt.Errorf("request failed: %w", err)
 		}
 		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			var result map[string]any
+			json.NewDecoder(resp.Body).Decode(&result)
+			return fmt.Errorf("list failed (%d): %v", resp.StatusCode, result["error"])
+		}
 
 		var builds []map[string]any
 		json.NewDecoder(resp.Body).Decode(&builds)
@@ -126,12 +190,17 @@ var buildListCmd = &cobra.Command{
 		}
 
 		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
-		fmt.Fprintf(tw, "BUILD\tUPLOADED BY\tFILES\tUPLOADED AT\n")
+		fmt.Fprintf(tw, "BUILD\tSTATE\tUPLOADED BY\tFILES\tUPLOADED AT\tMIRROR LINKS\n")
 		for _, b := range builds {
-			fmt.Fprintf(tw, "#%.0f\t%s\t%.0f\t%s\n",
-				b["build_number"], b["uploaded_by"], b["file_count"], b["uploaded_at"])
+			links, _ := b["mirror_links"].(string)
+			fmt.Fprintf(tw, "#%.0f\t%s\t%s\t%.0f\t%s\t%s\n",
+				b["build_number"], b["state"], b["uploaded_by"], b["file_count"], b["uploaded_at"], orDash(links))
 		}
-		return tw.Flush()
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		printNextCursor(resp)
+		return nil
 	},
 }
 
@@ -168,6 +237,151 @@ var buildShowCmd = &cobra.Command{
 	},
 }
 
+var buildStateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "Change a build's lifecycle state",
+	Long: `Moves a build to complete, failed or yanked. Pending builds become
+complete or failed; complete builds can be yanked, and yanked builds made
+complete again. Only complete builds can be promoted or rolled back to.
+
+Yanking a build that is live in an environment needs --rollback, which first
+rolls each such environment back to its previous complete build:
+
+  hydrarelease build state --project hydraguard --build 42 --set complete
+  hydrarelease build state --project hydraguard --build 41 --set yanked \
+    --reason "crashes on startup" --rollback`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := resolveToken(buildToken)
+		if token == "" {
+			return fmt.Errorf("auth token required: use --token or HYDRARELEASE_AUTH_TOKEN env")
+		}
+		if buildProject == "" {
+			return fmt.Errorf("--project is required")
+		}
+		if buildNumber <= 0 {
+			return fmt.Errorf("--build is required")
+		}
+		if buildState == "" {
+			return fmt.Errorf("--set is required")
+		}
+
+		body := map[string]any{
+			"state":            buildState,
+			"reason":           buildReason,
+			"confirm_rollback": buildRollback,
+		}
+		path := fmt.Sprintf("/api/v1/builds/%s/%d/state", buildProject, buildNumber)
+		resp, err := doJSON(buildServer, token, "POST", path, body)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		var result struct {
+			State      string   `json:"state"`
+			Error      string   `json:"error"`
+			LiveIn     []string `json:"live_in"`
+			RolledBack []struct {
+				Environment string `json:"environment"`
+				BuildNumber int    `json:"build_number"`
+				Version     string `json:"version"`
+			} `json:"rolled_back"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+
+		if resp.StatusCode != http.StatusOK {
+			if len(result.LiveIn) > 0 {
+				return fmt.Errorf("%s\nrerun with --rollback to roll back %s first", result.Error, strings.Join(result.LiveIn, ", "))
+			}
+			return fmt.Errorf("state change failed (%d): %s", resp.StatusCode, result.Error)
+		}
+
+		for _, rel := range result.RolledBack {
+			fmt.Printf("Rolled back %s/%s to build #%d (v%s)\n", buildProject, rel.Environment, rel.BuildNumber, rel.Version)
+		}
+		fmt.Printf("Build %s/#%d is now %s\n", buildProject, buildNumber, result.State)
+		return nil
+	},
+}
+
+var buildGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete builds outside their project's retention policy",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := resolveToken(buildToken)
+		if token == "" {
+			return fmt.Errorf("auth token required: use --token or HYDRARELEASE_AUTH_TOKEN env")
+		}
+
+		query := url.Values{}
+		if buildProject != "" {
+			query.Set("project", buildProject)
+		}
+		if buildGCDryRun {
+			query.Set("dry_run", "true")
+		}
+
+		resp, err := doJSON(buildServer, token, "POST", "/api/v1/builds/gc?"+query.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		var result struct {
+			DryRun           bool             `json:"dry_run"`
+			Deleted          []map[string]any `json:"deleted"`
+			DeletedArtifacts []struct {
+				SHA256 string `json:"sha256"`
+				Size   int64  `json:"size"`
+			} `json:"deleted_artifacts"`
+			Failed []string `json:"failed"`
+			Error  string   `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("gc failed (%d): %s", resp.StatusCode, result.Error)
+		}
+
+		if buildJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(result)
+		}
+
+		verb := "Deleted"
+		if result.DryRun {
+			verb = "Would delete"
+		}
+		if len(result.Deleted) == 0 {
+			fmt.Println("No builds to delete.")
+		} else {
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintf(tw, "PROJECT\tBUILD\tUPLOADED AT\n")
+			for _, b := range result.Deleted {
+				fmt.Fprintf(tw, "%s\t#%.0f\t%s\n", b["project"], b["build_number"], b["uploaded_at"])
+			}
+			tw.Flush()
+			fmt.Printf("%s %d builds\n", verb, len(result.Deleted))
+		}
+		if len(result.DeletedArtifacts) > 0 {
+			var freed int64
+			for _, a := range result.DeletedArtifacts {
+				freed += a.Size
+			}
+			fmt.Printf("%s %d unreferenced artifacts (%d bytes)\n", verb, len(result.DeletedArtifacts), freed)
+		}
+
+		for _, f := range result.Failed {
+			fmt.Fprintf(os.Stderr, "failed: %s\n", f)
+		}
+		if len(result.Failed) > 0 {
+			return fmt.Errorf("%d builds or artifacts could not be deleted", len(result.Failed))
+		}
+		return nil
+	},
+}
+
 func init() {
 	// Common flags for build commands.
 	buildCmd.PersistentFlags().StringVar(&buildServer, "server", "https://releases.experiencenet.com", "release server URL")
@@ -178,9 +392,27 @@ func init() {
 	buildSubmitCmd.Flags().StringVar(&buildUploadedBy, "uploaded-by", "", "who uploaded the build")
 	buildSubmitCmd.Flags().StringVar(&buildSource, "source", "", "source system (e.g. perforce, git)")
 	buildSubmitCmd.Flags().StringVar(&buildSourceRef, "source-ref", "", "source reference (e.g. changelist, commit SHA)")
+	buildSubmitCmd.Flags().IntVar(&buildParallel, "parallel", 4, "number of files to hash and upload concurrently")
+	buildSubmitCmd.Flags().IntVar(&buildRetries, "retries", 3, "retries per file before giving up")
+	buildSubmitCmd.Flags().BoolVar(&buildNoUpload, "no-upload", false, "only register file names, sizes and hashes")
+	buildSubmitCmd.Flags().BoolVar(&buildPending, "pending", false, "register the build as pending; mark it complete later with build state")
+	buildListCmd.Flags().StringVar(&buildState, "state", "", "only builds in this state, or all (default hides yanked builds)")
+	buildListCmd.Flags().StringVar(&buildUploadedBy, "uploaded-by", "", "only builds uploaded by this user")
+	buildListCmd.Flags().StringVar(&buildSource, "source", "", "only builds from this source system")
+	buildListCmd.Flags().StringVar(&buildSourceRef, "source-ref", "", "only builds with this source reference")
+	buildListCmd.Flags().StringVar(&buildSince, "since", "", "only builds uploaded at or after this RFC3339 time")
+	buildListCmd.Flags().StringVar(&buildUntil, "until", "", "only builds uploaded before this RFC3339 time")
+	buildListCmd.Flags().IntVar(&buildLimit, "limit", 50, "maximum number of builds to show (0 for all)")
+	buildListCmd.Flags().StringVar(&buildOrder, "order", "", "newest (default) or oldest first")
+	buildListCmd.Flags().StringVar(&buildCursor, "cursor", "", "continue a previous listing from this cursor")
 	buildShowCmd.Flags().IntVar(&buildNumber, "build", 0, "build number")
+	buildStateCmd.Flags().IntVar(&buildNumber, "build", 0, "build number")
+	buildStateCmd.Flags().StringVar(&buildState, "set", "", "new state: complete, failed or yanked")
+	buildStateCmd.Flags().StringVar(&buildReason, "reason", "", "why the state changed")
+	buildStateCmd.Flags().BoolVar(&buildRollback, "rollback", false, "roll back environments where a yanked build is live")
+	buildGCCmd.Flags().BoolVar(&buildGCDryRun, "dry-run", false, "only report which builds would be deleted")
 
-	buildCmd.AddCommand(buildSubmitCmd, buildListCmd, buildShowCmd)
+	buildCmd.AddCommand(buildSubmitCmd, buildListCmd, buildShowCmd, buildStateCmd, buildGCCmd)
 	rootCmd.AddCommand(buildCmd)
 }
 
@@ -219,3 +451,19 @@ func doJSON(server, token, method, path string, body any) (*http.Response, error
 
 	return http.DefaultClient.Do(req)
 }
+
+// setQuery adds the non-empty values to q.
+func setQuery(q url.Values, values map[string]string) {
+	for k, v := range values {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+}
+
+// printNextCursor tells the user how to fetch the next page of a listing.
+func printNextCursor(resp *http.Response) {
+	if next := resp.Header.Get("X-Next-Cursor"); next != "" {
+		fmt.Fprintf(os.Stderr, "\nMore results: rerun with --cursor %s\n", next)
+	}
+}
@@ -47,7 +47,17 @@ var updateCmd = &cobra.Command{
 			return nil
 		}
 
-		fmt.Println("\nA new version is available!")
+		if info.Downgrade {
+			fmt.Println("\nThe release server rolled back to an older version.")
+		} else {
+			fmt.Println("\nA new version is available!")
+		}
+		if info.Required {
+			fmt.Printf("Version %s is no longer supported (minimum %s).\n", info.CurrentVersion, info.MinVersion)
+		}
+		if info.ReleaseNotes != "" {
+			fmt.Printf("\nRelease notes:\n%s\n", info.ReleaseNotes)
+		}
 
 		fmt.Print("\nUpdate now? (yes/no): ")
 		var response string
@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	outboxServer string
+	outboxToken  string
+	outboxKind   string
+	outboxStatus string
+	outboxKey    string
+	outboxID     string
+	outboxFailed bool
+	outboxJSON   bool
+)
+
+type outboxJob struct {
+	ID          string    `json:"id"`
+	Kind        string    `json:"kind"`
+	Key         string    `json:"key"`
+	Payload     string    `json:"payload"`
+	Status      string    `json:"status"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+	LastError   string    `json:"last_error"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func fetchOutbox(token, kind, status, key string) ([]outboxJob, error) {
+	q := url.Values{}
+	for k, v := range map[string]string{"kind": kind, "status": status, "key": key} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	resp, err := doJSON(outboxServer, token, "GET", "/api/v1/outbox?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("outbox query failed (%d)", resp.StatusCode)
+	}
+	var jobs []outboxJob
+	if err := json.NewDecoder(resp.Body).Decode(&jobs); err != nil {
+		return nil, fmt.Errorf("parsing outbox: %w", err)
+	}
+	return jobs, nil
+}
+
+var outboxCmd = &cobra.Command{
+	Use:   "outbox",
+	Short: "Inspect and retry queued mirror links and issue resolutions",
+}
+
+var outboxListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List outbox jobs",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := resolveToken(outboxToken)
+		if token == "" {
+			return fmt.Errorf("auth token required: use --token or HYDRARELEASE_AUTH_TOKEN env")
+		}
+
+		jobs, err := fetchOutbox(token, outboxKind, outboxStatus, outboxKey)
+		if err != nil {
+			return err
+		}
+
+		if outboxJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(jobs)
+		}
+
+		if len(jobs) == 0 {
+			fmt.Println("No outbox jobs.")
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "ID\tKIND\tKEY\tCREATED\tSTATUS\tATTEMPTS\tNEXT\tLAST ERROR\n")
+		for _, j := range jobs {
+			next := "-"
+			if j.Status == "pending" && !j.NextAttempt.IsZero() {
+				next = j.NextAttempt.Local().Format("15:04:05")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+				j.ID, j.Kind, j.Key, j.CreatedAt.Local().Format("2006-01-02 15:04"), j.Status, j.Attempts, next, orDash(j.LastError))
+		}
+		return tw.Flush()
+	},
+}
+
+var outboxRetryCmd = &cobra.Command{
+	Use:   "retry",
+	Short: "Retry an outbox job now",
+	Long: `Makes a pending or failed job due immediately with a fresh set of
+attempts. Use --failed to retry every job that gave up:
+
+  hydrarelease outbox retry --id 3f9a1c0b2d4e5f60
+  hydrarelease outbox retry --failed --kind mirror.link`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := resolveToken(outboxToken)
+		if token == "" {
+			return fmt.Errorf("auth token required: use --token or HYDRARELEASE_AUTH_TOKEN env")
+		}
+		if (outboxID == "") == !outboxFailed {
+			return fmt.Errorf("exactly one of --id or --failed is required")
+		}
+
+		ids := []string{outboxID}
+		if outboxFailed {
+			jobs, err := fetchOutbox(token, outboxKind, "failed", "")
+			if err != nil {
+				return err
+			}
+			if len(jobs) == 0 {
+				fmt.Println("No failed jobs.")
+				return nil
+			}
+			ids = ids[:0]
+			for _, j := range jobs {
+				ids = append(ids, j.ID)
+			}
+		}
+
+		var failures int
+		for _, id := range ids {
+			resp, err := doJSON(outboxServer, token, "POST", "/api/v1/outbox/"+id+"/retry", nil)
+			if err != nil {
+				return err
+			}
+			var result map[string]any
+			json.NewDecoder(resp.Body).Decode(&result)
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				fmt.Fprintf(os.Stderr, "%s: retry failed (%d): %v\n", id, resp.StatusCode, result["error"])
+				failures++
+				continue
+			}
+			fmt.Printf("Retrying %v %v (%s)\n", result["kind"], result["key"], id)
+		}
+		if failures > 0 {
+			return fmt.Errorf("%d jobs could not be retried", failures)
+		}
+		return nil
+	},
+}
+
+func init() {
+	outboxCmd.PersistentFlags().StringVar(&outboxServer, "server", "https://releases.experiencenet.com", "release server URL")
+	outboxCmd.PersistentFlags().StringVar(&outboxToken, "token", "", "admin bearer token (or HYDRARELEASE_AUTH_TOKEN env)")
+	outboxCmd.PersistentFlags().StringVar(&outboxKind, "kind", "", "only mirror.link or issue.resolve jobs")
+
+	outboxListCmd.Flags().StringVar(&outboxStatus, "status", "", "only pending, done or failed jobs")
+	outboxListCmd.Flags().StringVar(&outboxKey, "key", "", "only jobs for this build (project/number) or issue ID")
+	outboxListCmd.Flags().BoolVar(&outboxJSON, "json", false, "output as JSON")
+
+	outboxRetryCmd.Flags().StringVar(&outboxID, "id", "", "job id (from outbox list)")
+	outboxRetryCmd.Flags().BoolVar(&outboxFailed, "failed", false, "retry every failed job")
+
+	outboxCmd.AddCommand(outboxListCmd, outboxRetryCmd)
+	rootCmd.AddCommand(outboxCmd)
+}
@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	pipelineServer  string
+	pipelineToken   string
+	pipelineProject string
+	pipelineStages  string
+	pipelineSoak    []string
+	pipelineJSON    bool
+)
+
+var pipelineCmd = &cobra.Command{
+	Use:   "pipeline",
+	Short: "Manage per-project promotion pipelines",
+}
+
+var pipelineShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the promotion pipeline for a project",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if pipelineProject == "" {
+			return fmt.Errorf("--project is required")
+		}
+
+		resp, err := doJSON(pipelineServer, "", "GET", "/api/v1/pipelines/"+pipelineProject, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		var result struct {
+			Stages []struct {
+				Environment string `json:"environment"`
+				MinSoak     string `json:"min_soak"`
+			} `json:"stages"`
+			UpdatedBy string `json:"updated_by"`
+			UpdatedAt string `json:"updated_at"`
+			Error     string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("not found: %s", result.Error)
+		}
+
+		if pipelineJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(result)
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "STAGE\tENV\tMIN SOAK IN PREVIOUS\n")
+		for i, st := range result.Stages {
+			soak := st.MinSoak
+			if soak == "" {
+				soak = "-"
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", i+1, st.Environment, soak)
+		}
+		return tw.Flush()
+	},
+}
+
+var pipelineSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Define the promotion pipeline for a project",
+	Long: `Defines the ordered environments a project's builds must pass through.
+
+A build may only enter a stage after it has been live in the preceding one.
+--soak adds a minimum time in the preceding stage:
+
+  hydrarelease pipeline set --project app --stages dev,staging,production --soak production=24h`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := resolveToken(pipelineToken)
+		if token == "" {
+			return fmt.Errorf("auth token required: use --token or HYDRARELEASE_AUTH_TOKEN env")
+		}
+		if pipelineProject == "" {
+			return fmt.Errorf("--project is required")
+		}
+		if pipelineStages == "" {
+			return fmt.Errorf("--stages is required")
+		}
+
+		soaks := make(map[string]string)
+		for _, s := range pipelineSoak {
+			env, dur, ok := strings.Cut(s, "=")
+			if !ok {
+				return fmt.Errorf("invalid --soak %q (want env=duration)", s)
+			}
+			soaks[env] = dur
+		}
+
+		var stages []map[string]string
+		for _, env := range strings.Split(pipelineStages, ",") {
+			env = strings.TrimSpace(env)
+			stage := map[string]string{"environment": env}
+			if d, ok := soaks[env]; ok {
+				stage["min_soak"] = d
+				delete(soaks, env)
+			}
+			stages = append(stages, stage)
+		}
+		for env := range soaks {
+			return fmt.Errorf("--soak given for %s, which is not in --stages", env)
+		}
+
+		resp, err := doJSON(pipelineServer, token, "PUT", "/api/v1/pipelines/"+pipelineProject, map[string]any{
+			"stages":     stages,
+			"updated_by": "",
+		})
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		var result map[string]any
+		json.NewDecoder(resp.Body).Decode(&result)
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("set failed (%d): %v", resp.StatusCode, result["error"])
+		}
+
+		fmt.Printf("Pipeline for %s set: %s\n", pipelineProject, strings.ReplaceAll(pipelineStages, ",", " -> "))
+		return nil
+	},
+}
+
+var pipelineDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Remove the promotion pipeline for a project",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := resolveToken(pipelineToken)
+		if token == "" {
+			return fmt.Errorf("auth token required: use --token or HYDRARELEASE_AUTH_TOKEN env")
+		}
+		if pipelineProject == "" {
+			return fmt.Errorf("--project is required")
+		}
+
+		resp, err := doJSON(pipelineServer, token, "DELETE", "/api/v1/pipelines/"+pipelineProject, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusNoContent {
+			var result map[string]any
+			json.NewDecoder(resp.Body).Decode(&result)
+			return fmt.Errorf("delete failed (%d): %v", resp.StatusCode, result["error"])
+		}
+
+		fmt.Printf("Pipeline for %s removed\n", pipelineProject)
+		return nil
+	},
+}
+
+func init() {
+	pipelineCmd.PersistentFlags().StringVar(&pipelineServer, "server", "https://releases.experiencenet.com", "release server URL")
+	pipelineCmd.PersistentFlags().StringVar(&pipelineToken, "token", "", "auth bearer token (or HYDRARELEASE_AUTH_TOKEN env)")
+	pipelineCmd.PersistentFlags().StringVar(&pipelineProject, "project", "", "project name")
+	pipelineCmd.PersistentFlags().BoolVar(&pipelineJSON, "json", false, "output as JSON")
+
+	pipelineSetCmd.Flags().StringVar(&pipelineStages, "stages", "", "comma-separated environments in promotion order (e.g. dev,staging,production)")
+	pipelineSetCmd.Flags().StringSliceVar(&pipelineSoak, "soak", nil, "minimum time in the previous stage before entering env (env=duration, repeatable)")
+
+	pipelineCmd.AddCommand(pipelineShowCmd, pipelineSetCmd, pipelineDeleteCmd)
+	rootCmd.AddCommand(pipelineCmd)
+}
@@ -0,0 +1,260 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	projectServer      string
+	projectToken       string
+	projectName        string
+	projectDisplayName string
+	projectHealthURL   string
+	projectEnvs        []string
+	projectOwners      []string
+	projectKeepLast    int
+	projectKeepDays    int
+	projectJSON        bool
+)
+
+// registeredProject mirrors the project registry entries served by the API.
+type registeredProject struct {
+	Name         string   `json:"name"`
+	DisplayName  string   `json:"display_name,omitempty"`
+	HealthURL    string   `json:"health_url,omitempty"`
+	Environments []string `json:"environments,omitempty"`
+	Owners       []string `json:"owners,omitempty"`
+	Retention    *struct {
+		KeepLast int `json:"keep_last,omitempty"`
+		KeepDays int `json:"keep_days,omitempty"`
+	} `json:"retention,omitempty"`
+}
+
+// fetchProjects returns the project registry of a release server.
+func fetchProjects(client *http.Client, server string) ([]registeredProject, error) {
+	resp, err := client.Get(strings.TrimRight(server, "/") + "/api/v1/projects")
+	if err != nil {
+		return nil, fmt.Errorf("fetching projects: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("project registry returned %d", resp.StatusCode)
+	}
+	var projects []registeredProject
+	if err := json.NewDecoder(resp.Body).Decode(&projects); err != nil {
+		return nil, fmt.Errorf("parsing projects: %w", err)
+	}
+	return projects, nil
+}
+
+var projectCmd = &cobra.Command{
+	Use:   "project",
+	Short: "Manage the project registry",
+}
+
+var projectListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List registered projects",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		projects, err := fetchProjects(http.DefaultClient, projectServer)
+		if err != nil {
+			return err
+		}
+
+		if projectJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(projects)
+		}
+
+		if len(projects) == 0 {
+			fmt.Println("No projects registered.")
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "PROJECT\tNAME\tENVIRONMENTS\tHEALTH URL\tOWNERS\n")
+		for _, p := range projects {
+			envs := "all"
+			if len(p.Environments) > 0 {
+				envs = strings.Join(p.Environments, ",")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+				p.Name, orDash(p.DisplayName), envs, orDash(p.HealthURL), orDash(strings.Join(p.Owners, ",")))
+		}
+		return tw.Flush()
+	},
+}
+
+var projectShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show a registered project",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if projectName == "" {
+			return fmt.Errorf("--project is required")
+		}
+
+		resp, err := doJSON(projectServer, "", "GET", "/api/v1/projects/"+projectName, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		var result map[string]any
+		json.NewDecoder(resp.Body).Decode(&result)
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("not found: %v", result["error"])
+		}
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	},
+}
+
+var projectAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Register a project",
+	Long: `Registers a project with its display name, health URL, allowed
+environments, owners and retention override:
+
+  hydrarelease project add --project hydraguard --health-url http://hydraguard.experiencenet.com:8081 \
+    --envs staging,production --owners ops@experiencenet.com --keep-last 20`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := resolveToken(projectToken)
+		if token == "" {
+			return fmt.Errorf("auth token required: use --token or HYDRARELEASE_AUTH_TOKEN env")
+		}
+		if projectName == "" {
+			return fmt.Errorf("--project is required")
+		}
+
+		body := map[string]any{
+			"name":         projectName,
+			"display_name": projectDisplayName,
+			"health_url":   projectHealthURL,
+			"environments": projectEnvs,
+			"owners":       projectOwners,
+		}
+		if projectKeepLast > 0 || projectKeepDays > 0 {
+			body["retention"] = map[string]int{"keep_last": projectKeepLast, "keep_days": projectKeepDays}
+		}
+
+		resp, err := doJSON(projectServer, token, "POST", "/api/v1/projects", body)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		var result map[string]any
+		json.NewDecoder(resp.Body).Decode(&result)
+
+		if resp.StatusCode != http.StatusCreated {
+			return fmt.Errorf("add failed (%d): %v", resp.StatusCode, result["error"])
+		}
+
+		fmt.Printf("Registered project %s\n", projectName)
+		return nil
+	},
+}
+
+var projectUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Change settings of a registered project",
+	Long: `Changes only the settings given on the command line. Pass an empty
+value to clear a field, and --keep-last 0 --keep-days 0 to fall back to the
+retention.yaml policy:
+
+  hydrarelease project update --project app --envs staging,production
+  hydrarelease project update --project app --health-url ""`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := resolveToken(projectToken)
+		if token == "" {
+			return fmt.Errorf("auth token required: use --token or HYDRARELEASE_AUTH_TOKEN env")
+		}
+		if projectName == "" {
+			return fmt.Errorf("--project is required")
+		}
+
+		flags := cmd.Flags()
+		body := map[string]any{}
+		if flags.Changed("display-name") {
+			body["display_name"] = projectDisplayName
+		}
+		if flags.Changed("health-url") {
+			body["health_url"] = projectHealthURL
+		}
+		if flags.Changed("envs") {
+			body["environments"] = nonNil(projectEnvs)
+		}
+		if flags.Changed("owners") {
+			body["owners"] = nonNil(projectOwners)
+		}
+		if flags.Changed("keep-last") || flags.Changed("keep-days") {
+			body["retention"] = map[string]int{"keep_last": projectKeepLast, "keep_days": projectKeepDays}
+		}
+		if len(body) == 0 {
+			return fmt.Errorf("nothing to update")
+		}
+
+		resp, err := doJSON(projectServer, token, "PATCH", "/api/v1/projects/"+projectName, body)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		var result map[string]any
+		json.NewDecoder(resp.Body).Decode(&result)
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("update failed (%d): %v", resp.StatusCode, result["error"])
+		}
+
+		fmt.Printf("Updated project %s\n", projectName)
+		return nil
+	},
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// nonNil turns a nil slice into an empty one so it encodes as [] and clears
+// the field instead of being ignored.
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func init() {
+	projectCmd.PersistentFlags().StringVar(&projectServer, "server", "https://releases.experiencenet.com", "release server URL")
+	projectCmd.PersistentFlags().StringVar(&projectToken, "token", "", "auth bearer token (or HYDRARELEASE_AUTH_TOKEN env)")
+	projectCmd.PersistentFlags().StringVar(&projectName, "project", "", "project name")
+	projectCmd.PersistentFlags().BoolVar(&projectJSON, "json", false, "output as JSON")
+
+	for _, c := range []*cobra.Command{projectAddCmd, projectUpdateCmd} {
+		c.Flags().StringVar(&projectDisplayName, "display-name", "", "human-readable project name")
+		c.Flags().StringVar(&projectHealthURL, "health-url", "", "base URL serving /api/v1/health with the deployed version")
+		c.Flags().StringSliceVar(&projectEnvs, "envs", nil, "environments the project may be released to (default all)")
+		c.Flags().StringSliceVar(&projectOwners, "owners", nil, "project owners")
+		c.Flags().IntVar(&projectKeepLast, "keep-last", 0, "retention override: keep the newest N builds")
+		c.Flags().IntVar(&projectKeepDays, "keep-days", 0, "retention override: keep builds uploaded within N days")
+	}
+
+	projectCmd.AddCommand(projectListCmd, projectShowCmd, projectAddCmd, projectUpdateCmd)
+	rootCmd.AddCommand(projectCmd)
+}
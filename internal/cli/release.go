@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"text/tabwriter"
@@ -20,6 +21,20 @@ var (
 	releaseVersion string
 	releaseNotes   string
 	releaseJSON    bool
+	releaseSteps   int
+	releaseForce   bool
+	releaseFrom    string
+	releaseReason  string
+	releasePercent int
+	releaseHalt    bool
+	releaseMinVer  string
+	releaseBy      string
+	releasePrefix  string
+	releaseSince   string
+	releaseUntil   string
+	releaseLimit   int
+	releaseOrder   string
+	releaseCursor  string
 )
 
 var releaseCmd = &cobra.Command{
@@ -30,6 +45,12 @@ var releaseCmd = &cobra.Command{
 var releasePromoteCmd = &cobra.Command{
 	Use:   "promote",
 	Short: "Promote a build to an environment",
+	Long: `Promotes a build to an environment.
+
+Either give --build and --version explicitly, or use --from to copy the
+build, version and notes currently live in another environment:
+
+  hydrarelease release promote --project app --from staging --env production`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		token := resolveToken(releaseToken)
 		if token == "" {
@@ -41,21 +62,41 @@ var releasePromoteCmd = &cobra.Command{
 		if releaseEnv == "" {
 			return fmt.Errorf("--env is required")
 		}
-		if releaseBuild <= 0 {
-			return fmt.Errorf("--build is required")
-		}
-		if releaseVersion == "" {
-			return fmt.Errorf("--version is required")
+		if releaseFrom != "" {
+			if releaseBuild > 0 || releaseVersion != "" {
+				return fmt.Errorf("--from cannot be combined with --build or --version")
+			}
+		} else {
+			if releaseBuild <= 0 {
+				return fmt.Errorf("--build is required (or use --from)")
+			}
+			if releaseVersion == "" {
+				return fmt.Errorf("--version is required (or use --from)")
+			}
 		}
 
 		body := map[string]any{
 			"project":       releaseProject,
 			"environment":   releaseEnv,
-			"build_number":  releaseBuild,
-			"version":       releaseVersion,
 			"released_by":   "",
 			"release_notes": releaseNotes,
 		}
+		if releasePercent > 0 {
+			body["rollout_percent"] = releasePercent
+		}
+		if releaseMinVer != "" {
+			body["min_version"] = releaseMinVer
+		}
+		if releaseReason != "" {
+			body["override"] = true
+			body["override_reason"] = releaseReason
+		}
+		if releaseFrom != "" {
+			body["from_environment"] = releaseFrom
+		} else {
+			body["build_number"] = releaseBuild
+			body["version"] = releaseVersion
+		}
 
 		resp, err := doJSON(releaseServer, token, "POST", "/api/v1/releases", body)
 		if err != nil {
@@ -66,6 +107,9 @@ var releasePromoteCmd = &cobra.Command{
 		var result map[string]any
 		json.NewDecoder(resp.Body).Decode(&result)
 
+		if resp.StatusCode == http.StatusConflict {
+			return fmt.Errorf("promote blocked: %v\nUse --override-reason to bypass the pipeline in an emergency", result["error"])
+		}
 		if resp.StatusCode != http.StatusCreated {
 			return fmt.Errorf("promote failed (%d): %v", resp.StatusCode, result["error"])
 		}
@@ -76,6 +120,11 @@ var releasePromoteCmd = &cobra.Command{
 			return enc.Encode(result)
 		}
 
+		if releaseFrom != "" {
+			fmt.Printf("Promoted build #%.0f from %s to %s/%s as %s\n",
+				result["build_number"], releaseFrom, releaseProject, releaseEnv, result["version"])
+			return nil
+		}
 		fmt.Printf("Promoted build #%d to %s/%s as %s\n", releaseBuild, releaseProject, releaseEnv, releaseVersion)
 		return nil
 	},
@@ -83,7 +132,13 @@ var releasePromoteCmd = &cobra.Command{
 
 var releaseRollbackCmd = &cobra.Command{
 	Use:   "rollback",
-	Short: "Roll back to previous build in an environment",
+	Short: "Roll back an environment to an earlier build",
+	Long: `Rolls an environment back to an earlier build.
+
+By default the environment goes back one release. Use --steps to go further
+back, or --build to pick a specific build from the environment's history.
+Builds that were never released to the environment are refused unless
+--force is given (with --version if the build was never released anywhere).`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		token := resolveToken(releaseToken)
 		if token == "" {
@@ -96,11 +151,27 @@ var releaseRollbackCmd = &cobra.Command{
 			return fmt.Errorf("--env is required")
 		}
 
+		if releaseBuild > 0 && releaseSteps > 0 {
+			return fmt.Errorf("--build and --steps are mutually exclusive")
+		}
+
 		body := map[string]any{
 			"project":        releaseProject,
 			"environment":    releaseEnv,
 			"rolled_back_by": "",
 		}
+		if releaseBuild > 0 {
+			body["target_build"] = releaseBuild
+		}
+		if releaseSteps > 0 {
+			body["steps"] = releaseSteps
+		}
+		if releaseForce {
+			body["force"] = true
+		}
+		if releaseVersion != "" {
+			body["version"] = releaseVersion
+		}
 
 		resp, err := doJSON(releaseServer, token, "POST", "/api/v1/releases/rollback", body)
 		if err != nil {
@@ -121,7 +192,74 @@ var releaseRollbackCmd = &cobra.Command{
 			return enc.Encode(result)
 		}
 
-		fmt.Printf("Rolled back %s/%s to build #%.0f\n", releaseProject, releaseEnv, result["build_number"])
+		fmt.Printf("Rolled back %s/%s from build #%.0f to build #%.0f (%s)\n",
+			releaseProject, releaseEnv, result["rolled_back_from"], result["build_number"], result["version"])
+		return nil
+	},
+}
+
+var releaseRolloutCmd = &cobra.Command{
+	Use:   "rollout",
+	Short: "Ramp or halt the staged rollout of the current release",
+	Long: `Changes the percentage of clients that receive the current release of an
+environment. Clients outside the rollout keep receiving the previous version.
+
+  hydrarelease release rollout --project app --env production --percent 25
+  hydrarelease release rollout --project app --env production --percent 100
+  hydrarelease release rollout --project app --env production --halt`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := resolveToken(releaseToken)
+		if token == "" {
+			return fmt.Errorf("auth token required: use --token or HYDRARELEASE_AUTH_TOKEN env")
+		}
+		if releaseProject == "" {
+			return fmt.Errorf("--project is required")
+		}
+		if releaseEnv == "" {
+			return fmt.Errorf("--env is required")
+		}
+		if releaseHalt == cmd.Flags().Changed("percent") {
+			return fmt.Errorf("exactly one of --percent or --halt is required")
+		}
+
+		body := map[string]any{
+			"project":     releaseProject,
+			"environment": releaseEnv,
+			"percent":     releasePercent,
+			"halt":        releaseHalt,
+			"updated_by":  "",
+		}
+
+		resp, err := doJSON(releaseServer, token, "POST", "/api/v1/releases/rollout", body)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		var result map[string]any
+		json.NewDecoder(resp.Body).Decode(&result)
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("rollout failed (%d): %v", resp.StatusCode, result["error"])
+		}
+
+		if releaseJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(result)
+		}
+
+		rollout, _ := result["rollout"].(map[string]any)
+		switch {
+		case rollout == nil:
+			fmt.Printf("%s/%s: %s is live for all clients\n", releaseProject, releaseEnv, result["version"])
+		case rollout["halted"] == true:
+			fmt.Printf("%s/%s: rollout of %s halted, clients receive %s\n",
+				releaseProject, releaseEnv, result["version"], result["previous_version"])
+		default:
+			fmt.Printf("%s/%s: %s rolling out to %.0f%% of clients (others receive %s)\n",
+				releaseProject, releaseEnv, result["version"], rollout["percent"], result["previous_version"])
+		}
 		return nil
 	},
 }
@@ -134,14 +272,27 @@ var releaseListCmd = &cobra.Command{
 			return fmt.Errorf("--project is required")
 		}
 
-		url := fmt.Sprintf("%s/api/v1/releases?project=%s",
-			strings.TrimRight(releaseServer, "/"), releaseProject)
-
-		resp, err := http.Get(url)
+		q := url.Values{"project": {releaseProject}, "limit": {fmt.Sprint(releaseLimit)}}
+		setQuery(q, map[string]string{
+			"environment":    releaseEnv,
+			"released_by":    releaseBy,
+			"version_prefix": releasePrefix,
+			"since":          releaseSince,
+			"until":          releaseUntil,
+			"order":          releaseOrder,
+			"cursor":         releaseCursor,
+		})
+
+		resp, err := http.Get(strings.TrimRight(releaseServer, "/") + "/api/v1/releases?" + q.Encode())
 		if err != nil {
 			return fmt.Errorf("request failed: %w", err)
 		}
 		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			var result map[string]any
+			json.NewDecoder(resp.Body).Decode(&result)
+			return fmt.Errorf("list failed (%d): %v", resp.StatusCode, result["error"])
+		}
 
 		var releases []map[string]any
 		json.NewDecoder(resp.Body).Decode(&releases)
@@ -158,15 +309,30 @@ var releaseListCmd = &cobra.Command{
 		}
 
 		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
-		fmt.Fprintf(tw, "ENV\tBUILD\tVERSION\tRELEASED BY\tRELEASED AT\n")
+		fmt.Fprintf(tw, "ENV\tBUILD\tVERSION\tPATH\tRELEASED BY\tRELEASED AT\n")
 		for _, r := range releases {
-			fmt.Fprintf(tw, "%s\t#%.0f\t%s\t%s\t%s\n",
-				r["environment"], r["build_number"], r["version"], r["released_by"], r["released_at"])
+			fmt.Fprintf(tw, "%s\t#%.0f\t%s\t%s\t%s\t%s\n",
+				r["environment"], r["build_number"], r["version"], releasePath(r), r["released_by"], r["released_at"])
 		}
-		return tw.Flush()
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		printNextCursor(resp)
+		return nil
 	},
 }
 
+// releasePath describes how a history entry reached its environment.
+func releasePath(r map[string]any) string {
+	if from, ok := r["promoted_from"].(string); ok && from != "" {
+		return fmt.Sprintf("%s -> %s", from, r["environment"])
+	}
+	if from, ok := r["rolled_back_from"].(float64); ok && from > 0 {
+		return fmt.Sprintf("rollback from #%.0f", from)
+	}
+	return "-"
+}
+
 var releaseShowCmd = &cobra.Command{
 	Use:   "show",
 	Short: "Show current release for a project and environment",
@@ -200,6 +366,43 @@ var releaseShowCmd = &cobra.Command{
 	},
 }
 
+var releaseReportsCmd = &cobra.Command{
+	Use:   "reports",
+	Short: "List updates that clients reverted after a failed health check",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		url := fmt.Sprintf("%s/api/v1/update-reports?project=%s&version=%s",
+			strings.TrimRight(releaseServer, "/"), releaseProject, releaseVersion)
+
+		resp, err := http.Get(url)
+		if err != nil {
+			return fmt.Errorf("request failed: %w", err)
+		}
+		defer resp.Body.Close()
+
+		var reports []map[string]any
+		json.NewDecoder(resp.Body).Decode(&reports)
+
+		if releaseJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(reports)
+		}
+
+		if len(reports) == 0 {
+			fmt.Println("No update reports found.")
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "PROJECT\tFROM\tTO\tSTATUS\tCLIENT\tREASON\tREPORTED AT\n")
+		for _, r := range reports {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				r["project"], r["from_version"], r["to_version"], r["status"], r["client_id"], r["reason"], r["reported_at"])
+		}
+		return tw.Flush()
+	},
+}
+
 func init() {
 	releaseCmd.PersistentFlags().StringVar(&releaseServer, "server", "https://releases.experiencenet.com", "release server URL")
 	releaseCmd.PersistentFlags().StringVar(&releaseToken, "token", "", "auth bearer token (or HYDRARELEASE_AUTH_TOKEN env)")
@@ -210,11 +413,34 @@ func init() {
 	releasePromoteCmd.Flags().IntVar(&releaseBuild, "build", 0, "build number to promote")
 	releasePromoteCmd.Flags().StringVar(&releaseVersion, "version", "", "version string")
 	releasePromoteCmd.Flags().StringVar(&releaseNotes, "notes", "", "release notes")
+	releasePromoteCmd.Flags().StringVar(&releaseFrom, "from", "", "copy the release currently live in this environment")
+	releasePromoteCmd.Flags().StringVar(&releaseReason, "override-reason", "", "bypass the project's promotion pipeline, recording this reason")
+	releasePromoteCmd.Flags().IntVar(&releasePercent, "rollout", 0, "start a staged rollout at this percentage of clients")
+	releasePromoteCmd.Flags().StringVar(&releaseMinVer, "min-version", "", "oldest version still supported; older clients are told the update is required")
 
 	releaseRollbackCmd.Flags().StringVar(&releaseEnv, "env", "", "environment (dev, staging, production)")
+	releaseRollbackCmd.Flags().IntVar(&releaseBuild, "build", 0, "build number to roll back to")
+	releaseRollbackCmd.Flags().IntVar(&releaseSteps, "steps", 0, "number of releases to go back (default 1)")
+	releaseRollbackCmd.Flags().BoolVar(&releaseForce, "force", false, "allow a build never released to this environment")
+	releaseRollbackCmd.Flags().StringVar(&releaseVersion, "version", "", "version for a forced build with no release history")
+
+	releaseRolloutCmd.Flags().StringVar(&releaseEnv, "env", "", "environment (dev, staging, production)")
+	releaseRolloutCmd.Flags().IntVar(&releasePercent, "percent", 0, "percentage of clients to receive the current release")
+	releaseRolloutCmd.Flags().BoolVar(&releaseHalt, "halt", false, "stop the rollout; all clients receive the previous version")
+
+	releaseListCmd.Flags().StringVar(&releaseEnv, "env", "", "only releases to this environment")
+	releaseListCmd.Flags().StringVar(&releaseBy, "released-by", "", "only releases made by this user")
+	releaseListCmd.Flags().StringVar(&releasePrefix, "version-prefix", "", "only versions starting with this prefix (e.g. 1.4)")
+	releaseListCmd.Flags().StringVar(&releaseSince, "since", "", "only releases at or after this RFC3339 time")
+	releaseListCmd.Flags().StringVar(&releaseUntil, "until", "", "only releases before this RFC3339 time")
+	releaseListCmd.Flags().IntVar(&releaseLimit, "limit", 50, "maximum number of releases to show (0 for all)")
+	releaseListCmd.Flags().StringVar(&releaseOrder, "order", "", "newest (default) or oldest first")
+	releaseListCmd.Flags().StringVar(&releaseCursor, "cursor", "", "continue a previous listing from this cursor")
 
 	releaseShowCmd.Flags().StringVar(&releaseEnv, "env", "", "environment (dev, staging, production)")
 
-	releaseCmd.AddCommand(releasePromoteCmd, releaseRollbackCmd, releaseListCmd, releaseShowCmd)
+	releaseReportsCmd.Flags().StringVar(&releaseVersion, "version", "", "only reports for updates to this version")
+
+	releaseCmd.AddCommand(releasePromoteCmd, releaseRollbackCmd, releaseRolloutCmd, releaseListCmd, releaseShowCmd, releaseReportsCmd)
 	rootCmd.AddCommand(releaseCmd)
 }
@@ -1,31 +1,43 @@
 package cli
 
 import (
+	"crypto/ed25519"
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/cederikdotcom/hydraauth"
 	"github.com/cederikdotcom/hydramonitor"
 	"github.com/cederikdotcom/hydrarelease/internal/api"
+	"github.com/cederikdotcom/hydrarelease/internal/oidc"
+	"github.com/cederikdotcom/hydrarelease/internal/storage"
 	"github.com/cederikdotcom/hydrarelease/internal/store"
 	"github.com/cederikdotcom/hydrarelease/pkg/updater"
+	"github.com/cederikdotcom/hydrarelease/pkg/updater/signing"
 	"github.com/cederikdotcom/hydraserve"
 	"github.com/spf13/cobra"
 )
 
 var (
-	serveDataDir         string
-	serveDomain          string
-	serveCerts           string
-	serveDev             bool
-	serveListen          string
-	servePublishToken    string
-	serveAuthToken       string
-	serveMirrorURL       string
-	serveMirrorToken     string
+	serveDataDir           string
+	serveStore             string
+	serveDomain            string
+	serveCerts             string
+	serveDev               bool
+	serveListen            string
+	servePublishToken      string
+	serveAuthToken         string
+	serveMirrorURL         string
+	serveMirrorToken       string
 	serveIssueTrackerURL   string
 	serveIssueTrackerToken string
+	serveGCInterval        time.Duration
+	serveTrustedKeys       string
+	serveSessionTTL        time.Duration
+	serveStrictProjects    bool
+	serveOIDCConfig        string
 )
 
 var serveCmd = &cobra.Command{
@@ -34,7 +46,15 @@ var serveCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		u := updater.NewProductionUpdater("hydrarelease", version)
 		u.SetServiceName("hydrarelease")
-		u.StartAutoCheck(6*time.Hour, true)
+		u.SetHealthCheck(5 * time.Minute)
+		if err := u.CheckPendingUpdate(); err != nil {
+			log.Printf("Warning: pending update check failed: %v", err)
+		}
+		autoUpdate := u.StartAutoCheckContext(cmd.Context(), updater.AutoCheckOptions{
+			Interval:  6 * time.Hour,
+			AutoApply: true,
+		})
+		defer autoUpdate.Stop()
 		log.Printf("Auto-update: enabled (every 6h)")
 
 		// Resolve tokens from flags or environment.
@@ -57,8 +77,12 @@ var serveCmd = &cobra.Command{
 		}
 
 		// Initialize stores.
-		builds := store.NewBuildStore(serveDataDir)
-		releases := store.NewReleaseStore(serveDataDir)
+		stores, err := store.Open(serveStore, serveDataDir)
+		if err != nil {
+			return err
+		}
+		defer stores.Close()
+		log.Printf("Store backend: %s", serveStore)
 
 		// Initialize auth and monitor.
 		auth := hydraauth.New(authToken)
@@ -77,8 +101,14 @@ var serveCmd = &cobra.Command{
 			mirrorToken = os.Getenv("HYDRARELEASE_MIRROR_TOKEN")
 		}
 
-		if mirrorURL == "" {
-			log.Printf("Warning: no mirror URL configured; publish and file serving will not work")
+		var files storage.Storage
+		if mirrorURL != "" {
+			files = storage.NewMirror(mirrorURL, mirrorToken)
+			log.Printf("File storage: hydramirror at %s", mirrorURL)
+		} else {
+			filesDir := filepath.Join(serveDataDir, "files")
+			files = storage.NewLocal(filesDir)
+			log.Printf("File storage: local disk at %s (no mirror URL configured)", filesDir)
 		}
 
 		issueTrackerURL := serveIssueTrackerURL
@@ -90,26 +120,93 @@ var serveCmd = &cobra.Command{
 			issueTrackerToken = os.Getenv("HYDRARELEASE_ISSUE_TRACKER_TOKEN")
 		}
 
+		trustedKeysFile := serveTrustedKeys
+		if trustedKeysFile == "" {
+			trustedKeysFile = os.Getenv("HYDRARELEASE_TRUSTED_KEYS")
+		}
+		var trustedKeys []ed25519.PublicKey
+		if trustedKeysFile != "" {
+			data, err := os.ReadFile(trustedKeysFile)
+			if err != nil {
+				return fmt.Errorf("reading trusted keys: %w", err)
+			}
+			trustedKeys, err = signing.ParsePublicKeys(data)
+			if err != nil {
+				return fmt.Errorf("parsing %s: %w", trustedKeysFile, err)
+			}
+			log.Printf("Release signing: required (%d trusted keys)", len(trustedKeys))
+		}
+
+		oidcConfigFile := serveOIDCConfig
+		if oidcConfigFile == "" {
+			oidcConfigFile = os.Getenv("HYDRARELEASE_OIDC_CONFIG")
+		}
+		var oidcVerifier *oidc.Verifier
+		if oidcConfigFile != "" {
+			cfg, err := oidc.LoadConfig(oidcConfigFile)
+			if err != nil {
+				return err
+			}
+			oidcVerifier = oidc.NewVerifier(cfg)
+			log.Printf("OIDC: accepting tokens from %s for audience %s (%d rules)", cfg.Issuer, cfg.Audience, len(cfg.Rules))
+		}
+
+		metrics := api.NewMetrics()
+
 		srv := &api.Server{
-			Builds:            builds,
-			Releases:          releases,
+			Builds:            metrics.BuildStore(stores.Builds),
+			Releases:          metrics.ReleaseStore(stores.Releases),
+			Retention:         store.NewRetentionStore(serveDataDir),
+			Pipelines:         store.NewPipelineStore(serveDataDir),
+			UpdateReports:     store.NewUpdateReportStore(serveDataDir),
+			Artifacts:         store.NewArtifactStore(serveDataDir),
+			Projects:          store.NewProjectStore(serveDataDir),
+			Tokens:            store.NewTokenStore(serveDataDir),
+			OIDC:              oidcVerifier,
+			Audit:             store.NewAuditLog(serveDataDir),
+			Webhooks:          store.NewWebhookStore(serveDataDir),
+			Outbox:            store.NewOutboxStore(serveDataDir),
+			StrictProjects:    serveStrictProjects,
+			UploadSessions:    store.NewUploadSessionStore(serveDataDir),
+			UploadSessionTTL:  serveSessionTTL,
 			Auth:              auth,
 			Monitor:           monitor,
 			Version:           version,
-			MirrorURL:         mirrorURL,
-			MirrorToken:       mirrorToken,
+			Storage:           metrics.Storage(files),
 			IssueTrackerURL:   issueTrackerURL,
 			IssueTrackerToken: issueTrackerToken,
+			TrustedKeys:       trustedKeys,
+			AutoUpdate:        autoUpdate,
+			Metrics:           metrics,
+		}
+		metrics.RegisterGauges(srv)
+
+		srv.InitLatest()
+
+		if serveStrictProjects {
+			log.Printf("Project registry: strict (unregistered projects are refused)")
 		}
 
-		if err := releases.Migrate(); err != nil {
-			log.Printf("Warning: channel migration failed: %v", err)
+		if serveGCInterval > 0 {
+			srv.StartGC(serveGCInterval)
+			log.Printf("Build GC: enabled (every %s, policies from %s/retention.yaml)", serveGCInterval, serveDataDir)
 		}
 
-		srv.InitLatest()
+		srv.StartUploadSessionExpiry(time.Hour)
+		srv.StartWebhooks(15 * time.Second)
+		srv.StartOutbox(15 * time.Second)
 
 		handler := srv.Handler(publishToken, startTime)
 
+		// Confirm a fresh update once the server has stayed up for a while;
+		// otherwise the updater restores the previous binary.
+		go func() {
+			time.Sleep(time.Minute)
+			if err := u.ConfirmHealthy(); err != nil {
+				log.Printf("Warning: confirming update health failed: %v", err)
+			}
+		}()
+
 		listen := serveListen
 		if serveDev && listen == "" {
 			listen = ":8080"
@@ -126,16 +223,22 @@ var serveCmd = &cobra.Command{
 
 func init() {
 	serveCmd.Flags().StringVar(&serveDataDir, "data-dir", "/var/lib/hydrarelease", "directory for build/release metadata")
+	serveCmd.Flags().StringVar(&serveStore, "store", store.BackendYAML, "metadata store backend (yaml or db)")
 	serveCmd.Flags().StringVar(&serveDomain, "domain", "releases.experiencenet.com", "domain for TLS certificate")
 	serveCmd.Flags().StringVar(&serveCerts, "certs", "/var/lib/hydrarelease/certs", "directory to cache TLS certificates")
 	serveCmd.Flags().BoolVar(&serveDev, "dev", false, "run in development mode (plain HTTP)")
 	serveCmd.Flags().StringVar(&serveListen, "listen", "", "listen address (default :8080 in dev mode)")
 	serveCmd.Flags().StringVar(&servePublishToken, "publish-token", "", "bearer token for legacy publish API (or HYDRARELEASE_PUBLISH_TOKEN env)")
 	serveCmd.Flags().StringVar(&serveAuthToken, "auth-token", "", "bearer token for build/release API and SSE (or HYDRARELEASE_AUTH_TOKEN env)")
-	serveCmd.Flags().StringVar(&serveMirrorURL, "mirror-url", "", "hydramirror URL for file storage (or HYDRARELEASE_MIRROR_URL env)")
+	serveCmd.Flags().StringVar(&serveMirrorURL, "mirror-url", "", "hydramirror URL for file storage; files are kept under <data-dir>/files when unset (or HYDRARELEASE_MIRROR_URL env)")
 	serveCmd.Flags().StringVar(&serveMirrorToken, "mirror-token", "", "bearer token for hydramirror (or HYDRARELEASE_MIRROR_TOKEN env)")
 	serveCmd.Flags().StringVar(&serveIssueTrackerURL, "issue-tracker-url", "", "hydraissue URL for issue resolution (or HYDRARELEASE_ISSUE_TRACKER_URL env)")
 	serveCmd.Flags().StringVar(&serveIssueTrackerToken, "issue-tracker-token", "", "bearer token for hydraissue (or HYDRARELEASE_ISSUE_TRACKER_TOKEN env)")
+	serveCmd.Flags().StringVar(&serveTrustedKeys, "trusted-keys", "", "file of Ed25519 public keys; finalize then requires a signed SHA256SUMS (or HYDRARELEASE_TRUSTED_KEYS env)")
+	serveCmd.Flags().DurationVar(&serveSessionTTL, "upload-session-ttl", 24*time.Hour, "how long legacy publish uploads wait for finalize before expiring")
+	serveCmd.Flags().BoolVar(&serveStrictProjects, "strict-projects", false, "refuse builds, promotions and publishes for projects not in the registry")
+	serveCmd.Flags().StringVar(&serveOIDCConfig, "oidc-config", "", "YAML file mapping CI OIDC token claims to projects; enables OIDC auth (or HYDRARELEASE_OIDC_CONFIG env)")
+	serveCmd.Flags().DurationVar(&serveGCInterval, "gc-interval", 24*time.Hour, "how often to garbage-collect builds per retention policy (0 disables)")
 
 	rootCmd.AddCommand(serveCmd)
 }
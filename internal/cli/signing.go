@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"os"
+
+	"github.com/cederikdotcom/hydrarelease/pkg/updater/signing"
+	"github.com/spf13/cobra"
+)
+
+var (
+	signingOut  string
+	signingKey  string
+	signingKeys string
+)
+
+var signingCmd = &cobra.Command{
+	Use:   "signing",
+	Short: "Sign releases with offline Ed25519 keys",
+}
+
+var signingKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Generate a release signing key pair",
+	Long: `Writes <out>.key (private, keep offline) and <out>.pub (public, give to
+'serve --trusted-keys' and to updaters via SetTrustedKeys).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pub, priv, err := signing.GenerateKey()
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(signingOut+".key", []byte(signing.EncodePrivateKey(priv)+"\n"), 0600); err != nil {
+			return err
+		}
+		if err := os.WriteFile(signingOut+".pub", []byte(signing.EncodePublicKey(pub)+"\n"), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Key %s written to %s.key and %s.pub\n", signing.KeyID(pub), signingOut, signingOut)
+		return nil
+	},
+}
+
+var signingSignCmd = &cobra.Command{
+	Use:   "sign SHA256SUMS",
+	Short: "Sign a SHA256SUMS file",
+	Long: `Appends a signature line for --key to SHA256SUMS.sig next to the given
+file. Run it once per active key to sign with several keys during rotation.
+
+Publish the signature by sending it as the finalize request body:
+
+  curl -X POST --data-binary @SHA256SUMS.sig \
+    -H "Authorization: Bearer $TOKEN" \
+    https://releases.experiencenet.com/api/v1/publish/<project>/<channel>/<version>/finalize`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if signingKey == "" {
+			return fmt.Errorf("--key is required")
+		}
+		keyData, err := os.ReadFile(signingKey)
+		if err != nil {
+			return err
+		}
+		priv, err := signing.ParsePrivateKey(string(keyData))
+		if err != nil {
+			return err
+		}
+		sums, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+
+		sigPath := args[0] + ".sig"
+		f, err := os.OpenFile(sigPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(signing.Sign(priv, sums)); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+
+		fmt.Printf("Signed %s with key %s -> %s\n", args[0], signing.KeyID(priv.Public().(ed25519.PublicKey)), sigPath)
+		return nil
+	},
+}
+
+var signingVerifyCmd = &cobra.Command{
+	Use:   "verify SHA256SUMS",
+	Short: "Verify SHA256SUMS.sig against trusted public keys",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if signingKeys == "" {
+			return fmt.Errorf("--keys is required")
+		}
+		keyData, err := os.ReadFile(signingKeys)
+		if err != nil {
+			return err
+		}
+		keys, err := signing.ParsePublicKeys(keyData)
+		if err != nil {
+			return err
+		}
+		sums, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		sig, err := os.ReadFile(args[0] + ".sig")
+		if err != nil {
+			return err
+		}
+
+		if err := signing.Verify(sums, sig, keys); err != nil {
+			return err
+		}
+		fmt.Printf("%s: signature OK\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	signingKeygenCmd.Flags().StringVar(&signingOut, "out", "release-signing", "output file prefix")
+	signingSignCmd.Flags().StringVar(&signingKey, "key", "", "private key file")
+	signingVerifyCmd.Flags().StringVar(&signingKeys, "keys", "", "file of trusted public keys, one per line")
+
+	signingCmd.AddCommand(signingKeygenCmd, signingSignCmd, signingVerifyCmd)
+	rootCmd.AddCommand(signingCmd)
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	statusServer string
+	statusJSON   bool
+)
+
+type statusOverview struct {
+	Environments []string `json:"environments"`
+	Projects     []struct {
+		Project     string                    `json:"project"`
+		Releases    map[string]*statusRelease `json:"releases"`
+		LatestBuild *struct {
+			BuildNumber int `json:"build_number"`
+		} `json:"latest_build"`
+		UnreleasedBuilds int `json:"unreleased_builds"`
+	} `json:"projects"`
+}
+
+type statusRelease struct {
+	BuildNumber    int    `json:"build_number"`
+	Version        string `json:"version"`
+	RolloutPercent int    `json:"rollout_percent"`
+	RolloutHalted  bool   `json:"rollout_halted"`
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show what is live in every environment of every project",
+	Long: `Shows the current release of every project in every environment, the
+latest complete build and how many complete builds are newer than the one in
+production.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client := &http.Client{Timeout: 30 * time.Second}
+		resp, err := client.Get(strings.TrimRight(statusServer, "/") + "/api/v1/overview")
+		if err != nil {
+			return fmt.Errorf("request failed: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("overview failed (%d)", resp.StatusCode)
+		}
+
+		if statusJSON {
+			var raw json.RawMessage
+			if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+				return fmt.Errorf("decoding overview: %w", err)
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(raw)
+		}
+
+		var ov statusOverview
+		if err := json.NewDecoder(resp.Body).Decode(&ov); err != nil {
+			return fmt.Errorf("decoding overview: %w", err)
+		}
+		if len(ov.Projects) == 0 {
+			fmt.Println("No projects found.")
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "PROJECT\t%s\tLATEST BUILD\tUNRELEASED\n", strings.ToUpper(strings.Join(ov.Environments, "\t")))
+		for _, p := range ov.Projects {
+			cells := []string{p.Project}
+			for _, env := range ov.Environments {
+				cells = append(cells, statusCell(p.Releases[env]))
+			}
+			latest := "-"
+			if p.LatestBuild != nil {
+				latest = fmt.Sprintf("#%d", p.LatestBuild.BuildNumber)
+			}
+			cells = append(cells, latest, fmt.Sprint(p.UnreleasedBuilds))
+			fmt.Fprintln(tw, strings.Join(cells, "\t"))
+		}
+		return tw.Flush()
+	},
+}
+
+// statusCell renders one environment's release, e.g. "1.4.0 #12 (25%)".
+func statusCell(r *statusRelease) string {
+	if r == nil {
+		return "-"
+	}
+	cell := r.Version
+	if r.BuildNumber > 0 {
+		cell += fmt.Sprintf(" #%d", r.BuildNumber)
+	}
+	switch {
+	case r.RolloutHalted:
+		cell += " (halted)"
+	case r.RolloutPercent > 0:
+		cell += fmt.Sprintf(" (%d%%)", r.RolloutPercent)
+	}
+	return cell
+}
+
+func init() {
+	statusCmd.Flags().StringVar(&statusServer, "server", "https://releases.experiencenet.com", "release server URL")
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "output as JSON")
+
+	rootCmd.AddCommand(statusCmd)
+}
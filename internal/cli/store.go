@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var (
+	storeDataDir string
+	storeDBPath  string
+)
+
+var storeCmd = &cobra.Command{
+	Use:   "store",
+	Short: "Manage the metadata store",
+}
+
+var storeImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import the YAML metadata tree into the embedded database",
+	Long: `Copies builds.yaml, releases.yaml and the per-build/per-release files
+from the data directory into the embedded database used by 'serve --store=db'.
+The YAML files are left untouched. The target database must be empty.
+
+Stop the server before importing so no promotions are missed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dbPath := storeDBPath
+		if dbPath == "" {
+			dbPath = filepath.Join(storeDataDir, store.DBFileName)
+		}
+
+		db, err := store.OpenDB(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		result, err := store.ImportYAML(storeDataDir, db)
+		if err != nil {
+			return fmt.Errorf("import failed: %w", err)
+		}
+
+		fmt.Printf("Imported %d builds, %d release history entries and %d current releases into %s\n",
+			result.Builds, result.Releases, result.CurrentReleases, dbPath)
+		return nil
+	},
+}
+
+func init() {
+	storeCmd.PersistentFlags().StringVar(&storeDataDir, "data-dir", "/var/lib/hydrarelease", "directory for build/release metadata")
+	storeImportCmd.Flags().StringVar(&storeDBPath, "db", "", "database file (default <data-dir>/"+store.DBFileName+")")
+
+	storeCmd.AddCommand(storeImportCmd)
+	rootCmd.AddCommand(storeCmd)
+}
@@ -0,0 +1,219 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	tokenServer  string
+	tokenToken   string
+	tokenName    string
+	tokenProject string
+	tokenEnvs    []string
+	tokenActions []string
+	tokenExpires time.Duration
+	tokenID      string
+	tokenJSON    bool
+)
+
+// apiToken mirrors the scoped token records served by the API.
+type apiToken struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Scopes []struct {
+		Project      string   `json:"project"`
+		Environments []string `json:"environments,omitempty"`
+		Actions      []string `json:"actions"`
+	} `json:"scopes"`
+	CreatedBy string     `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (t apiToken) status(now time.Time) string {
+	switch {
+	case t.RevokedAt != nil:
+		return "revoked"
+	case t.ExpiresAt != nil && !now.Before(*t.ExpiresAt):
+		return "expired"
+	}
+	return "active"
+}
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage scoped API tokens",
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Issue a scoped API token",
+	Long: `Issues a token limited to one project, some environments and some
+actions. The token is printed once and cannot be shown again:
+
+  hydrarelease token create --name ci-hydraguard --project hydraguard \
+    --env dev,staging --action build.submit,release.promote --expires 2160h
+
+Actions: build.submit, build.yank, release.promote, release.rollback, events.read
+(events.read needs --project '*').`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := resolveToken(tokenToken)
+		if token == "" {
+			return fmt.Errorf("auth token required: use --token or HYDRARELEASE_AUTH_TOKEN env")
+		}
+		if tokenName == "" {
+			return fmt.Errorf("--name is required")
+		}
+		if len(tokenActions) == 0 {
+			return fmt.Errorf("--action is required")
+		}
+
+		body := map[string]any{
+			"name": tokenName,
+			"scopes": []map[string]any{{
+				"project":      tokenProject,
+				"environments": tokenEnvs,
+				"actions":      tokenActions,
+			}},
+			"created_by": os.Getenv("USER"),
+		}
+		if tokenExpires > 0 {
+			body["expires_in"] = tokenExpires.String()
+		}
+
+		resp, err := doJSON(tokenServer, token, "POST", "/api/v1/tokens", body)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		var result map[string]any
+		json.NewDecoder(resp.Body).Decode(&result)
+
+		if resp.StatusCode != http.StatusCreated {
+			return fmt.Errorf("create failed (%d): %v", resp.StatusCode, result["error"])
+		}
+
+		if tokenJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(result)
+		}
+		fmt.Printf("Created token %s (id %v)\n", tokenName, result["id"])
+		fmt.Printf("\n  %v\n\nStore it now; it will not be shown again.\n", result["token"])
+		return nil
+	},
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List scoped API tokens",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := resolveToken(tokenToken)
+		if token == "" {
+			return fmt.Errorf("auth token required: use --token or HYDRARELEASE_AUTH_TOKEN env")
+		}
+
+		resp, err := doJSON(tokenServer, token, "GET", "/api/v1/tokens", nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("list failed (%d)", resp.StatusCode)
+		}
+		var tokens []apiToken
+		if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+			return fmt.Errorf("parsing tokens: %w", err)
+		}
+
+		if tokenJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(tokens)
+		}
+
+		if len(tokens) == 0 {
+			fmt.Println("No tokens issued.")
+			return nil
+		}
+
+		now := time.Now()
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "ID\tNAME\tSCOPES\tCREATED\tEXPIRES\tSTATUS\n")
+		for _, t := range tokens {
+			var scopes []string
+			for _, sc := range t.Scopes {
+				s := sc.Project
+				if len(sc.Environments) > 0 {
+					s += "/" + strings.Join(sc.Environments, ",")
+				}
+				scopes = append(scopes, s+":"+strings.Join(sc.Actions, ","))
+			}
+			expires := "-"
+			if t.ExpiresAt != nil {
+				expires = t.ExpiresAt.Format("2006-01-02 15:04")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				t.ID, t.Name, strings.Join(scopes, " "), t.CreatedAt.Format("2006-01-02 15:04"), expires, t.status(now))
+		}
+		return tw.Flush()
+	},
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "Revoke a scoped API token",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := resolveToken(tokenToken)
+		if token == "" {
+			return fmt.Errorf("auth token required: use --token or HYDRARELEASE_AUTH_TOKEN env")
+		}
+		if tokenID == "" {
+			return fmt.Errorf("--id is required")
+		}
+
+		resp, err := doJSON(tokenServer, token, "DELETE", "/api/v1/tokens/"+tokenID, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		var result map[string]any
+		json.NewDecoder(resp.Body).Decode(&result)
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("revoke failed (%d): %v", resp.StatusCode, result["error"])
+		}
+
+		fmt.Printf("Revoked token %v (%s)\n", result["name"], tokenID)
+		return nil
+	},
+}
+
+func init() {
+	tokenCmd.PersistentFlags().StringVar(&tokenServer, "server", "https://releases.experiencenet.com", "release server URL")
+	tokenCmd.PersistentFlags().StringVar(&tokenToken, "token", "", "admin bearer token (or HYDRARELEASE_AUTH_TOKEN env)")
+	tokenCmd.PersistentFlags().BoolVar(&tokenJSON, "json", false, "output as JSON")
+
+	tokenCreateCmd.Flags().StringVar(&tokenName, "name", "", "token name, e.g. ci-hydraguard")
+	tokenCreateCmd.Flags().StringVar(&tokenProject, "project", "*", "project the token is limited to ('*' for all)")
+	tokenCreateCmd.Flags().StringSliceVar(&tokenEnvs, "env", nil, "environments the token may promote to (default all)")
+	tokenCreateCmd.Flags().StringSliceVar(&tokenActions, "action", nil, "actions to grant (build.submit, build.yank, release.promote, release.rollback, events.read)")
+	tokenCreateCmd.Flags().DurationVar(&tokenExpires, "expires", 0, "lifetime of the token, e.g. 2160h (default never)")
+
+	tokenRevokeCmd.Flags().StringVar(&tokenID, "id", "", "token id (from token list)")
+
+	tokenCmd.AddCommand(tokenCreateCmd, tokenListCmd, tokenRevokeCmd)
+	rootCmd.AddCommand(tokenCmd)
+}
@@ -0,0 +1,250 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// localArtifact is a file to be uploaded as a content-addressed artifact.
+type localArtifact struct {
+	LocalPath string
+	Name      string
+	Size      int64
+	SHA256    string
+}
+
+// hashFiles computes the SHA256 of each file, using up to parallel workers.
+func hashFiles(paths []string, parallel int) ([]localArtifact, error) {
+	artifacts := make([]localArtifact, len(paths))
+	errs := make([]error, len(paths))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallel)
+	for i, path := range paths {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, path string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			f, err := os.Open(path)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer f.Close()
+
+			h := sha256.New()
+			n, err := io.Copy(h, f)
+			if err != nil {
+				errs[i] = fmt.Errorf("hashing %s: %w", path, err)
+				return
+			}
+			artifacts[i] = localArtifact{
+				LocalPath: path,
+				Name:      filepath.Base(path),
+				Size:      n,
+				SHA256:    hex.EncodeToString(h.Sum(nil)),
+			}
+		}(i, path)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return artifacts, nil
+}
+
+// uploader pushes artifacts to the release server's artifact endpoint.
+type uploader struct {
+	server   string
+	token    string
+	parallel int
+	retries  int
+	quiet    bool
+
+	total int64
+	sent  atomic.Int64
+}
+
+// uploadAll uploads every artifact the server does not already have. It
+// returns the names of files that still failed after all retries; rerunning
+// the same command resumes with just those.
+func (u *uploader) uploadAll(artifacts []localArtifact) []string {
+	for _, a := range artifacts {
+		u.total += a.Size
+	}
+
+	stop := make(chan struct{})
+	if !u.quiet {
+		go u.reportProgress(stop)
+	}
+
+	var (
+		mu     sync.Mutex
+		failed []string
+		wg     sync.WaitGroup
+		done   atomic.Int32
+	)
+	sem := make(chan struct{}, u.parallel)
+	for _, a := range artifacts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(a localArtifact) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			skipped, err := u.uploadWithRetry(a)
+			n := done.Add(1)
+			if err != nil {
+				u.logf("[%d/%d] %s: FAILED: %v\n", n, len(artifacts), a.Name, err)
+				mu.Lock()
+				failed = append(failed, a.Name)
+				mu.Unlock()
+				return
+			}
+			status := "uploaded"
+			if skipped {
+				status = "already on server"
+			}
+			u.logf("[%d/%d] %s (%s): %s\n", n, len(artifacts), a.Name, formatBytes(a.Size), status)
+		}(a)
+	}
+	wg.Wait()
+	close(stop)
+	return failed
+}
+
+func (u *uploader) uploadWithRetry(a localArtifact) (skipped bool, err error) {
+	for attempt := 0; attempt <= u.retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(1<<(attempt-1)) * time.Second)
+		}
+		var exists bool
+		exists, err = u.exists(a.SHA256)
+		if err == nil && exists {
+			u.sent.Add(a.Size)
+			return true, nil
+		}
+		if err == nil {
+			if err = u.upload(a); err == nil {
+				return false, nil
+			}
+		}
+	}
+	return false, err
+}
+
+func (u *uploader) exists(hash string) (bool, error) {
+	req, err := http.NewRequest("HEAD", strings.TrimRight(u.server, "/")+"/api/v1/artifacts/"+hash, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+u.token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("checking artifact returned %d", resp.StatusCode)
+}
+
+func (u *uploader) upload(a localArtifact) error {
+	f, err := os.Open(a.LocalPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	body := &progressReader{r: f, sent: &u.sent}
+	req, err := http.NewRequest("PUT", strings.TrimRight(u.server, "/")+"/api/v1/artifacts/"+a.SHA256, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = a.Size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Authorization", "Bearer "+u.token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		u.sent.Add(-body.n)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		u.sent.Add(-body.n)
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("upload returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func (u *uploader) reportProgress(stop chan struct{}) {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			sent := u.sent.Load()
+			pct := 100.0
+			if u.total > 0 {
+				pct = float64(sent) * 100 / float64(u.total)
+			}
+			fmt.Fprintf(os.Stderr, "  %s / %s (%.0f%%)\n", formatBytes(sent), formatBytes(u.total), pct)
+		}
+	}
+}
+
+func (u *uploader) logf(format string, args ...any) {
+	if !u.quiet {
+		fmt.Fprintf(os.Stderr, format, args...)
+	}
+}
+
+// progressReader adds the bytes read through it to a shared counter.
+type progressReader struct {
+	r    io.Reader
+	sent *atomic.Int64
+	n    int64
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.n += int64(n)
+	p.sent.Add(int64(n))
+	return n, err
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
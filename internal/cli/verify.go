@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"slices"
 	"strings"
 	"sync"
 	"text/tabwriter"
@@ -15,21 +16,6 @@ import (
 	"github.com/spf13/cobra"
 )
 
-type projectDef struct {
-	Name      string
-	HealthURL string // empty if no direct health endpoint
-}
-
-var projects = []projectDef{
-	{Name: "hydrarelease", HealthURL: "https://releases.experiencenet.com"},
-	{Name: "hydratransfer", HealthURL: "https://hydratransfer.experiencenet.com"},
-	{Name: "hydrapipeline", HealthURL: "https://hydrapipeline.experiencenet.com"},
-	{Name: "hydraexperiencelibrary", HealthURL: "https://hydraexperiencelibrary.experiencenet.com"},
-	{Name: "hydracluster", HealthURL: "https://hydracluster.experiencenet.com"},
-	{Name: "hydraguard", HealthURL: "http://hydraguard.experiencenet.com:8081"},
-	{Name: "hydrabody", HealthURL: ""},
-}
-
 var (
 	verifyServer       string
 	verifyProject      string
@@ -52,18 +38,27 @@ var verifyCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		client := &http.Client{Timeout: time.Duration(verifyTimeout) * time.Second}
 
-		selected := projects
-		if verifyProject != "" {
-			selected = nil
-			for _, p := range projects {
-				if p.Name == verifyProject {
-					selected = []projectDef{p}
-					break
-				}
+		projects, err := fetchProjects(client, verifyServer)
+		if err != nil {
+			return err
+		}
+
+		// Only projects released to production have a version to compare.
+		var selected []registeredProject
+		for _, p := range projects {
+			if verifyProject != "" && p.Name != verifyProject {
+				continue
 			}
-			if selected == nil {
-				return fmt.Errorf("unknown project: %s", verifyProject)
+			if len(p.Environments) > 0 && !slices.Contains(p.Environments, "production") {
+				continue
+			}
+			selected = append(selected, p)
+		}
+		if len(selected) == 0 {
+			if verifyProject != "" {
+				return fmt.Errorf("unknown project: %s (register it with 'hydrarelease project add')", verifyProject)
 			}
+			return fmt.Errorf("no production projects registered on %s (register them with 'hydrarelease project add')", verifyServer)
 		}
 
 		var (
@@ -118,12 +113,7 @@ var verifyCmd = &cobra.Command{
 						return // error already recorded above
 					}
 					for _, node := range clusterNodes {
-						status := "ok"
-						if node.BodyVersion == "" {
-							status = "unknown"
-						} else if semver.Compare(released, node.BodyVersion) != 0 {
-							status = "outdated"
-						}
+						status := deployStatus(released, node.BodyVersion)
 						label := node.Name
 						if node.District != "" {
 							label = fmt.Sprintf("%s (%s)", node.Name, node.District)
@@ -168,10 +158,7 @@ var verifyCmd = &cobra.Command{
 					return
 				}
 
-				status := "ok"
-				if semver.Compare(released, deployed) != 0 {
-					status = "outdated"
-				}
+				status := deployStatus(released, deployed)
 
 				mu.Lock()
 				results = append(results, verifyResult{
@@ -250,10 +237,32 @@ func fetchLatestVersion(client *http.Client, server, project string) (string, er
 	if latest.Version == "" {
 		return "", fmt.Errorf("empty version in latest.json")
 	}
+	if _, err := semver.Parse(latest.Version); err != nil {
+		return "", fmt.Errorf("latest.json: %w", err)
+	}
 
 	return latest.Version, nil
 }
 
+// deployStatus compares a deployed version against the released one by
+// SemVer precedence; build metadata does not count as a difference.
+func deployStatus(released, deployed string) string {
+	if deployed == "" {
+		return "unknown"
+	}
+	dv, err := semver.Parse(deployed)
+	if err != nil {
+		return "invalid version"
+	}
+	switch dv.Compare(semver.MustParse(released)) {
+	case 0:
+		return "ok"
+	case 1:
+		return "ahead"
+	}
+	return "outdated"
+}
+
 func fetchHealthVersion(client *http.Client, healthURL string) (string, error) {
 	url := strings.TrimRight(healthURL, "/") + "/api/v1/health"
 
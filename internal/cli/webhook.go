@@ -0,0 +1,252 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	webhookServer   string
+	webhookToken    string
+	webhookURL      string
+	webhookSecret   string
+	webhookProjects []string
+	webhookEvents   []string
+	webhookID       string
+	webhookStatus   string
+	webhookJSON     bool
+)
+
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "Manage outbound webhooks for build and release events",
+}
+
+var webhookAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Subscribe a URL to events",
+	Long: `Subscribes a URL to events. Payloads are POSTed as JSON and signed with
+HMAC-SHA256 in the X-Hydrarelease-Signature header ("sha256=<hex>"). The
+secret is generated unless given, and printed once:
+
+  hydrarelease webhook add --url https://chat.example.com/hooks/releases \
+    --project hydraguard --event 'release.*'`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := resolveToken(webhookToken)
+		if token == "" {
+			return fmt.Errorf("auth token required: use --token or HYDRARELEASE_AUTH_TOKEN env")
+		}
+		if webhookURL == "" {
+			return fmt.Errorf("--url is required")
+		}
+
+		body := map[string]any{
+			"url":        webhookURL,
+			"secret":     webhookSecret,
+			"projects":   webhookProjects,
+			"events":     webhookEvents,
+			"created_by": os.Getenv("USER"),
+		}
+
+		resp, err := doJSON(webhookServer, token, "POST", "/api/v1/webhooks", body)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		var result map[string]any
+		json.NewDecoder(resp.Body).Decode(&result)
+
+		if resp.StatusCode != http.StatusCreated {
+			return fmt.Errorf("add failed (%d): %v", resp.StatusCode, result["error"])
+		}
+
+		fmt.Printf("Created webhook %v for %s\n", result["id"], webhookURL)
+		if webhookSecret == "" {
+			fmt.Printf("\n  secret: %v\n\nStore it now; it will not be shown again.\n", result["secret"])
+		}
+		return nil
+	},
+}
+
+var webhookListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List webhooks",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := resolveToken(webhookToken)
+		if token == "" {
+			return fmt.Errorf("auth token required: use --token or HYDRARELEASE_AUTH_TOKEN env")
+		}
+
+		resp, err := doJSON(webhookServer, token, "GET", "/api/v1/webhooks", nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("list failed (%d)", resp.StatusCode)
+		}
+		var hooks []struct {
+			ID        string    `json:"id"`
+			URL       string    `json:"url"`
+			Projects  []string  `json:"projects"`
+			Events    []string  `json:"events"`
+			CreatedAt time.Time `json:"created_at"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&hooks); err != nil {
+			return fmt.Errorf("parsing webhooks: %w", err)
+		}
+
+		if webhookJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(hooks)
+		}
+
+		if len(hooks) == 0 {
+			fmt.Println("No webhooks configured.")
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "ID\tURL\tPROJECTS\tEVENTS\tCREATED\n")
+		for _, h := range hooks {
+			projects, events := "all", "all"
+			if len(h.Projects) > 0 {
+				projects = strings.Join(h.Projects, ",")
+			}
+			if len(h.Events) > 0 {
+				events = strings.Join(h.Events, ",")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", h.ID, h.URL, projects, events, h.CreatedAt.Format("2006-01-02 15:04"))
+		}
+		return tw.Flush()
+	},
+}
+
+var webhookRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Delete a webhook and its pending deliveries",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := resolveToken(webhookToken)
+		if token == "" {
+			return fmt.Errorf("auth token required: use --token or HYDRARELEASE_AUTH_TOKEN env")
+		}
+		if webhookID == "" {
+			return fmt.Errorf("--id is required")
+		}
+
+		resp, err := doJSON(webhookServer, token, "DELETE", "/api/v1/webhooks/"+webhookID, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		var result map[string]any
+		json.NewDecoder(resp.Body).Decode(&result)
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("remove failed (%d): %v", resp.StatusCode, result["error"])
+		}
+
+		fmt.Printf("Deleted webhook %s\n", webhookID)
+		return nil
+	},
+}
+
+var webhookDeliveriesCmd = &cobra.Command{
+	Use:   "deliveries",
+	Short: "Show the delivery log of a webhook",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token := resolveToken(webhookToken)
+		if token == "" {
+			return fmt.Errorf("auth token required: use --token or HYDRARELEASE_AUTH_TOKEN env")
+		}
+		if webhookID == "" {
+			return fmt.Errorf("--id is required")
+		}
+
+		path := "/api/v1/webhooks/" + webhookID + "/deliveries"
+		if webhookStatus != "" {
+			path += "?status=" + webhookStatus
+		}
+		resp, err := doJSON(webhookServer, token, "GET", path, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			var result map[string]any
+			json.NewDecoder(resp.Body).Decode(&result)
+			return fmt.Errorf("deliveries failed (%d): %v", resp.StatusCode, result["error"])
+		}
+		var deliveries []struct {
+			ID          string    `json:"id"`
+			Event       string    `json:"event"`
+			Status      string    `json:"status"`
+			Attempts    int       `json:"attempts"`
+			NextAttempt time.Time `json:"next_attempt"`
+			LastStatus  int       `json:"last_status"`
+			LastError   string    `json:"last_error"`
+			CreatedAt   time.Time `json:"created_at"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&deliveries); err != nil {
+			return fmt.Errorf("parsing deliveries: %w", err)
+		}
+
+		if webhookJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(deliveries)
+		}
+
+		if len(deliveries) == 0 {
+			fmt.Println("No deliveries.")
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "ID\tEVENT\tCREATED\tSTATUS\tATTEMPTS\tLAST\tNEXT\n")
+		for _, d := range deliveries {
+			last := d.LastError
+			if last == "" && d.LastStatus != 0 {
+				last = fmt.Sprintf("HTTP %d", d.LastStatus)
+			}
+			next := "-"
+			if d.Status == "pending" && !d.NextAttempt.IsZero() {
+				next = d.NextAttempt.Local().Format("15:04:05")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+				d.ID, d.Event, d.CreatedAt.Local().Format("2006-01-02 15:04"), d.Status, d.Attempts, orDash(last), next)
+		}
+		return tw.Flush()
+	},
+}
+
+func init() {
+	webhookCmd.PersistentFlags().StringVar(&webhookServer, "server", "https://releases.experiencenet.com", "release server URL")
+	webhookCmd.PersistentFlags().StringVar(&webhookToken, "token", "", "admin bearer token (or HYDRARELEASE_AUTH_TOKEN env)")
+	webhookCmd.PersistentFlags().BoolVar(&webhookJSON, "json", false, "output as JSON")
+
+	webhookAddCmd.Flags().StringVar(&webhookURL, "url", "", "URL to POST events to")
+	webhookAddCmd.Flags().StringVar(&webhookSecret, "secret", "", "HMAC signing secret (default generated)")
+	webhookAddCmd.Flags().StringSliceVar(&webhookProjects, "project", nil, "only events for these projects (default all)")
+	webhookAddCmd.Flags().StringSliceVar(&webhookEvents, "event", nil, "only these event types, globs allowed, e.g. 'release.*' (default all)")
+
+	for _, c := range []*cobra.Command{webhookRemoveCmd, webhookDeliveriesCmd} {
+		c.Flags().StringVar(&webhookID, "id", "", "webhook id (from webhook list)")
+	}
+	webhookDeliveriesCmd.Flags().StringVar(&webhookStatus, "status", "", "only pending, delivered or failed deliveries")
+
+	webhookCmd.AddCommand(webhookAddCmd, webhookListCmd, webhookRemoveCmd, webhookDeliveriesCmd)
+	rootCmd.AddCommand(webhookCmd)
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	reg := NewRegistry()
+	requests := reg.Counter("test_requests_total", "Requests.", "route", "code")
+	latency := reg.Histogram("test_duration_seconds", "Latency.", []float64{0.1, 1}, "route")
+	reg.GaugeFunc("test_builds", "Builds.", func() (float64, bool) { return 7, true })
+	reg.GaugeFunc("test_missing", "Unavailable.", func() (float64, bool) { return 0, false })
+
+	requests.Inc("GET /a", "200")
+	requests.Inc("GET /a", "200")
+	requests.Add(3, `GET /"b"`, "500")
+	latency.Observe(0.05, "GET /a")
+	latency.Observe(0.5, "GET /a")
+	latency.Observe(2, "GET /a")
+
+	var out strings.Builder
+	if err := reg.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+
+	for _, want := range []string{
+		"# TYPE test_requests_total counter\n",
+		`test_requests_total{route="GET /a",code="200"} 2` + "\n",
+		`test_requests_total{route="GET /\"b\"",code="500"} 3` + "\n",
+		"# TYPE test_duration_seconds histogram\n",
+		`test_duration_seconds_bucket{route="GET /a",le="0.1"} 1` + "\n",
+		`test_duration_seconds_bucket{route="GET /a",le="1"} 2` + "\n",
+		`test_duration_seconds_bucket{route="GET /a",le="+Inf"} 3` + "\n",
+		`test_duration_seconds_sum{route="GET /a"} 2.55` + "\n",
+		`test_duration_seconds_count{route="GET /a"} 3` + "\n",
+		"test_builds 7\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output is missing %q\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "test_missing") {
+		t.Error("unavailable gauge was written")
+	}
+}
+
+func TestLabelCountMismatchPanics(t *testing.T) {
+	c := NewRegistry().Counter("test_total", "Test.", "a", "b")
+	defer func() {
+		if recover() == nil {
+			t.Error("Inc with too few label values did not panic")
+		}
+	}()
+	c.Inc("only-one")
+}
@@ -0,0 +1,119 @@
+package oidc
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minRefresh limits how often an unknown key ID triggers a JWKS refetch.
+const minRefresh = time.Minute
+
+// keySet caches the RSA keys of a JWKS document loaded from a file or URL.
+type keySet struct {
+	source string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func newKeySet(source string) *keySet {
+	return &keySet{source: source, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// key returns the key with the given ID, refetching the JWKS when the ID is
+// unknown so that key rotations are picked up.
+func (k *keySet) key(kid string) (*rsa.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if key, ok := k.keys[kid]; ok {
+		return key, nil
+	}
+	if k.keys != nil && time.Since(k.fetchedAt) < minRefresh {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	keys, err := k.fetch()
+	if err != nil {
+		return nil, err
+	}
+	k.keys, k.fetchedAt = keys, time.Now()
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (k *keySet) fetch() (map[string]*rsa.PublicKey, error) {
+	var data []byte
+	if strings.HasPrefix(k.source, "http://") || strings.HasPrefix(k.source, "https://") {
+		resp, err := k.client.Get(k.source)
+		if err != nil {
+			return nil, fmt.Errorf("fetching JWKS: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetching JWKS: %s returned %d", k.source, resp.StatusCode)
+		}
+		data, err = io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return nil, fmt.Errorf("reading JWKS: %w", err)
+		}
+	} else {
+		var err error
+		data, err = os.ReadFile(k.source)
+		if err != nil {
+			return nil, fmt.Errorf("reading JWKS: %w", err)
+		}
+	}
+	return parseJWKS(data)
+}
+
+// parseJWKS extracts the RSA keys of a JWKS document, keyed by kid.
+func parseJWKS(data []byte) (map[string]*rsa.PublicKey, error) {
+	var doc struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range doc.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: invalid modulus", jwk.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("key %s: invalid exponent", jwk.Kid)
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS contains no RSA signing keys")
+	}
+	return keys, nil
+}
@@ -0,0 +1,252 @@
+// Package oidc verifies OpenID Connect tokens issued to CI jobs, such as
+// GitHub Actions ID tokens, and maps their claims to release permissions.
+package oidc
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+	"gopkg.in/yaml.v3"
+)
+
+// GitHubIssuer is the issuer of GitHub Actions ID tokens.
+const GitHubIssuer = "https://token.actions.githubusercontent.com"
+
+// leeway absorbs clock skew when checking exp and nbf.
+const leeway = time.Minute
+
+// Config selects the trusted issuer and maps token claims to permissions.
+type Config struct {
+	Issuer   string `yaml:"issuer"`   // default GitHubIssuer
+	Audience string `yaml:"audience"` // required; the aud CI requests its token for
+	JWKS     string `yaml:"jwks"`     // file path or URL; default <issuer>/.well-known/jwks
+	Rules    []Rule `yaml:"rules"`
+}
+
+// Rule grants a repository, optionally only on some refs, actions on
+// projects and environments. Every matching rule contributes its grants.
+type Rule struct {
+	Repository   string   `yaml:"repository"`             // "owner/repo"
+	Ref          string   `yaml:"ref,omitempty"`          // glob such as "refs/tags/v*"; empty = any
+	Projects     []string `yaml:"projects"`               // projects the repository may publish
+	Environments []string `yaml:"environments,omitempty"` // empty = all
+	Actions      []string `yaml:"actions,omitempty"`      // default build.submit and release.promote
+}
+
+func (r Rule) matches(c *Claims) bool {
+	if !strings.EqualFold(r.Repository, c.Repository) {
+		return false
+	}
+	if r.Ref == "" {
+		return true
+	}
+	ok, _ := path.Match(r.Ref, c.Ref)
+	return ok
+}
+
+// LoadConfig reads and validates an OIDC configuration file.
+func LoadConfig(file string) (*Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading OIDC config: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing OIDC config: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return &cfg, nil
+}
+
+func (c *Config) validate() error {
+	if c.Issuer == "" {
+		c.Issuer = GitHubIssuer
+	}
+	if c.JWKS == "" {
+		c.JWKS = strings.TrimRight(c.Issuer, "/") + "/.well-known/jwks"
+	}
+	if c.Audience == "" {
+		return fmt.Errorf("audience is required")
+	}
+	for i, r := range c.Rules {
+		if r.Repository == "" || len(r.Projects) == 0 {
+			return fmt.Errorf("rule %d needs a repository and projects", i+1)
+		}
+		if _, err := path.Match(r.Ref, ""); err != nil {
+			return fmt.Errorf("rule %d: invalid ref pattern %q", i+1, r.Ref)
+		}
+		for _, a := range r.Actions {
+			if err := (store.TokenScope{Project: "x", Actions: []string{a}}).Validate(); err != nil {
+				return fmt.Errorf("rule %d: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Scopes returns the permissions the rules grant to a verified token.
+func (c *Config) Scopes(claims *Claims) []store.TokenScope {
+	var scopes []store.TokenScope
+	for _, r := range c.Rules {
+		if !r.matches(claims) {
+			continue
+		}
+		actions := r.Actions
+		if len(actions) == 0 {
+			actions = []string{store.ActionBuildSubmit, store.ActionPromote}
+		}
+		for _, p := range r.Projects {
+			scopes = append(scopes, store.TokenScope{Project: p, Environments: r.Environments, Actions: actions})
+		}
+	}
+	return scopes
+}
+
+// Claims holds the registered JWT claims and the GitHub Actions claims
+// used for authorization and provenance.
+type Claims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  audience `json:"aud"`
+	Expiry    int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+
+	Repository  string `json:"repository"`
+	Ref         string `json:"ref"`
+	SHA         string `json:"sha"`
+	Actor       string `json:"actor"`
+	Workflow    string `json:"workflow"`
+	WorkflowRef string `json:"workflow_ref"`
+	RunID       string `json:"run_id"`
+	RunAttempt  string `json:"run_attempt"`
+}
+
+// Provenance returns the claims worth recording with a build.
+func (c *Claims) Provenance() map[string]string {
+	meta := map[string]string{
+		"oidc_issuer": c.Issuer,
+		"repository":  c.Repository,
+		"ref":         c.Ref,
+		"sha":         c.SHA,
+		"actor":       c.Actor,
+		"workflow":    c.WorkflowRef,
+		"run_id":      c.RunID,
+		"run_attempt": c.RunAttempt,
+	}
+	if c.Issuer == GitHubIssuer && c.Repository != "" && c.RunID != "" {
+		meta["workflow_run"] = "https://github.com/" + c.Repository + "/actions/runs/" + c.RunID
+	}
+	for k, v := range meta {
+		if v == "" {
+			delete(meta, k)
+		}
+	}
+	return meta
+}
+
+// audience accepts the aud claim as a single string or a list.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("invalid aud claim")
+	}
+	*a = many
+	return nil
+}
+
+// Verifier checks RS256-signed ID tokens against the configured issuer,
+// audience and JWKS.
+type Verifier struct {
+	Config *Config
+	keys   *keySet
+	now    func() time.Time
+}
+
+// NewVerifier creates a Verifier for cfg.
+func NewVerifier(cfg *Config) *Verifier {
+	return &Verifier{Config: cfg, keys: newKeySet(cfg.JWKS), now: time.Now}
+}
+
+// LooksLikeJWT reports whether a bearer token has the shape of a compact JWS,
+// so callers can route it here instead of to static token checks.
+func LooksLikeJWT(token string) bool {
+	return strings.HasPrefix(token, "eyJ") && strings.Count(token, ".") == 2
+}
+
+// Verify checks the token's signature, issuer, audience and lifetime and
+// returns its claims.
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed token header")
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", header.Alg)
+	}
+	key, err := v.keys.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature")
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return nil, fmt.Errorf("invalid token signature")
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims")
+	}
+	if claims.Issuer != v.Config.Issuer {
+		return nil, fmt.Errorf("untrusted issuer %q", claims.Issuer)
+	}
+	if !slices.Contains(claims.Audience, v.Config.Audience) {
+		return nil, fmt.Errorf("token is not for audience %q", v.Config.Audience)
+	}
+	now := v.now()
+	if claims.Expiry == 0 || now.After(time.Unix(claims.Expiry, 0).Add(leeway)) {
+		return nil, fmt.Errorf("token expired")
+	}
+	if claims.NotBefore != 0 && now.Add(leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return nil, fmt.Errorf("token not valid yet")
+	}
+	return &claims, nil
+}
+
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,159 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cederikdotcom/hydrarelease/internal/store"
+)
+
+// testIssuer signs tokens with a throwaway key published in a JWKS file.
+type testIssuer struct {
+	key  *rsa.PrivateKey
+	kid  string
+	jwks string
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "test-key",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	data, _ := json.Marshal(doc)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return &testIssuer{key: key, kid: "test-key", jwks: path}
+}
+
+func (ti *testIssuer) sign(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	enc := func(v any) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	input := enc(map[string]string{"alg": "RS256", "kid": ti.kid, "typ": "JWT"}) + "." + enc(claims)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, ti.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func githubClaims() map[string]any {
+	return map[string]any{
+		"iss":          GitHubIssuer,
+		"aud":          "https://releases.example.com",
+		"exp":          time.Now().Add(5 * time.Minute).Unix(),
+		"nbf":          time.Now().Add(-time.Minute).Unix(),
+		"repository":   "acme/app",
+		"ref":          "refs/tags/v1.2.0",
+		"sha":          "abc123",
+		"workflow_ref": "acme/app/.github/workflows/release.yml@refs/tags/v1.2.0",
+		"run_id":       "42",
+		"run_attempt":  "1",
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ti := newTestIssuer(t)
+	v := NewVerifier(&Config{Issuer: GitHubIssuer, Audience: "https://releases.example.com", JWKS: ti.jwks})
+
+	token := ti.sign(t, githubClaims())
+	if !LooksLikeJWT(token) {
+		t.Fatal("signed token does not look like a JWT")
+	}
+	claims, err := v.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	meta := claims.Provenance()
+	if meta["repository"] != "acme/app" || meta["workflow_run"] != "https://github.com/acme/app/actions/runs/42" {
+		t.Errorf("Provenance = %v", meta)
+	}
+	if _, ok := meta["actor"]; ok {
+		t.Error("empty claims should be left out of provenance")
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(map[string]any)
+	}{
+		{"expired", func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{"wrong audience", func(c map[string]any) { c["aud"] = []string{"https://elsewhere.example.com"} }},
+		{"wrong issuer", func(c map[string]any) { c["iss"] = "https://evil.example.com" }},
+		{"not yet valid", func(c map[string]any) { c["nbf"] = time.Now().Add(time.Hour).Unix() }},
+	}
+	for _, tt := range tests {
+		c := githubClaims()
+		tt.mutate(c)
+		if _, err := v.Verify(ti.sign(t, c)); err == nil {
+			t.Errorf("%s: Verify succeeded", tt.name)
+		}
+	}
+
+	// A token signed by another key must fail even with a known kid.
+	other := newTestIssuer(t)
+	if _, err := v.Verify(other.sign(t, githubClaims())); err == nil {
+		t.Error("Verify accepted a token signed with an unknown key")
+	}
+	// Tampering with the payload breaks the signature.
+	parts := []byte(token)
+	parts[len(token)/2] ^= 1
+	if _, err := v.Verify(string(parts)); err == nil {
+		t.Error("Verify accepted a tampered token")
+	}
+}
+
+func TestScopes(t *testing.T) {
+	cfg := &Config{Audience: "x", Rules: []Rule{
+		{Repository: "acme/app", Ref: "refs/tags/v*", Projects: []string{"app"}},
+		{Repository: "acme/app", Ref: "refs/heads/main", Projects: []string{"app"}, Environments: []string{"dev"}},
+		{Repository: "acme/tools", Projects: []string{"tool"}, Actions: []string{store.ActionBuildSubmit}},
+	}}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	tag := &store.APIToken{Scopes: cfg.Scopes(&Claims{Repository: "acme/app", Ref: "refs/tags/v1.0.0"})}
+	if !tag.Allows(store.ActionPromote, "app", "production") || tag.Allows(store.ActionPromote, "tool", "dev") {
+		t.Errorf("tag scopes = %+v", tag.Scopes)
+	}
+	main := &store.APIToken{Scopes: cfg.Scopes(&Claims{Repository: "ACME/app", Ref: "refs/heads/main"})}
+	if !main.Allows(store.ActionPromote, "app", "dev") || main.Allows(store.ActionPromote, "app", "production") {
+		t.Errorf("main scopes = %+v", main.Scopes)
+	}
+	if s := cfg.Scopes(&Claims{Repository: "acme/app", Ref: "refs/heads/feature/x"}); len(s) != 0 {
+		t.Errorf("feature branch got scopes %+v", s)
+	}
+	if s := cfg.Scopes(&Claims{Repository: "acme/other", Ref: "refs/tags/v1"}); len(s) != 0 {
+		t.Errorf("unknown repository got scopes %+v", s)
+	}
+
+	bad := &Config{Audience: "x", Rules: []Rule{{Repository: "acme/app", Projects: []string{"app"}, Actions: []string{"delete"}}}}
+	if err := bad.validate(); err == nil {
+		t.Error("validate accepted an unknown action")
+	}
+	if err := (&Config{}).validate(); err == nil {
+		t.Error("validate accepted a config without audience")
+	}
+}
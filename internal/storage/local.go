@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Local stores files under a directory and serves them directly, with
+// Range and conditional request support.
+type Local struct {
+	root string
+}
+
+// NewLocal creates a Local rooted at dir.
+func NewLocal(dir string) *Local {
+	return &Local{root: dir}
+}
+
+// Name implements Storage.
+func (l *Local) Name() string { return "local" }
+
+func (l *Local) fullPath(path string) (string, error) {
+	p, err := cleanPath(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.root, filepath.FromSlash(p)), nil
+}
+
+// Put implements Storage. The file is written to a temporary name and
+// renamed into place so readers never see a partial file.
+func (l *Local) Put(path string, r io.Reader, size int64) error {
+	full, err := l.fullPath(path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return fmt.Errorf("creating directory for %s: %w", path, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(full), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", path, err)
+	}
+	n, err := io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && size >= 0 && n != size {
+		err = fmt.Errorf("wrote %d bytes, expected %d", n, size)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), full); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// Delete implements Storage.
+func (l *Local) Delete(path string) error {
+	full, err := l.fullPath(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(full); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Link implements Storage with hardlinks, copying if linking fails.
+func (l *Local) Link(source string, targets []string) error {
+	src, err := l.fullPath(source)
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		dst, err := l.fullPath(target)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		os.Remove(dst)
+		if err := os.Link(src, dst); err == nil {
+			continue
+		}
+		f, err := os.Open(src)
+		if err != nil {
+			return fmt.Errorf("linking %s: %w", source, err)
+		}
+		err = l.Put(target, f, -1)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Serve implements Storage.
+func (l *Local) Serve(w http.ResponseWriter, r *http.Request, path string) {
+	full, err := l.fullPath(path)
+	if err != nil {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+	f, err := os.Open(full)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLocalPutLinkServe(t *testing.T) {
+	l := NewLocal(t.TempDir())
+
+	if err := l.Put("artifacts/abc", strings.NewReader("hello world"), 11); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := l.Link("artifacts/abc", []string{"builds/app/1/app.bin"}); err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/app/production/1/app.bin", nil)
+	req.Header.Set("Range", "bytes=6-10")
+	rec := httptest.NewRecorder()
+	l.Serve(rec, req, "builds/app/1/app.bin")
+
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("status = %d, want 206", rec.Code)
+	}
+	if body, _ := io.ReadAll(rec.Body); string(body) != "world" {
+		t.Errorf("body = %q, want %q", body, "world")
+	}
+
+	if err := l.Delete("builds/app/1/app.bin"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := l.Delete("builds/app/1/app.bin"); err != nil {
+		t.Errorf("Delete of missing file: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	l.Serve(rec, httptest.NewRequest("GET", "/", nil), "builds/app/1/app.bin")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status after delete = %d, want 404", rec.Code)
+	}
+}
+
+func TestLocalRejectsEscapingPaths(t *testing.T) {
+	l := NewLocal(t.TempDir())
+	for _, p := range []string{"../x", "a/../../x", "/etc/passwd", "a//b", ""} {
+		if err := l.Put(p, strings.NewReader("x"), 1); err == nil {
+			t.Errorf("Put(%q) succeeded, want error", p)
+		}
+	}
+}
+
+func TestLocalPutRejectsShortBody(t *testing.T) {
+	l := NewLocal(t.TempDir())
+	if err := l.Put("a", strings.NewReader("abc"), 10); err == nil {
+		t.Error("Put with truncated body succeeded")
+	}
+}
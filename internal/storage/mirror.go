@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Mirror stores files on hydramirror and redirects downloads to it.
+type Mirror struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewMirror creates a Mirror for the hydramirror at url.
+func NewMirror(url, token string) *Mirror {
+	return &Mirror{
+		url:    strings.TrimRight(url, "/"),
+		token:  token,
+		client: &http.Client{Timeout: 30 * time.Minute},
+	}
+}
+
+// Name implements Storage.
+func (m *Mirror) Name() string { return "mirror" }
+
+// URL returns the hydramirror base URL.
+func (m *Mirror) URL() string { return m.url }
+
+func (m *Mirror) fileURL(path string) string {
+	return m.url + "/api/v1/files/" + path
+}
+
+// Put implements Storage.
+func (m *Mirror) Put(path string, r io.Reader, size int64) error {
+	req, err := http.NewRequest("PUT", m.fileURL(path), r)
+	if err != nil {
+		return err
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	req.Header.Set("Authorization", "Bearer "+m.token)
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("mirror PUT %s: %w", path, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("mirror PUT %s returned %d", path, resp.StatusCode)
+	}
+	return nil
+}
+
+// Delete implements Storage.
+func (m *Mirror) Delete(path string) error {
+	req, err := http.NewRequest("DELETE", m.fileURL(path), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.token)
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("mirror DELETE %s: %w", path, err)
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return fmt.Errorf("mirror DELETE %s returned %d", path, resp.StatusCode)
+	}
+}
+
+// Link implements Storage using hydramirror's hardlink endpoint.
+func (m *Mirror) Link(source string, targets []string) error {
+	body, _ := json.Marshal(map[string]any{
+		"source":  source,
+		"targets": targets,
+	})
+
+	req, err := http.NewRequest("POST", m.url+"/api/v1/link", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+m.token)
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("mirror link %s: %w", source, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("mirror link %s returned %d", source, resp.StatusCode)
+	}
+	return nil
+}
+
+// Serve implements Storage by redirecting to hydramirror.
+func (m *Mirror) Serve(w http.ResponseWriter, r *http.Request, path string) {
+	http.Redirect(w, r, m.fileURL(path), http.StatusFound)
+}
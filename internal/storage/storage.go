@@ -0,0 +1,39 @@
+// Package storage abstracts where release and build files live: on
+// hydramirror in production, or on local disk for dev and small installs.
+package storage
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Storage stores files under slash-separated relative paths such as
+// "releases/<project>/<channel>/<version>/<file>".
+type Storage interface {
+	// Put stores the contents of r at path, replacing any existing file.
+	// size is the content length, or -1 if unknown.
+	Put(path string, r io.Reader, size int64) error
+	// Delete removes the file at path. A missing file is not an error.
+	Delete(path string) error
+	// Link makes the file at source also available at each target.
+	Link(source string, targets []string) error
+	// Serve answers a download request for path.
+	Serve(w http.ResponseWriter, r *http.Request, path string)
+	// Name identifies the backend in logs and health output.
+	Name() string
+}
+
+// cleanPath rejects paths that could escape the storage root.
+func cleanPath(path string) (string, error) {
+	if path == "" || strings.HasPrefix(path, "/") || strings.Contains(path, "\\") {
+		return "", fmt.Errorf("invalid storage path %q", path)
+	}
+	for _, part := range strings.Split(path, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", fmt.Errorf("invalid storage path %q", path)
+		}
+	}
+	return path, nil
+}
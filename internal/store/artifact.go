@@ -0,0 +1,210 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ArtifactMirrorPrefix is the hydramirror directory holding content-addressed
+// artifacts, stored as <prefix><sha256>.
+const ArtifactMirrorPrefix = "artifacts/"
+
+// artifactGCGrace protects freshly uploaded or reused artifacts from
+// collection while the build that will reference them is still being
+// submitted.
+const artifactGCGrace = 24 * time.Hour
+
+// artifactTouchInterval limits how often Touch rewrites artifacts.yaml for
+// the same artifact.
+const artifactTouchInterval = time.Hour
+
+// Artifact is a verified, content-addressed file on hydramirror.
+type Artifact struct {
+	SHA256     string    `yaml:"sha256" json:"sha256"`
+	Size       int64     `yaml:"size" json:"size"`
+	UploadedAt time.Time `yaml:"uploaded_at" json:"uploaded_at"`
+	// LastUsedAt is when a client last found the artifact already uploaded
+	// or a build was verified against it.
+	LastUsedAt time.Time `yaml:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+}
+
+// lastUsed returns the later of the upload and last-use times.
+func (a *Artifact) lastUsed() time.Time {
+	if a.LastUsedAt.After(a.UploadedAt) {
+		return a.LastUsedAt
+	}
+	return a.UploadedAt
+}
+
+// MirrorPath returns the artifact's location on hydramirror.
+func (a *Artifact) MirrorPath() string {
+	return ArtifactMirrorPrefix + a.SHA256
+}
+
+// ArtifactStore records which artifacts have been uploaded and verified,
+// persisted in artifacts.yaml.
+type ArtifactStore struct {
+	mu      sync.Mutex
+	dataDir string
+}
+
+// NewArtifactStore creates a new ArtifactStore.
+func NewArtifactStore(dataDir string) *ArtifactStore {
+	return &ArtifactStore{dataDir: dataDir}
+}
+
+func (s *ArtifactStore) path() string {
+	return filepath.Join(s.dataDir, "artifacts.yaml")
+}
+
+func (s *ArtifactStore) load() (map[string]Artifact, error) {
+	data, err := os.ReadFile(s.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string]Artifact), nil
+		}
+		return nil, fmt.Errorf("reading artifacts: %w", err)
+	}
+	var file struct {
+		Artifacts []Artifact `yaml:"artifacts"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing artifacts: %w", err)
+	}
+	result := make(map[string]Artifact, len(file.Artifacts))
+	for _, a := range file.Artifacts {
+		result[a.SHA256] = a
+	}
+	return result, nil
+}
+
+func (s *ArtifactStore) save(artifacts map[string]Artifact) error {
+	var file struct {
+		Artifacts []Artifact `yaml:"artifacts"`
+	}
+	for _, a := range artifacts {
+		file.Artifacts = append(file.Artifacts, a)
+	}
+	sort.Slice(file.Artifacts, func(i, j int) bool { return file.Artifacts[i].SHA256 < file.Artifacts[j].SHA256 })
+
+	data, err := yaml.Marshal(&file)
+	if err != nil {
+		return fmt.Errorf("marshaling artifacts: %w", err)
+	}
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+	return atomicWriteFile(s.path(), data, 0644)
+}
+
+// Get returns the artifact with the given hash, or nil if it was never uploaded.
+func (s *ArtifactStore) Get(sha256 string) (*Artifact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	artifacts, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	a, ok := artifacts[sha256]
+	if !ok {
+		return nil, nil
+	}
+	return &a, nil
+}
+
+// Add records a verified artifact.
+func (s *ArtifactStore) Add(sha256 string, size int64) (*Artifact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	artifacts, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	a := Artifact{SHA256: sha256, Size: size, UploadedAt: time.Now().UTC()}
+	artifacts[sha256] = a
+	if err := s.save(artifacts); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Touch records that an artifact is about to be referenced and returns it,
+// or nil if it was never uploaded.
+func (s *ArtifactStore) Touch(sha256 string) (*Artifact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	artifacts, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	a, ok := artifacts[sha256]
+	if !ok {
+		return nil, nil
+	}
+	now := time.Now().UTC()
+	if now.Sub(a.lastUsed()) < artifactTouchInterval {
+		return &a, nil
+	}
+	a.LastUsedAt = now
+	artifacts[sha256] = a
+	if err := s.save(artifacts); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// List returns every recorded artifact, sorted by hash.
+func (s *ArtifactStore) List() ([]Artifact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	artifacts, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Artifact, 0, len(artifacts))
+	for _, a := range artifacts {
+		result = append(result, a)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].SHA256 < result[j].SHA256 })
+	return result, nil
+}
+
+// Delete forgets an artifact. A missing artifact is not an error.
+func (s *ArtifactStore) Delete(sha256 string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	artifacts, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := artifacts[sha256]; !ok {
+		return nil
+	}
+	delete(artifacts, sha256)
+	return s.save(artifacts)
+}
+
+// UnreferencedArtifacts returns the artifacts no build references, skipping
+// those uploaded or used within the last day. referenced holds the hashes of every
+// artifact a remaining build points at.
+func UnreferencedArtifacts(artifacts []Artifact, referenced map[string]bool, now time.Time) []Artifact {
+	var result []Artifact
+	for _, a := range artifacts {
+		if referenced[a.SHA256] || now.Sub(a.lastUsed()) < artifactGCGrace {
+			continue
+		}
+		result = append(result, a)
+	}
+	return result
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnreferencedArtifacts(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	artifacts := []Artifact{
+		{SHA256: "aa", UploadedAt: now.AddDate(0, 0, -10)},                                  // still used
+		{SHA256: "bb", UploadedAt: now.AddDate(0, 0, -10)},                                  // orphaned
+		{SHA256: "cc", UploadedAt: now.Add(-time.Hour)},                                     // orphaned, but its build may still be on the way
+		{SHA256: "dd", UploadedAt: now.AddDate(0, 0, -10), LastUsedAt: now.Add(-time.Hour)}, // old, but reused for a build on the way
+	}
+	got := UnreferencedArtifacts(artifacts, map[string]bool{"aa": true}, now)
+	if len(got) != 1 || got[0].SHA256 != "bb" {
+		t.Errorf("UnreferencedArtifacts = %+v, want only bb", got)
+	}
+}
+
+func TestArtifactStoreDelete(t *testing.T) {
+	s := NewArtifactStore(t.TempDir())
+	for _, h := range []string{"bb", "aa"} {
+		if _, err := s.Add(h, 1); err != nil {
+			t.Fatalf("Add %s: %v", h, err)
+		}
+	}
+	if err := s.Delete("aa"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := s.Delete("missing"); err != nil {
+		t.Errorf("Delete of a missing artifact: %v", err)
+	}
+	list, err := s.List()
+	if err != nil || len(list) != 1 || list[0].SHA256 != "bb" {
+		t.Errorf("List = %+v, %v", list, err)
+	}
+	if a, _ := s.Get("aa"); a != nil {
+		t.Errorf("Get after Delete = %+v, want nil", a)
+	}
+}
+
+func TestArtifactStoreTouch(t *testing.T) {
+	s := NewArtifactStore(t.TempDir())
+	if a, err := s.Touch("aa"); err != nil || a != nil {
+		t.Fatalf("Touch of a missing artifact = %+v, %v; want nil", a, err)
+	}
+	if _, err := s.Add("aa", 1); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	// Backdate the upload so the touch is recorded.
+	artifacts, _ := s.load()
+	a := artifacts["aa"]
+	a.UploadedAt = a.UploadedAt.AddDate(0, 0, -10)
+	artifacts["aa"] = a
+	if err := s.save(artifacts); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Touch("aa"); err != nil {
+		t.Fatalf("Touch: %v", err)
+	}
+	got, _ := s.Get("aa")
+	if got == nil || time.Since(got.LastUsedAt) > time.Minute {
+		t.Fatalf("after Touch = %+v, want a fresh last_used_at", got)
+	}
+	if u := UnreferencedArtifacts([]Artifact{*got}, nil, time.Now()); len(u) != 0 {
+		t.Errorf("recently used artifact is collectable: %+v", u)
+	}
+}
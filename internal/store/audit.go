@@ -0,0 +1,224 @@
+package store
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// maxAuditLine bounds a single audit log line when reading it back.
+const maxAuditLine = 1 << 20
+
+// AuditEntry records one call to a write endpoint. Entries form a hash
+// chain: Hash covers the entry and PrevHash, so editing or dropping a line
+// breaks every hash after it.
+type AuditEntry struct {
+	Seq         int64           `json:"seq"`
+	Time        time.Time       `json:"time"`
+	Actor       string          `json:"actor"`
+	TokenID     string          `json:"token_id,omitempty"`
+	SourceIP    string          `json:"source_ip,omitempty"`
+	Action      string          `json:"action"` // route pattern, e.g. "POST /api/v1/releases"
+	Path        string          `json:"path"`
+	Project     string          `json:"project,omitempty"`
+	Environment string          `json:"environment,omitempty"`
+	Request     json.RawMessage `json:"request,omitempty"` // JSON body, if any
+	Status      int             `json:"status"`
+	Outcome     string          `json:"outcome"` // "ok" or "error"
+	Error       string          `json:"error,omitempty"`
+	PrevHash    string          `json:"prev_hash"`
+	Hash        string          `json:"hash"`
+}
+
+// computeHash returns the chain hash of e, ignoring its current Hash.
+func (e AuditEntry) computeHash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AuditFilter selects audit entries. Zero fields match everything.
+type AuditFilter struct {
+	Actor   string
+	Project string
+	Action  string
+	Outcome string
+	Since   time.Time
+	Until   time.Time
+	Limit   int // newest N matching entries; 0 = all
+}
+
+func (f AuditFilter) matches(e *AuditEntry) bool {
+	switch {
+	case f.Actor != "" && e.Actor != f.Actor,
+		f.Project != "" && e.Project != f.Project,
+		f.Action != "" && e.Action != f.Action,
+		f.Outcome != "" && e.Outcome != f.Outcome,
+		!f.Since.IsZero() && e.Time.Before(f.Since),
+		!f.Until.IsZero() && !e.Time.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// AuditLog is an append-only, hash-chained log in audit.jsonl.
+type AuditLog struct {
+	mu      sync.Mutex
+	dataDir string
+
+	loaded   bool
+	lastSeq  int64
+	lastHash string
+}
+
+// NewAuditLog creates a new AuditLog.
+func NewAuditLog(dataDir string) *AuditLog {
+	return &AuditLog{dataDir: dataDir}
+}
+
+func (l *AuditLog) path() string {
+	return filepath.Join(l.dataDir, "audit.jsonl")
+}
+
+// scan calls fn for every entry in file order.
+func (l *AuditLog) scan(fn func(e *AuditEntry) error) error {
+	f, err := os.Open(l.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), maxAuditLine)
+	line := 0
+	for sc.Scan() {
+		line++
+		var e AuditEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return fmt.Errorf("audit log line %d: %w", line, err)
+		}
+		if err := fn(&e); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// Append stamps e with the next sequence number and chain hash and writes it.
+func (l *AuditLog) Append(e *AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.loaded {
+		err := l.scan(func(last *AuditEntry) error {
+			l.lastSeq, l.lastHash = last.Seq, last.Hash
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		l.loaded = true
+	}
+
+	e.Seq = l.lastSeq + 1
+	e.Time = e.Time.UTC()
+	e.PrevHash = l.lastHash
+	hash, err := e.computeHash()
+	if err != nil {
+		return fmt.Errorf("hashing audit entry: %w", err)
+	}
+	e.Hash = hash
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshaling audit entry: %w", err)
+	}
+	if err := os.MkdirAll(l.dataDir, 0755); err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+	f, err := os.OpenFile(l.path(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+	l.lastSeq, l.lastHash = e.Seq, e.Hash
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("syncing audit log: %w", err)
+	}
+	return nil
+}
+
+// Query returns the matching entries, oldest first.
+func (l *AuditLog) Query(f AuditFilter) ([]AuditEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var result []AuditEntry
+	err := l.scan(func(e *AuditEntry) error {
+		if f.matches(e) {
+			result = append(result, *e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if f.Limit > 0 && len(result) > f.Limit {
+		result = result[len(result)-f.Limit:]
+	}
+	return result, nil
+}
+
+// Verify walks the whole log and reports the first broken link, if any.
+// It returns the number of entries checked.
+func (l *AuditLog) Verify() (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var n int64
+	prev := ""
+	err := l.scan(func(e *AuditEntry) error {
+		if err := VerifyAuditLink(e, prev, n+1); err != nil {
+			return err
+		}
+		prev = e.Hash
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// VerifyAuditLink checks that e follows the entry with hash prev and has
+// sequence number seq.
+func VerifyAuditLink(e *AuditEntry, prev string, seq int64) error {
+	if e.Seq != seq {
+		return fmt.Errorf("audit entry %d: expected sequence %d (entries missing or reordered)", e.Seq, seq)
+	}
+	if e.PrevHash != prev {
+		return fmt.Errorf("audit entry %d: previous hash does not match", e.Seq)
+	}
+	hash, err := e.computeHash()
+	if err != nil {
+		return err
+	}
+	if hash != e.Hash {
+		return fmt.Errorf("audit entry %d: hash mismatch (entry was modified)", e.Seq)
+	}
+	return nil
+}
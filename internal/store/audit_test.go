@@ -0,0 +1,75 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLogChain(t *testing.T) {
+	dir := t.TempDir()
+	l := NewAuditLog(dir)
+
+	start := time.Now()
+	for i, e := range []*AuditEntry{
+		{Time: start, Actor: "admin", Action: "POST /api/v1/releases", Project: "app", Environment: "production", Request: json.RawMessage(`{"project": "app"}`), Status: 200, Outcome: "ok"},
+		{Time: start.Add(time.Second), Actor: "token:ci", Action: "POST /api/v1/builds", Project: "app", Status: 403, Outcome: "error", Error: "denied"},
+		{Time: start.Add(2 * time.Second), Actor: "admin", Action: "POST /api/v1/builds", Project: "other", Status: 201, Outcome: "ok"},
+	} {
+		if err := l.Append(e); err != nil {
+			t.Fatalf("Append %d: %v", i, err)
+		}
+	}
+
+	// A fresh AuditLog continues the chain from disk.
+	l = NewAuditLog(dir)
+	if err := l.Append(&AuditEntry{Time: start.Add(3 * time.Second), Actor: "admin", Action: "DELETE /api/v1/tokens/{id}", Status: 200, Outcome: "ok"}); err != nil {
+		t.Fatalf("Append after reopen: %v", err)
+	}
+	if n, err := l.Verify(); err != nil || n != 4 {
+		t.Fatalf("Verify = %d, %v; want 4 entries, no error", n, err)
+	}
+
+	got, err := l.Query(AuditFilter{Project: "app"})
+	if err != nil || len(got) != 2 {
+		t.Fatalf("Query project = %d entries, %v", len(got), err)
+	}
+	got, _ = l.Query(AuditFilter{Outcome: "error"})
+	if len(got) != 1 || got[0].Actor != "token:ci" {
+		t.Errorf("Query outcome = %+v", got)
+	}
+	got, _ = l.Query(AuditFilter{Since: start.Add(time.Second), Until: start.Add(3 * time.Second)})
+	if len(got) != 2 {
+		t.Errorf("Query time range = %d entries, want 2", len(got))
+	}
+	got, _ = l.Query(AuditFilter{Limit: 1})
+	if len(got) != 1 || got[0].Seq != 4 {
+		t.Errorf("Query limit should return the newest entry, got %+v", got)
+	}
+
+	// Editing an entry in place breaks the chain.
+	data, err := os.ReadFile(l.path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := bytes.Replace(data, []byte(`"status":403`), []byte(`"status":200`), 1)
+	if err := os.WriteFile(l.path(), tampered, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Verify(); err == nil || !strings.Contains(err.Error(), "entry 2") {
+		t.Errorf("Verify after edit = %v, want error at entry 2", err)
+	}
+
+	// So does dropping a line.
+	lines := strings.SplitAfter(string(data), "\n")
+	dropped := strings.Join(append(lines[:1:1], lines[2:]...), "")
+	if err := os.WriteFile(l.path(), []byte(dropped), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Verify(); err == nil {
+		t.Error("Verify accepted a log with a missing entry")
+	}
+}
@@ -1,9 +1,12 @@
 package store
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
@@ -18,16 +21,87 @@ type BuildFile struct {
 	MirrorPath string `yaml:"mirror_path,omitempty" json:"mirror_path,omitempty"`
 }
 
-// Build represents a numbered, immutable build artifact.
+// Build lifecycle states. Builds stored before states existed read as
+// complete.
+const (
+	BuildPending  = "pending"  // registered, uploads still in progress
+	BuildComplete = "complete" // ready to be released
+	BuildFailed   = "failed"   // never finished; cannot be released
+	BuildYanked   = "yanked"   // withdrawn; refused for releases and hidden from listings
+)
+
+// buildTransitions lists the states each state may move to. Yanking can be
+// undone; failing cannot.
+var buildTransitions = map[string][]string{
+	BuildPending:  {BuildComplete, BuildFailed},
+	BuildComplete: {BuildYanked},
+	BuildYanked:   {BuildComplete},
+}
+
+// ErrInvalidTransition is returned by SetState for a state change that is not
+// allowed from the build's current state.
+var ErrInvalidTransition = errors.New("invalid build state transition")
+
+// ValidBuildState reports whether state is a known build state.
+func ValidBuildState(state string) bool {
+	switch state {
+	case BuildPending, BuildComplete, BuildFailed, BuildYanked:
+		return true
+	}
+	return false
+}
+
+func stateOrComplete(state string) string {
+	if state == "" {
+		return BuildComplete
+	}
+	return state
+}
+
+// Build represents a numbered build artifact. Only its state changes after
+// creation.
 type Build struct {
-	Project     string            `yaml:"project" json:"project"`
-	BuildNumber int               `yaml:"build_number" json:"build_number"`
-	UploadedBy  string            `yaml:"uploaded_by" json:"uploaded_by"`
-	UploadedAt  time.Time         `yaml:"uploaded_at" json:"uploaded_at"`
-	Source      string            `yaml:"source,omitempty" json:"source,omitempty"`
-	SourceRef   string            `yaml:"source_ref,omitempty" json:"source_ref,omitempty"`
-	SourceMeta  map[string]string `yaml:"source_meta,omitempty" json:"source_meta,omitempty"`
-	Files       []BuildFile       `yaml:"files" json:"files"`
+	Project        string            `yaml:"project" json:"project"`
+	BuildNumber    int               `yaml:"build_number" json:"build_number"`
+	UploadedBy     string            `yaml:"uploaded_by" json:"uploaded_by"`
+	UploadedAt     time.Time         `yaml:"uploaded_at" json:"uploaded_at"`
+	Source         string            `yaml:"source,omitempty" json:"source,omitempty"`
+	SourceRef      string            `yaml:"source_ref,omitempty" json:"source_ref,omitempty"`
+	SourceMeta     map[string]string `yaml:"source_meta,omitempty" json:"source_meta,omitempty"`
+	Files          []BuildFile       `yaml:"files" json:"files"`
+	State          string            `yaml:"state,omitempty" json:"state"`
+	StateReason    string            `yaml:"state_reason,omitempty" json:"state_reason,omitempty"`
+	StateChangedBy string            `yaml:"state_changed_by,omitempty" json:"state_changed_by,omitempty"`
+	StateChangedAt *time.Time        `yaml:"state_changed_at,omitempty" json:"state_changed_at,omitempty"`
+}
+
+// StateChange is a requested build state transition.
+type StateChange struct {
+	State  string
+	Reason string
+	By     string
+}
+
+// CheckTransition returns an error wrapping ErrInvalidTransition unless the
+// build may move to state.
+func (b *Build) CheckTransition(state string) error {
+	from := stateOrComplete(b.State)
+	if slices.Contains(buildTransitions[from], state) {
+		return nil
+	}
+	return fmt.Errorf("%w: build %s/%d is %s and cannot become %s", ErrInvalidTransition, b.Project, b.BuildNumber, from, state)
+}
+
+// applyStateChange moves b to c.State if the transition is allowed.
+func applyStateChange(b *Build, c StateChange, now time.Time) error {
+	if err := b.CheckTransition(c.State); err != nil {
+		return err
+	}
+	b.State = c.State
+	b.StateReason = c.Reason
+	b.StateChangedBy = c.By
+	b.StateChangedAt = &now
+	return nil
 }
 
 // BuildIndex is the YAML-persisted index of all builds.
@@ -37,38 +111,43 @@ type BuildIndex struct {
 
 // BuildIndexEntry is a summary entry in the builds index.
 type BuildIndexEntry struct {
-	Project     string    `yaml:"project"`
-	BuildNumber int       `yaml:"build_number"`
-	UploadedBy  string    `yaml:"uploaded_by"`
-	UploadedAt  time.Time `yaml:"uploaded_at"`
-	FileCount   int       `yaml:"file_count"`
-	TotalBytes  int64     `yaml:"total_bytes"`
+	Project     string    `yaml:"project" json:"project"`
+	BuildNumber int       `yaml:"build_number" json:"build_number"`
+	UploadedBy  string    `yaml:"uploaded_by" json:"uploaded_by"`
+	UploadedAt  time.Time `yaml:"uploaded_at" json:"uploaded_at"`
+	FileCount   int       `yaml:"file_count" json:"file_count"`
+	TotalBytes  int64     `yaml:"total_bytes" json:"total_bytes"`
+	State       string    `yaml:"state,omitempty" json:"state"`
+	// Source and SourceRef are empty on entries indexed before they were
+	// added; the Build itself has them.
+	Source    string `yaml:"source,omitempty" json:"source,omitempty"`
+	SourceRef string `yaml:"source_ref,omitempty" json:"source_ref,omitempty"`
 }
 
-// BuildStore manages build metadata with YAML persistence.
-type BuildStore struct {
+// YAMLBuildStore manages build metadata with YAML persistence.
+type YAMLBuildStore struct {
 	mu      sync.Mutex
 	dataDir string // root data directory (e.g. /var/lib/hydrarelease)
 }
 
-// NewBuildStore creates a new BuildStore rooted at the given data directory.
-func NewBuildStore(dataDir string) *BuildStore {
-	return &BuildStore{dataDir: dataDir}
+// NewYAMLBuildStore creates a new YAMLBuildStore rooted at the given data directory.
+func NewYAMLBuildStore(dataDir string) *YAMLBuildStore {
+	return &YAMLBuildStore{dataDir: dataDir}
 }
 
-func (s *BuildStore) indexPath() string {
+func (s *YAMLBuildStore) indexPath() string {
 	return filepath.Join(s.dataDir, "builds.yaml")
 }
 
-func (s *BuildStore) buildDir(project string, number int) string {
+func (s *YAMLBuildStore) buildDir(project string, number int) string {
 	return filepath.Join(s.dataDir, "builds", project, fmt.Sprintf("%d", number))
 }
 
-func (s *BuildStore) buildPath(project string, number int) string {
+func (s *YAMLBuildStore) buildPath(project string, number int) string {
 	return filepath.Join(s.buildDir(project, number), "build.yaml")
 }
 
-func (s *BuildStore) loadIndex() (*BuildIndex, error) {
+func (s *YAMLBuildStore) loadIndex() (*BuildIndex, error) {
 	data, err := os.ReadFile(s.indexPath())
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -83,7 +162,7 @@ func (s *BuildStore) loadIndex() (*BuildIndex, error) {
 	return &idx, nil
 }
 
-func (s *BuildStore) saveIndex(idx *BuildIndex) error {
+func (s *YAMLBuildStore) saveIndex(idx *BuildIndex) error {
 	data, err := yaml.Marshal(idx)
 	if err != nil {
 		return fmt.Errorf("marshaling build index: %w", err)
@@ -95,7 +174,7 @@ func (s *BuildStore) saveIndex(idx *BuildIndex) error {
 }
 
 // nextBuildNumber returns the next build number for a project.
-func (s *BuildStore) nextBuildNumber(idx *BuildIndex, project string) int {
+func (s *YAMLBuildStore) nextBuildNumber(idx *BuildIndex, project string) int {
 	max := 0
 	for _, e := range idx.Builds {
 		if e.Project == project && e.BuildNumber > max {
@@ -105,6 +184,40 @@ func (s *BuildStore) nextBuildNumber(idx *BuildIndex, project string) int {
 	return max + 1
 }
 
+// newBuild assembles a Build from creation parameters.
+func newBuild(p CreateParams, number int, now time.Time) *Build {
+	return &Build{
+		Project:     p.Project,
+		BuildNumber: number,
+		UploadedBy:  p.UploadedBy,
+		UploadedAt:  now,
+		Source:      p.Source,
+		SourceRef:   p.SourceRef,
+		SourceMeta:  p.SourceMeta,
+		Files:       p.Files,
+		State:       stateOrComplete(p.State),
+	}
+}
+
+// buildIndexEntry summarizes a build for the index.
+func buildIndexEntry(b *Build) BuildIndexEntry {
+	var totalBytes int64
+	for _, f := range b.Files {
+		totalBytes += f.Size
+	}
+	return BuildIndexEntry{
+		Project:     b.Project,
+		BuildNumber: b.BuildNumber,
+		UploadedBy:  b.UploadedBy,
+		UploadedAt:  b.UploadedAt,
+		FileCount:   len(b.Files),
+		TotalBytes:  totalBytes,
+		State:       stateOrComplete(b.State),
+		Source:      b.Source,
+		SourceRef:   b.SourceRef,
+	}
+}
+
 // CreateParams holds the parameters for creating a new build.
 type CreateParams struct {
 	Project    string
@@ -113,10 +226,11 @@ type CreateParams struct {
 	SourceRef  string
 	SourceMeta map[string]string
 	Files      []BuildFile
+	State      string // BuildPending or BuildComplete (the default)
 }
 
 // Create registers a new build, assigns a build number, and persists it.
-func (s *BuildStore) Create(p CreateParams) (*Build, error) {
+func (s *YAMLBuildStore) Create(p CreateParams) (*Build, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -126,47 +240,15 @@ func (s *BuildStore) Create(p CreateParams) (*Build, error) {
 	}
 
 	number := s.nextBuildNumber(idx, p.Project)
-	now := time.Now().UTC()
-
-	build := &Build{
-		Project:     p.Project,
-		BuildNumber: number,
-		UploadedBy:  p.UploadedBy,
-		UploadedAt:  now,
-		Source:      p.Source,
-		SourceRef:   p.SourceRef,
-		SourceMeta:  p.SourceMeta,
-		Files:       p.Files,
-	}
+	build := newBuild(p, number, time.Now().UTC())
 
 	// Persist per-build metadata.
-	dir := s.buildDir(p.Project, number)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return nil, fmt.Errorf("creating build directory: %w", err)
-	}
-
-	data, err := yaml.Marshal(build)
-	if err != nil {
-		return nil, fmt.Errorf("marshaling build: %w", err)
-	}
-	if err := atomicWriteFile(s.buildPath(p.Project, number), data, 0644); err != nil {
-		return nil, fmt.Errorf("writing build metadata: %w", err)
+	if err := s.saveBuild(build); err != nil {
+		return nil, err
 	}
 
 	// Update index.
-	var totalBytes int64
-	for _, f := range p.Files {
-		totalBytes += f.Size
-	}
-
-	idx.Builds = append(idx.Builds, BuildIndexEntry{
-		Project:     p.Project,
-		BuildNumber: number,
-		UploadedBy:  p.UploadedBy,
-		UploadedAt:  now,
-		FileCount:   len(p.Files),
-		TotalBytes:  totalBytes,
-	})
+	idx.Builds = append(idx.Builds, buildIndexEntry(build))
 
 	if err := s.saveIndex(idx); err != nil {
 		return nil, err
@@ -175,11 +257,30 @@ func (s *BuildStore) Create(p CreateParams) (*Build, error) {
 	return build, nil
 }
 
+func (s *YAMLBuildStore) saveBuild(build *Build) error {
+	dir := s.buildDir(build.Project, build.BuildNumber)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating build directory: %w", err)
+	}
+
+	data, err := yaml.Marshal(build)
+	if err != nil {
+		return fmt.Errorf("marshaling build: %w", err)
+	}
+	if err := atomicWriteFile(s.buildPath(build.Project, build.BuildNumber), data, 0644); err != nil {
+		return fmt.Errorf("writing build metadata: %w", err)
+	}
+	return nil
+}
+
 // Get retrieves a specific build by project and number.
-func (s *BuildStore) Get(project string, number int) (*Build, error) {
+func (s *YAMLBuildStore) Get(project string, number int) (*Build, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.loadBuild(project, number)
+}
 
+func (s *YAMLBuildStore) loadBuild(project string, number int) (*Build, error) {
 	data, err := os.ReadFile(s.buildPath(project, number))
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -192,11 +293,43 @@ func (s *BuildStore) Get(project string, number int) (*Build, error) {
 	if err := yaml.Unmarshal(data, &build); err != nil {
 		return nil, fmt.Errorf("parsing build: %w", err)
 	}
+	build.State = stateOrComplete(build.State)
 	return &build, nil
 }
 
+// SetState moves a build to a new lifecycle state.
+func (s *YAMLBuildStore) SetState(project string, number int, c StateChange) (*Build, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	build, err := s.loadBuild(project, number)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyStateChange(build, c, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+
+	idx, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.saveBuild(build); err != nil {
+		return nil, err
+	}
+	for i, e := range idx.Builds {
+		if e.Project == project && e.BuildNumber == number {
+			idx.Builds[i].State = build.State
+		}
+	}
+	if err := s.saveIndex(idx); err != nil {
+		return nil, err
+	}
+	return build, nil
+}
+
 // List returns all builds for a project (from the index).
-func (s *BuildStore) List(project string) ([]BuildIndexEntry, error) {
+func (s *YAMLBuildStore) List(project string) ([]BuildIndexEntry, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -208,14 +341,70 @@ func (s *BuildStore) List(project string) ([]BuildIndexEntry, error) {
 	var result []BuildIndexEntry
 	for _, e := range idx.Builds {
 		if e.Project == project {
+			e.State = stateOrComplete(e.State)
 			result = append(result, e)
 		}
 	}
 	return result, nil
 }
 
+// Projects returns the distinct projects that have builds, sorted by name.
+func (s *YAMLBuildStore) Projects() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{})
+	var result []string
+	for _, e := range idx.Builds {
+		if _, ok := seen[e.Project]; !ok {
+			seen[e.Project] = struct{}{}
+			result = append(result, e.Project)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// Delete removes a build from the index and deletes its metadata directory.
+func (s *YAMLBuildStore) Delete(project string, number int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.loadIndex()
+	if err != nil {
+		return err
+	}
+
+	kept := idx.Builds[:0]
+	found := false
+	for _, e := range idx.Builds {
+		if e.Project == project && e.BuildNumber == number {
+			found = true
+			continue
+		}
+		kept = append(kept, e)
+	}
+	if !found {
+		return fmt.Errorf("build %s/%d not found", project, number)
+	}
+	idx.Builds = kept
+
+	if err := s.saveIndex(idx); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(s.buildDir(project, number)); err != nil {
+		return fmt.Errorf("removing build directory: %w", err)
+	}
+	return nil
+}
+
 // Stats returns total build count and distinct project count.
-func (s *BuildStore) Stats() (buildCount int, projects int, err error) {
+func (s *YAMLBuildStore) Stats() (buildCount int, projects int, err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildStateTransitions(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+	defer db.Close()
+
+	for name, builds := range map[string]BuildStore{
+		"yaml": NewYAMLBuildStore(t.TempDir()),
+		"db":   NewDBBuildStore(db),
+	} {
+		t.Run(name, func(t *testing.T) {
+			legacy, err := builds.Create(CreateParams{Project: "app", Files: []BuildFile{{Path: "a"}}})
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if legacy.State != BuildComplete {
+				t.Errorf("default state = %q, want complete", legacy.State)
+			}
+
+			b, err := builds.Create(CreateParams{Project: "app", State: BuildPending, Files: []BuildFile{{Path: "a"}}})
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if _, err := builds.SetState("app", b.BuildNumber, StateChange{State: BuildYanked}); !errors.Is(err, ErrInvalidTransition) {
+				t.Errorf("pending → yanked: err = %v, want ErrInvalidTransition", err)
+			}
+
+			for _, state := range []string{BuildComplete, BuildYanked, BuildComplete, BuildYanked} {
+				got, err := builds.SetState("app", b.BuildNumber, StateChange{State: state, Reason: "test", By: "ops"})
+				if err != nil {
+					t.Fatalf("→ %s: %v", state, err)
+				}
+				if got.State != state || got.StateChangedBy != "ops" || got.StateChangedAt == nil {
+					t.Errorf("→ %s: build = %+v", state, got)
+				}
+			}
+
+			stored, err := builds.Get("app", b.BuildNumber)
+			if err != nil || stored.State != BuildYanked || stored.StateReason != "test" {
+				t.Errorf("Get = %+v, %v", stored, err)
+			}
+			list, _ := builds.List("app")
+			if len(list) != 2 || list[0].State != BuildComplete || list[1].State != BuildYanked {
+				t.Errorf("List states = %+v", list)
+			}
+
+			failed, _ := builds.Create(CreateParams{Project: "app", State: BuildPending})
+			if _, err := builds.SetState("app", failed.BuildNumber, StateChange{State: BuildFailed}); err != nil {
+				t.Fatalf("pending → failed: %v", err)
+			}
+			if _, err := builds.SetState("app", failed.BuildNumber, StateChange{State: BuildComplete}); !errors.Is(err, ErrInvalidTransition) {
+				t.Errorf("failed → complete: err = %v, want ErrInvalidTransition", err)
+			}
+			if _, err := builds.SetState("app", 99, StateChange{State: BuildYanked}); err == nil {
+				t.Error("SetState of a missing build succeeded")
+			}
+		})
+	}
+}
+
+func TestBuildIndexSource(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+	defer db.Close()
+
+	for name, builds := range map[string]BuildStore{
+		"yaml": NewYAMLBuildStore(t.TempDir()),
+		"db":   NewDBBuildStore(db),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := builds.Create(CreateParams{Project: "app", Source: "git", SourceRef: "4f2c9e1"}); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			list, err := builds.List("app")
+			if err != nil || len(list) != 1 || list[0].Source != "git" || list[0].SourceRef != "4f2c9e1" {
+				t.Errorf("List = %+v, %v", list, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// DB is the embedded, transactional key/value database behind the db
+// backend. It is a thin layer over bbolt that stores JSON values in named
+// buckets; buckets are created on first write.
+type DB struct {
+	bolt *bolt.DB
+}
+
+// OpenDB opens (or creates) the database file at path. It fails after a
+// few seconds if another process holds the file open.
+func OpenDB(path string) (*DB, error) {
+	b, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	return &DB{bolt: b}, nil
+}
+
+// Close closes the database file.
+func (db *DB) Close() error {
+	return db.bolt.Close()
+}
+
+// View runs fn in a read-only transaction.
+func (db *DB) View(fn func(tx *Tx) error) error {
+	return db.bolt.View(func(btx *bolt.Tx) error {
+		return fn(&Tx{tx: btx})
+	})
+}
+
+// Update runs fn in a read-write transaction. If fn returns nil the
+// transaction's writes are committed atomically and synced to disk;
+// otherwise they are discarded.
+func (db *DB) Update(fn func(tx *Tx) error) error {
+	return db.bolt.Update(func(btx *bolt.Tx) error {
+		return fn(&Tx{tx: btx})
+	})
+}
+
+// Tx is a database transaction. Reads inside a write transaction observe the
+// transaction's own uncommitted writes. Values returned by Get and passed to
+// ForEach are only valid until the transaction ends.
+type Tx struct {
+	tx *bolt.Tx
+}
+
+// Get returns the value stored under key, or nil if absent.
+func (tx *Tx) Get(bucket, key string) []byte {
+	b := tx.tx.Bucket([]byte(bucket))
+	if b == nil {
+		return nil
+	}
+	return b.Get([]byte(key))
+}
+
+// GetJSON decodes the value stored under key into v and reports whether it existed.
+func (tx *Tx) GetJSON(bucket, key string, v any) (bool, error) {
+	data := tx.Get(bucket, key)
+	if data == nil {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return true, fmt.Errorf("decoding %s/%s: %w", bucket, key, err)
+	}
+	return true, nil
+}
+
+// Put stores value under key.
+func (tx *Tx) Put(bucket, key string, value []byte) error {
+	if !tx.tx.Writable() {
+		return errors.New("put in read-only transaction")
+	}
+	if !json.Valid(value) {
+		return fmt.Errorf("value for %s/%s is not valid JSON", bucket, key)
+	}
+	b, err := tx.tx.CreateBucketIfNotExists([]byte(bucket))
+	if err != nil {
+		return fmt.Errorf("creating bucket %s: %w", bucket, err)
+	}
+	return b.Put([]byte(key), value)
+}
+
+// PutJSON encodes v as JSON and stores it under key.
+func (tx *Tx) PutJSON(bucket, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding %s/%s: %w", bucket, key, err)
+	}
+	return tx.Put(bucket, key, data)
+}
+
+// Delete removes key from bucket.
+func (tx *Tx) Delete(bucket, key string) error {
+	if !tx.tx.Writable() {
+		return errors.New("delete in read-only transaction")
+	}
+	b := tx.tx.Bucket([]byte(bucket))
+	if b == nil {
+		return nil
+	}
+	return b.Delete([]byte(key))
+}
+
+// ForEach calls fn for every key in bucket that starts with prefix, in
+// ascending key order. fn must not modify bucket.
+func (tx *Tx) ForEach(bucket, prefix string, fn func(key string, value []byte) error) error {
+	b := tx.tx.Bucket([]byte(bucket))
+	if b == nil {
+		return nil
+	}
+	p := []byte(prefix)
+	c := b.Cursor()
+	for k, v := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
+		if err := fn(string(k), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Count returns the number of keys in bucket.
+func (tx *Tx) Count(bucket string) int {
+	b := tx.tx.Bucket([]byte(bucket))
+	if b == nil {
+		return 0
+	}
+	n := 0
+	c := b.Cursor()
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,183 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Buckets used by DBBuildStore.
+const (
+	bucketBuilds     = "builds"      // "<project>/<number>" → Build
+	bucketBuildIndex = "build_index" // "<project>/<number>" → BuildIndexEntry
+	bucketBuildSeq   = "build_seq"   // "<project>" → last assigned build number
+)
+
+// DBBuildStore manages build metadata in the embedded database.
+type DBBuildStore struct {
+	db *DB
+}
+
+// NewDBBuildStore creates a BuildStore backed by db.
+func NewDBBuildStore(db *DB) *DBBuildStore {
+	return &DBBuildStore{db: db}
+}
+
+// buildKey returns the database key for a build. Numbers are zero-padded so
+// keys sort in build order.
+func buildKey(project string, number int) string {
+	return fmt.Sprintf("%s/%010d", project, number)
+}
+
+// Create registers a new build, assigns a build number, and persists it.
+func (s *DBBuildStore) Create(p CreateParams) (*Build, error) {
+	var build *Build
+	err := s.db.Update(func(tx *Tx) error {
+		var last int
+		if _, err := tx.GetJSON(bucketBuildSeq, p.Project, &last); err != nil {
+			return err
+		}
+		build = newBuild(p, last+1, time.Now().UTC())
+		return putBuild(tx, build)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return build, nil
+}
+
+// putBuild writes a build, its index entry, and advances the project's build
+// sequence if needed.
+func putBuild(tx *Tx, build *Build) error {
+	key := buildKey(build.Project, build.BuildNumber)
+	if err := tx.PutJSON(bucketBuilds, key, build); err != nil {
+		return err
+	}
+	if err := tx.PutJSON(bucketBuildIndex, key, buildIndexEntry(build)); err != nil {
+		return err
+	}
+	var last int
+	if _, err := tx.GetJSON(bucketBuildSeq, build.Project, &last); err != nil {
+		return err
+	}
+	if build.BuildNumber > last {
+		return tx.PutJSON(bucketBuildSeq, build.Project, build.BuildNumber)
+	}
+	return nil
+}
+
+// Get retrieves a specific build by project and number.
+func (s *DBBuildStore) Get(project string, number int) (*Build, error) {
+	var build Build
+	var found bool
+	err := s.db.View(func(tx *Tx) error {
+		var err error
+		found, err = tx.GetJSON(bucketBuilds, buildKey(project, number), &build)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading build: %w", err)
+	}
+	if !found {
+		return nil, fmt.Errorf("build %s/%d not found", project, number)
+	}
+	build.State = stateOrComplete(build.State)
+	return &build, nil
+}
+
+// SetState moves a build to a new lifecycle state.
+func (s *DBBuildStore) SetState(project string, number int, c StateChange) (*Build, error) {
+	var build Build
+	err := s.db.Update(func(tx *Tx) error {
+		found, err := tx.GetJSON(bucketBuilds, buildKey(project, number), &build)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("build %s/%d not found", project, number)
+		}
+		if err := applyStateChange(&build, c, time.Now().UTC()); err != nil {
+			return err
+		}
+		return putBuild(tx, &build)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &build, nil
+}
+
+// List returns all builds for a project in build order.
+func (s *DBBuildStore) List(project string) ([]BuildIndexEntry, error) {
+	var result []BuildIndexEntry
+	err := s.db.View(func(tx *Tx) error {
+		return tx.ForEach(bucketBuildIndex, project+"/", func(_ string, v []byte) error {
+			var e BuildIndexEntry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return fmt.Errorf("parsing build index entry: %w", err)
+			}
+			e.State = stateOrComplete(e.State)
+			result = append(result, e)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Projects returns the distinct projects that have builds, sorted by name.
+func (s *DBBuildStore) Projects() ([]string, error) {
+	var result []string
+	err := s.db.View(func(tx *Tx) error {
+		var err error
+		result, err = indexedProjects(tx)
+		return err
+	})
+	return result, err
+}
+
+// indexedProjects returns the distinct projects in the build index, sorted
+// by name. The build sequence can't be used: it outlives deleted builds.
+func indexedProjects(tx *Tx) ([]string, error) {
+	var result []string
+	err := tx.ForEach(bucketBuildIndex, "", func(k string, _ []byte) error {
+		project := k[:strings.LastIndex(k, "/")]
+		if len(result) == 0 || result[len(result)-1] != project {
+			result = append(result, project)
+		}
+		return nil
+	})
+	sort.Strings(result)
+	return slices.Compact(result), err
+}
+
+// Delete removes a build and its index entry. The project's build sequence is
+// left untouched so build numbers are never reused.
+func (s *DBBuildStore) Delete(project string, number int) error {
+	return s.db.Update(func(tx *Tx) error {
+		key := buildKey(project, number)
+		if tx.Get(bucketBuildIndex, key) == nil {
+			return fmt.Errorf("build %s/%d not found", project, number)
+		}
+		if err := tx.Delete(bucketBuilds, key); err != nil {
+			return err
+		}
+		return tx.Delete(bucketBuildIndex, key)
+	})
+}
+
+// Stats returns total build count and distinct project count.
+func (s *DBBuildStore) Stats() (buildCount int, projects int, err error) {
+	err = s.db.View(func(tx *Tx) error {
+		buildCount = tx.Count(bucketBuildIndex)
+		names, err := indexedProjects(tx)
+		projects = len(names)
+		return err
+	})
+	return buildCount, projects, err
+}
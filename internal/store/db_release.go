@@ -0,0 +1,186 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Buckets used by DBReleaseStore.
+const (
+	bucketReleases       = "releases"        // "<project>/<env>" → current Release
+	bucketReleaseHistory = "release_history" // "<project>/<seq>" → ReleaseIndexEntry
+	bucketMeta           = "meta"
+
+	metaReleaseSeq = "release_seq" // last assigned release history sequence
+)
+
+// DBReleaseStore manages release metadata in the embedded database.
+type DBReleaseStore struct {
+	db *DB
+}
+
+// NewDBReleaseStore creates a ReleaseStore backed by db.
+func NewDBReleaseStore(db *DB) *DBReleaseStore {
+	return &DBReleaseStore{db: db}
+}
+
+func releaseKey(project, env string) string {
+	return project + "/" + env
+}
+
+// appendHistory records a release in the history bucket under the next
+// global sequence number, preserving promotion order.
+func appendHistory(tx *Tx, e ReleaseIndexEntry) error {
+	var seq int
+	if _, err := tx.GetJSON(bucketMeta, metaReleaseSeq, &seq); err != nil {
+		return err
+	}
+	seq++
+	if err := tx.PutJSON(bucketMeta, metaReleaseSeq, seq); err != nil {
+		return err
+	}
+	return tx.PutJSON(bucketReleaseHistory, fmt.Sprintf("%s/%012d", e.Project, seq), e)
+}
+
+func loadHistory(tx *Tx, project string) ([]ReleaseIndexEntry, error) {
+	var result []ReleaseIndexEntry
+	err := tx.ForEach(bucketReleaseHistory, project+"/", func(_ string, v []byte) error {
+		var e ReleaseIndexEntry
+		if err := json.Unmarshal(v, &e); err != nil {
+			return fmt.Errorf("parsing release history entry: %w", err)
+		}
+		result = append(result, e)
+		return nil
+	})
+	return result, err
+}
+
+func loadCurrent(tx *Tx, project, env string) (*Release, error) {
+	var rel Release
+	found, err := tx.GetJSON(bucketReleases, releaseKey(project, env), &rel)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &rel, nil
+}
+
+// Promote promotes a build to an environment.
+func (s *DBReleaseStore) Promote(req PromoteRequest) (*Release, error) {
+	var rel *Release
+	err := s.db.Update(func(tx *Tx) error {
+		current, err := loadCurrent(tx, req.Project, req.Environment)
+		if err != nil {
+			return err
+		}
+		rel = newRelease(req, current, time.Now().UTC())
+		if err := tx.PutJSON(bucketReleases, releaseKey(rel.Project, rel.Environment), rel); err != nil {
+			return err
+		}
+		return appendHistory(tx, releaseIndexEntry(rel))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rel, nil
+}
+
+// Rollback rolls an environment back to an earlier build.
+func (s *DBReleaseStore) Rollback(req RollbackRequest) (*Release, error) {
+	var rel *Release
+	err := s.db.Update(func(tx *Tx) error {
+		current, err := loadCurrent(tx, req.Project, req.Environment)
+		if err != nil {
+			return err
+		}
+		history, err := loadHistory(tx, req.Project)
+		if err != nil {
+			return err
+		}
+		rel, err = rollbackRelease(req, current, history, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if err := tx.PutJSON(bucketReleases, releaseKey(rel.Project, rel.Environment), rel); err != nil {
+			return err
+		}
+		return appendHistory(tx, releaseIndexEntry(rel))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rel, nil
+}
+
+// SetRollout changes the staged rollout of the current release.
+func (s *DBReleaseStore) SetRollout(req RolloutRequest) (*Release, error) {
+	var rel *Release
+	err := s.db.Update(func(tx *Tx) error {
+		var err error
+		rel, err = loadCurrent(tx, req.Project, req.Environment)
+		if err != nil {
+			return err
+		}
+		if err := applyRollout(req, rel, time.Now().UTC()); err != nil {
+			return err
+		}
+		return tx.PutJSON(bucketReleases, releaseKey(rel.Project, rel.Environment), rel)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rel, nil
+}
+
+// Get returns the current release for a project/environment.
+func (s *DBReleaseStore) Get(project, env string) (*Release, error) {
+	var rel *Release
+	err := s.db.View(func(tx *Tx) error {
+		var err error
+		rel, err = loadCurrent(tx, project, env)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rel == nil {
+		return nil, fmt.Errorf("no release found for %s/%s", project, env)
+	}
+	return rel, nil
+}
+
+// List returns all release history for a project in promotion order.
+func (s *DBReleaseStore) List(project string) ([]ReleaseIndexEntry, error) {
+	var result []ReleaseIndexEntry
+	err := s.db.View(func(tx *Tx) error {
+		var err error
+		result, err = loadHistory(tx, project)
+		return err
+	})
+	return result, err
+}
+
+// ListCurrentReleases returns the current release for every project/environment.
+func (s *DBReleaseStore) ListCurrentReleases() ([]Release, error) {
+	var result []Release
+	err := s.db.View(func(tx *Tx) error {
+		return tx.ForEach(bucketReleases, "", func(_ string, v []byte) error {
+			var rel Release
+			if err := json.Unmarshal(v, &rel); err != nil {
+				return fmt.Errorf("parsing release: %w", err)
+			}
+			result = append(result, rel)
+			return nil
+		})
+	})
+	return result, err
+}
+
+// Stats returns the total number of release promotions.
+func (s *DBReleaseStore) Stats() (releaseCount int, err error) {
+	err = s.db.View(func(tx *Tx) error {
+		releaseCount = tx.Count(bucketReleaseHistory)
+		return nil
+	})
+	return releaseCount, err
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func openTestDB(t *testing.T, path string) *DB {
+	t.Helper()
+	db, err := OpenDB(path)
+	if err != nil {
+		t.Fatalf("OpenDB: %v", err)
+	}
+	return db
+}
+
+func TestDBPersistsCommittedTransactions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db := openTestDB(t, path)
+
+	err := db.Update(func(tx *Tx) error {
+		if err := tx.PutJSON("b", "a/1", 1); err != nil {
+			return err
+		}
+		if err := tx.PutJSON("b", "a/2", 2); err != nil {
+			return err
+		}
+		return tx.PutJSON("b", "b/1", 3)
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	// A failed transaction must leave no trace.
+	wantErr := errors.New("boom")
+	err = db.Update(func(tx *Tx) error {
+		tx.PutJSON("b", "a/3", 4)
+		return wantErr
+	})
+	if err != wantErr {
+		t.Fatalf("Update returned %v, want %v", err, wantErr)
+	}
+	db.Close()
+
+	db = openTestDB(t, path)
+	defer db.Close()
+
+	var keys []string
+	db.View(func(tx *Tx) error {
+		return tx.ForEach("b", "a/", func(k string, _ []byte) error {
+			keys = append(keys, k)
+			return nil
+		})
+	})
+	if len(keys) != 2 || keys[0] != "a/1" || keys[1] != "a/2" {
+		t.Errorf("ForEach(a/) = %v, want [a/1 a/2]", keys)
+	}
+}
+
+func TestDBStores(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+	defer db.Close()
+	builds := NewDBBuildStore(db)
+	releases := NewDBReleaseStore(db)
+
+	for i := 0; i < 3; i++ {
+		b, err := builds.Create(CreateParams{Project: "app", Files: []BuildFile{{Path: "a", Size: 10}}})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if b.BuildNumber != i+1 {
+			t.Errorf("build number = %d, want %d", b.BuildNumber, i+1)
+		}
+	}
+	list, _ := builds.List("app")
+	if len(list) != 3 || list[2].TotalBytes != 10 {
+		t.Errorf("List = %+v", list)
+	}
+	if n, p, _ := builds.Stats(); n != 3 || p != 1 {
+		t.Errorf("Stats = %d builds, %d projects; want 3, 1", n, p)
+	}
+
+	releases.Promote(PromoteRequest{Project: "app", Environment: "production", BuildNumber: 1, Version: "1.0.0"})
+	releases.Promote(PromoteRequest{Project: "app", Environment: "production", BuildNumber: 2, Version: "1.1.0"})
+	rel, err := releases.Rollback(RollbackRequest{Project: "app", Environment: "production", RolledBackBy: "ops"})
+	if err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if rel.BuildNumber != 1 || rel.Version != "1.0.0" {
+		t.Errorf("rolled back to %d/%s, want 1/1.0.0", rel.BuildNumber, rel.Version)
+	}
+	if n, _ := releases.Stats(); n != 3 {
+		t.Errorf("release count = %d, want 3", n)
+	}
+}
+
+func TestOpenDBCreatesDataDir(t *testing.T) {
+	stores, err := Open(BackendDB, filepath.Join(t.TempDir(), "new", "data"))
+	if err != nil {
+		t.Fatalf("Open on a missing data directory: %v", err)
+	}
+	defer stores.Close()
+	if _, err := stores.Builds.Create(CreateParams{Project: "app"}); err != nil {
+		t.Errorf("Create: %v", err)
+	}
+}
+
+func TestDBProjectsIgnoreDeletedBuilds(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+	defer db.Close()
+	builds := NewDBBuildStore(db)
+
+	for _, project := range []string{"app", "app-tools", "app", "web"} {
+		if _, err := builds.Create(CreateParams{Project: project}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	if err := builds.Delete("web", 1); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	projects, err := builds.Projects()
+	if err != nil || len(projects) != 2 || projects[0] != "app" || projects[1] != "app-tools" {
+		t.Errorf("Projects = %v, %v; want [app app-tools]", projects, err)
+	}
+	if n, p, _ := builds.Stats(); n != 3 || p != 2 {
+		t.Errorf("Stats = %d builds, %d projects; want 3, 2", n, p)
+	}
+
+	// Build numbers are still never reused.
+	if b, _ := builds.Create(CreateParams{Project: "web"}); b == nil || b.BuildNumber != 2 {
+		t.Errorf("build after delete = %+v, want number 2", b)
+	}
+}
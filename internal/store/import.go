@@ -0,0 +1,98 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+// ImportResult summarizes what ImportYAML copied.
+type ImportResult struct {
+	Builds          int `json:"builds"`
+	Releases        int `json:"releases"`
+	CurrentReleases int `json:"current_releases"`
+}
+
+// ErrNotEmpty is returned by ImportYAML when the target database already holds data.
+var ErrNotEmpty = errors.New("database is not empty")
+
+// ImportYAML copies the YAML tree rooted at dataDir (builds.yaml, releases.yaml
+// and the per-build/per-release files) into db in a single transaction.
+// Build numbers, timestamps and history order are preserved. The database
+// must be empty so an import can never interleave with live data.
+func ImportYAML(dataDir string, db *DB) (*ImportResult, error) {
+	builds := NewYAMLBuildStore(dataDir)
+	releases := NewYAMLReleaseStore(dataDir)
+
+	if err := releases.Migrate(); err != nil {
+		return nil, fmt.Errorf("migrating YAML tree: %w", err)
+	}
+
+	buildIdx, err := builds.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	releaseIdx, err := releases.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	current, err := releases.ListCurrentReleases()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{}
+	err = db.Update(func(tx *Tx) error {
+		if tx.Count(bucketBuildIndex) > 0 || tx.Count(bucketReleaseHistory) > 0 {
+			return ErrNotEmpty
+		}
+
+		for _, e := range buildIdx.Builds {
+			build, err := builds.Get(e.Project, e.BuildNumber)
+			if err != nil {
+				// Keep the index entry if its metadata file is gone; any
+				// other failure aborts the import.
+				if _, statErr := os.Stat(builds.buildPath(e.Project, e.BuildNumber)); !os.IsNotExist(statErr) {
+					return fmt.Errorf("importing build %s/%d: %w", e.Project, e.BuildNumber, err)
+				}
+				log.Printf("import: %v; importing index entry only", err)
+				build = &Build{
+					Project:     e.Project,
+					BuildNumber: e.BuildNumber,
+					UploadedBy:  e.UploadedBy,
+					UploadedAt:  e.UploadedAt,
+				}
+				if err := putBuild(tx, build); err != nil {
+					return err
+				}
+				if err := tx.PutJSON(bucketBuildIndex, buildKey(e.Project, e.BuildNumber), e); err != nil {
+					return err
+				}
+			} else if err := putBuild(tx, build); err != nil {
+				return err
+			}
+			result.Builds++
+		}
+
+		for _, e := range releaseIdx.Releases {
+			if err := appendHistory(tx, e); err != nil {
+				return err
+			}
+			result.Releases++
+		}
+
+		for i := range current {
+			rel := &current[i]
+			if err := tx.PutJSON(bucketReleases, releaseKey(rel.Project, rel.Environment), rel); err != nil {
+				return err
+			}
+			result.CurrentReleases++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
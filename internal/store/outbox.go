@@ -0,0 +1,232 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Outbox job kinds.
+const (
+	JobMirrorLink   = "mirror.link"   // hardlink a build's files in storage
+	JobIssueResolve = "issue.resolve" // resolve and comment on a hydraissue issue
+)
+
+// Outbox job states.
+const (
+	JobPending = "pending"
+	JobDone    = "done"
+	JobFailed  = "failed" // gave up after the last retry; retry by hand
+)
+
+// outboxDoneRetention is how long finished jobs are kept so that their
+// outcome stays visible. Failed jobs are kept until retried.
+const outboxDoneRetention = 7 * 24 * time.Hour
+
+// OutboxJob is a side effect of an API call that is retried until it
+// succeeds. Payload is kind-specific JSON and may be rewritten between
+// attempts to record partial progress.
+type OutboxJob struct {
+	ID          string     `yaml:"id" json:"id"`
+	Kind        string     `yaml:"kind" json:"kind"`
+	Key         string     `yaml:"key" json:"key"` // e.g. "app/12" for a build, the issue ID for an issue
+	Payload     string     `yaml:"payload" json:"payload"`
+	Status      string     `yaml:"status" json:"status"`
+	Attempts    int        `yaml:"attempts" json:"attempts"`
+	NextAttempt time.Time  `yaml:"next_attempt,omitempty" json:"next_attempt,omitzero"`
+	LastError   string     `yaml:"last_error,omitempty" json:"last_error,omitempty"`
+	CreatedAt   time.Time  `yaml:"created_at" json:"created_at"`
+	DoneAt      *time.Time `yaml:"done_at,omitempty" json:"done_at,omitempty"`
+}
+
+// OutboxFilter selects jobs in List. Empty fields match everything.
+type OutboxFilter struct {
+	Kind   string
+	Status string
+	Key    string
+}
+
+// OutboxStore persists pending side effects in outbox.yaml so they are
+// retried across restarts.
+type OutboxStore struct {
+	mu      sync.Mutex
+	dataDir string
+}
+
+// NewOutboxStore creates a new OutboxStore.
+func NewOutboxStore(dataDir string) *OutboxStore {
+	return &OutboxStore{dataDir: dataDir}
+}
+
+func (s *OutboxStore) path() string {
+	return filepath.Join(s.dataDir, "outbox.yaml")
+}
+
+func (s *OutboxStore) load() ([]*OutboxJob, error) {
+	data, err := os.ReadFile(s.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading outbox: %w", err)
+	}
+	var file struct {
+		Jobs []*OutboxJob `yaml:"jobs"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing outbox: %w", err)
+	}
+	return file.Jobs, nil
+}
+
+func (s *OutboxStore) save(jobs []*OutboxJob) error {
+	cutoff := time.Now().Add(-outboxDoneRetention)
+	jobs = slices.DeleteFunc(jobs, func(j *OutboxJob) bool {
+		return j.Status == JobDone && j.DoneAt != nil && j.DoneAt.Before(cutoff)
+	})
+
+	file := struct {
+		Jobs []*OutboxJob `yaml:"jobs"`
+	}{Jobs: jobs}
+
+	data, err := yaml.Marshal(&file)
+	if err != nil {
+		return fmt.Errorf("marshaling outbox: %w", err)
+	}
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+	return atomicWriteFile(s.path(), data, 0644)
+}
+
+// Enqueue adds a pending job, due immediately, and fills in its ID.
+func (s *OutboxStore) Enqueue(job *OutboxJob) error {
+	id, err := randomID(8)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	job.ID = id
+	job.Status = JobPending
+	job.CreatedAt = now
+	job.NextAttempt = now
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs, err := s.load()
+	if err != nil {
+		return err
+	}
+	return s.save(append(jobs, job))
+}
+
+// Due returns pending jobs whose next attempt is at or before now, oldest
+// first.
+func (s *OutboxStore) Due(now time.Time) ([]OutboxJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	var due []OutboxJob
+	for _, j := range jobs {
+		if j.Status == JobPending && !j.NextAttempt.After(now) {
+			due = append(due, *j)
+		}
+	}
+	return due, nil
+}
+
+// Update stores the outcome of an attempt.
+func (s *OutboxStore) Update(job *OutboxJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs, err := s.load()
+	if err != nil {
+		return err
+	}
+	for i, existing := range jobs {
+		if existing.ID == job.ID {
+			jobs[i] = job
+			return s.save(jobs)
+		}
+	}
+	return fmt.Errorf("no outbox job with id %s", job.ID)
+}
+
+// Retry makes a job pending again with a fresh set of attempts. It returns
+// nil if the job does not exist.
+func (s *OutboxStore) Retry(id string) (*OutboxJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	for _, j := range jobs {
+		if j.ID != id {
+			continue
+		}
+		if j.Status == JobDone {
+			return nil, fmt.Errorf("outbox job %s already succeeded", id)
+		}
+		j.Status = JobPending
+		j.Attempts = 0
+		j.NextAttempt = time.Now().UTC()
+		if err := s.save(jobs); err != nil {
+			return nil, err
+		}
+		return j, nil
+	}
+	return nil, nil
+}
+
+// List returns the jobs matching f, newest first.
+func (s *OutboxStore) List(f OutboxFilter) ([]OutboxJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	result := []OutboxJob{}
+	for _, j := range jobs {
+		if (f.Kind == "" || j.Kind == f.Kind) &&
+			(f.Status == "" || j.Status == f.Status) &&
+			(f.Key == "" || j.Key == f.Key) {
+			result = append(result, *j)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	return result, nil
+}
+
+// Statuses returns the status of the newest job of the given kind per key.
+func (s *OutboxStore) Statuses(kind string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string)
+	for _, j := range jobs {
+		if j.Kind == kind {
+			result[j.Key] = j.Status // jobs are stored oldest first
+		}
+	}
+	return result, nil
+}
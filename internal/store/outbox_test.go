@@ -0,0 +1,96 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOutboxRetryLifecycle(t *testing.T) {
+	s := NewOutboxStore(t.TempDir())
+
+	job := &OutboxJob{Kind: JobMirrorLink, Key: "app/1", Payload: `{"links":[]}`}
+	if err := s.Enqueue(job); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	due, err := s.Due(time.Now())
+	if err != nil || len(due) != 1 {
+		t.Fatalf("Due = %d, %v; want 1", len(due), err)
+	}
+
+	// A failed attempt is not due until its backoff has passed.
+	j := due[0]
+	j.Attempts, j.LastError, j.NextAttempt = 1, "mirror down", time.Now().Add(time.Minute)
+	if err := s.Update(&j); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if due, _ := s.Due(time.Now()); len(due) != 0 {
+		t.Errorf("Due during backoff = %d, want 0", len(due))
+	}
+
+	// Giving up, then retrying by hand, makes it due again from scratch.
+	j.Status = JobFailed
+	if err := s.Update(&j); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if failed, _ := s.List(OutboxFilter{Status: JobFailed}); len(failed) != 1 {
+		t.Fatalf("failed jobs = %d, want 1", len(failed))
+	}
+	retried, err := s.Retry(j.ID)
+	if err != nil || retried == nil {
+		t.Fatalf("Retry = %v, %v", retried, err)
+	}
+	if retried.Status != JobPending || retried.Attempts != 0 || retried.LastError != "mirror down" {
+		t.Errorf("retried job = %+v", retried)
+	}
+	if due, _ := s.Due(time.Now()); len(due) != 1 {
+		t.Errorf("Due after retry = %d, want 1", len(due))
+	}
+
+	now := time.Now().UTC()
+	retried.Status, retried.DoneAt = JobDone, &now
+	if err := s.Update(retried); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if _, err := s.Retry(j.ID); err == nil {
+		t.Error("Retry of a finished job succeeded")
+	}
+	if missing, err := s.Retry("nope"); missing != nil || err != nil {
+		t.Errorf("Retry(unknown) = %v, %v; want nil, nil", missing, err)
+	}
+}
+
+func TestOutboxStatusesAndPruning(t *testing.T) {
+	s := NewOutboxStore(t.TempDir())
+
+	old := &OutboxJob{Kind: JobMirrorLink, Key: "app/1"}
+	newer := &OutboxJob{Kind: JobMirrorLink, Key: "app/1"}
+	issue := &OutboxJob{Kind: JobIssueResolve, Key: "42"}
+	for _, j := range []*OutboxJob{old, newer, issue} {
+		if err := s.Enqueue(j); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+
+	longAgo := time.Now().Add(-30 * 24 * time.Hour)
+	old.Status, old.DoneAt = JobDone, &longAgo
+	if err := s.Update(old); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	statuses, err := s.Statuses(JobMirrorLink)
+	if err != nil {
+		t.Fatalf("Statuses: %v", err)
+	}
+	if statuses["app/1"] != JobPending || len(statuses) != 1 {
+		t.Errorf("Statuses = %v, want app/1 pending from the newest job", statuses)
+	}
+
+	// Saving drops jobs that finished beyond the retention window.
+	jobs, _ := s.List(OutboxFilter{Kind: JobMirrorLink})
+	if len(jobs) != 1 || jobs[0].ID != newer.ID {
+		t.Errorf("mirror link jobs after pruning = %+v", jobs)
+	}
+	if jobs, _ := s.List(OutboxFilter{Key: "42"}); len(jobs) != 1 || jobs[0].Kind != JobIssueResolve {
+		t.Errorf("List by key = %+v", jobs)
+	}
+}
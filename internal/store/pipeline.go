@@ -0,0 +1,236 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PipelineStage is one environment in a promotion pipeline.
+type PipelineStage struct {
+	Environment string `yaml:"environment" json:"environment"`
+	// MinSoak is how long (e.g. "24h") a build must have been live in the
+	// preceding stage before it may enter this one.
+	MinSoak string `yaml:"min_soak,omitempty" json:"min_soak,omitempty"`
+}
+
+// Pipeline is the ordered list of environments a project's builds must pass through.
+type Pipeline struct {
+	Project   string          `yaml:"project" json:"project"`
+	Stages    []PipelineStage `yaml:"stages" json:"stages"`
+	UpdatedBy string          `yaml:"updated_by,omitempty" json:"updated_by,omitempty"`
+	UpdatedAt time.Time       `yaml:"updated_at" json:"updated_at"`
+}
+
+// PipelineViolation describes the prerequisite a promotion is missing.
+type PipelineViolation struct {
+	Environment     string `json:"environment"`                 // stage the build must have been live in
+	BuildNumber     int    `json:"build_number"`                // build being promoted
+	MinSoak         string `json:"min_soak,omitempty"`          // required soak in Environment
+	Soaked          string `json:"soaked,omitempty"`            // longest time the build was live there
+	NeverReleasedTo bool   `json:"never_released_to,omitempty"` // the build was never live in Environment
+}
+
+func (v *PipelineViolation) Error() string {
+	if v.NeverReleasedTo {
+		return fmt.Sprintf("build %d has not been released to %s", v.BuildNumber, v.Environment)
+	}
+	return fmt.Sprintf("build %d has been live in %s for %s, needs %s", v.BuildNumber, v.Environment, v.Soaked, v.MinSoak)
+}
+
+// Validate checks that stages are unique and soak durations parse.
+func (p *Pipeline) Validate() error {
+	if len(p.Stages) == 0 {
+		return fmt.Errorf("pipeline needs at least one stage")
+	}
+	seen := make(map[string]bool)
+	for i, st := range p.Stages {
+		if st.Environment == "" {
+			return fmt.Errorf("stage %d has no environment", i+1)
+		}
+		if seen[st.Environment] {
+			return fmt.Errorf("environment %q appears twice", st.Environment)
+		}
+		seen[st.Environment] = true
+		if st.MinSoak != "" {
+			if i == 0 {
+				return fmt.Errorf("the first stage cannot have a min_soak")
+			}
+			if _, err := time.ParseDuration(st.MinSoak); err != nil {
+				return fmt.Errorf("stage %s: invalid min_soak %q: %w", st.Environment, st.MinSoak, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Check reports whether build may enter env. history is the project's release
+// index in promotion order. Environments outside the pipeline and its first
+// stage are not gated.
+func (p *Pipeline) Check(history []ReleaseIndexEntry, env string, build int, now time.Time) *PipelineViolation {
+	stage := -1
+	for i, st := range p.Stages {
+		if st.Environment == env {
+			stage = i
+			break
+		}
+	}
+	if stage <= 0 {
+		return nil
+	}
+
+	prev := p.Stages[stage-1].Environment
+	var minSoak time.Duration
+	if p.Stages[stage].MinSoak != "" {
+		minSoak, _ = time.ParseDuration(p.Stages[stage].MinSoak)
+	}
+
+	soaked, ok := longestLive(history, prev, build, now)
+	if !ok {
+		return &PipelineViolation{Environment: prev, BuildNumber: build, MinSoak: p.Stages[stage].MinSoak, NeverReleasedTo: true}
+	}
+	if soaked < minSoak {
+		return &PipelineViolation{
+			Environment: prev,
+			BuildNumber: build,
+			MinSoak:     p.Stages[stage].MinSoak,
+			Soaked:      soaked.Truncate(time.Second).String(),
+		}
+	}
+	return nil
+}
+
+// longestLive returns the longest contiguous period build was live in env,
+// and whether it was ever live there.
+func longestLive(history []ReleaseIndexEntry, env string, build int, now time.Time) (time.Duration, bool) {
+	var longest time.Duration
+	var since time.Time
+	live, found := false, false
+	for _, e := range history {
+		if e.Environment != env {
+			continue
+		}
+		if live && e.BuildNumber != build {
+			if d := e.ReleasedAt.Sub(since); d > longest {
+				longest = d
+			}
+			live = false
+		}
+		if !live && e.BuildNumber == build {
+			live, found = true, true
+			since = e.ReleasedAt
+		}
+	}
+	if live {
+		if d := now.Sub(since); d > longest {
+			longest = d
+		}
+	}
+	return longest, found
+}
+
+// PipelineStore manages per-project pipelines with YAML persistence.
+type PipelineStore struct {
+	mu      sync.Mutex
+	dataDir string
+}
+
+// NewPipelineStore creates a new PipelineStore.
+func NewPipelineStore(dataDir string) *PipelineStore {
+	return &PipelineStore{dataDir: dataDir}
+}
+
+func (s *PipelineStore) path() string {
+	return filepath.Join(s.dataDir, "pipelines.yaml")
+}
+
+func (s *PipelineStore) load() (map[string]*Pipeline, error) {
+	data, err := os.ReadFile(s.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string]*Pipeline), nil
+		}
+		return nil, fmt.Errorf("reading pipelines: %w", err)
+	}
+	var file struct {
+		Pipelines []*Pipeline `yaml:"pipelines"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing pipelines: %w", err)
+	}
+	result := make(map[string]*Pipeline, len(file.Pipelines))
+	for _, p := range file.Pipelines {
+		result[p.Project] = p
+	}
+	return result, nil
+}
+
+func (s *PipelineStore) save(pipelines map[string]*Pipeline) error {
+	var file struct {
+		Pipelines []*Pipeline `yaml:"pipelines"`
+	}
+	for _, p := range pipelines {
+		file.Pipelines = append(file.Pipelines, p)
+	}
+	sort.Slice(file.Pipelines, func(i, j int) bool { return file.Pipelines[i].Project < file.Pipelines[j].Project })
+
+	data, err := yaml.Marshal(&file)
+	if err != nil {
+		return fmt.Errorf("marshaling pipelines: %w", err)
+	}
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+	return atomicWriteFile(s.path(), data, 0644)
+}
+
+// Get returns the pipeline for a project, or nil if none is defined.
+func (s *PipelineStore) Get(project string) (*Pipeline, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pipelines, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return pipelines[project], nil
+}
+
+// Set validates and stores the pipeline for a project, replacing any existing one.
+func (s *PipelineStore) Set(p *Pipeline) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pipelines, err := s.load()
+	if err != nil {
+		return err
+	}
+	p.UpdatedAt = time.Now().UTC()
+	pipelines[p.Project] = p
+	return s.save(pipelines)
+}
+
+// Delete removes the pipeline for a project.
+func (s *PipelineStore) Delete(project string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pipelines, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := pipelines[project]; !ok {
+		return fmt.Errorf("no pipeline defined for %s", project)
+	}
+	delete(pipelines, project)
+	return s.save(pipelines)
+}
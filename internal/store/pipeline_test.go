@@ -0,0 +1,43 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPipelineCheck(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	p := &Pipeline{Stages: []PipelineStage{
+		{Environment: "dev"},
+		{Environment: "staging"},
+		{Environment: "production", MinSoak: "24h"},
+	}}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	history := []ReleaseIndexEntry{
+		{Environment: "dev", BuildNumber: 1, ReleasedAt: now.Add(-72 * time.Hour)},
+		{Environment: "staging", BuildNumber: 1, ReleasedAt: now.Add(-48 * time.Hour)},
+		{Environment: "dev", BuildNumber: 2, ReleasedAt: now.Add(-30 * time.Hour)},
+		{Environment: "staging", BuildNumber: 2, ReleasedAt: now.Add(-2 * time.Hour)},
+	}
+
+	tests := []struct {
+		env   string
+		build int
+		ok    bool
+	}{
+		{"dev", 3, true},         // first stage is open
+		{"staging", 3, false},    // never in dev
+		{"staging", 2, true},     // was in dev
+		{"production", 1, true},  // 46h in staging
+		{"production", 2, false}, // only 2h in staging
+	}
+	for _, tt := range tests {
+		v := p.Check(history, tt.env, tt.build, now)
+		if (v == nil) != tt.ok {
+			t.Errorf("Check(%s, %d) = %v, want ok=%v", tt.env, tt.build, v, tt.ok)
+		}
+	}
+}
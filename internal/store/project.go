@@ -0,0 +1,198 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Project is a registered project and its release settings.
+type Project struct {
+	Name        string `yaml:"name" json:"name"`
+	DisplayName string `yaml:"display_name,omitempty" json:"display_name,omitempty"`
+	// HealthURL is the base URL whose /api/v1/health reports the deployed
+	// version; empty if the project has no direct health endpoint.
+	HealthURL string `yaml:"health_url,omitempty" json:"health_url,omitempty"`
+	// Environments limits where the project may be released; empty allows all.
+	Environments []string `yaml:"environments,omitempty" json:"environments,omitempty"`
+	Owners       []string `yaml:"owners,omitempty" json:"owners,omitempty"`
+	// Retention overrides the policy from retention.yaml when set.
+	Retention *RetentionPolicy `yaml:"retention,omitempty" json:"retention,omitempty"`
+	CreatedAt time.Time        `yaml:"created_at" json:"created_at"`
+	UpdatedAt time.Time        `yaml:"updated_at" json:"updated_at"`
+}
+
+// AllowsEnvironment reports whether the project may be released to env.
+func (p *Project) AllowsEnvironment(env string) bool {
+	if len(p.Environments) == 0 {
+		return true
+	}
+	for _, e := range p.Environments {
+		if e == env {
+			return true
+		}
+	}
+	return false
+}
+
+// ProjectUpdate holds the fields of a partial project update; nil fields are
+// left unchanged. A zero Retention policy removes the override.
+type ProjectUpdate struct {
+	DisplayName  *string
+	HealthURL    *string
+	Environments *[]string
+	Owners       *[]string
+	Retention    *RetentionPolicy
+}
+
+// ProjectStore manages the project registry with YAML persistence in projects.yaml.
+type ProjectStore struct {
+	mu      sync.Mutex
+	dataDir string
+}
+
+// NewProjectStore creates a new ProjectStore.
+func NewProjectStore(dataDir string) *ProjectStore {
+	return &ProjectStore{dataDir: dataDir}
+}
+
+func (s *ProjectStore) path() string {
+	return filepath.Join(s.dataDir, "projects.yaml")
+}
+
+func (s *ProjectStore) load() (map[string]*Project, error) {
+	data, err := os.ReadFile(s.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string]*Project), nil
+		}
+		return nil, fmt.Errorf("reading projects: %w", err)
+	}
+	var file struct {
+		Projects []*Project `yaml:"projects"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing projects: %w", err)
+	}
+	result := make(map[string]*Project, len(file.Projects))
+	for _, p := range file.Projects {
+		result[p.Name] = p
+	}
+	return result, nil
+}
+
+func (s *ProjectStore) save(projects map[string]*Project) error {
+	var file struct {
+		Projects []*Project `yaml:"projects"`
+	}
+	for _, p := range projects {
+		file.Projects = append(file.Projects, p)
+	}
+	sort.Slice(file.Projects, func(i, j int) bool { return file.Projects[i].Name < file.Projects[j].Name })
+
+	data, err := yaml.Marshal(&file)
+	if err != nil {
+		return fmt.Errorf("marshaling projects: %w", err)
+	}
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+	return atomicWriteFile(s.path(), data, 0644)
+}
+
+// Get returns a registered project, or nil if it is not registered.
+func (s *ProjectStore) Get(name string) (*Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	projects, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return projects[name], nil
+}
+
+// List returns all registered projects sorted by name.
+func (s *ProjectStore) List() ([]Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	projects, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Project, 0, len(projects))
+	for _, p := range projects {
+		result = append(result, *p)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// Create registers a new project.
+func (s *ProjectStore) Create(p *Project) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	projects, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := projects[p.Name]; ok {
+		return fmt.Errorf("project %s is already registered", p.Name)
+	}
+	now := time.Now().UTC()
+	p.CreatedAt = now
+	p.UpdatedAt = now
+	if p.Retention != nil && p.Retention.IsZero() {
+		p.Retention = nil
+	}
+	projects[p.Name] = p
+	return s.save(projects)
+}
+
+// Update applies a partial update to a registered project.
+func (s *ProjectStore) Update(name string, u ProjectUpdate) (*Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	projects, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	p, ok := projects[name]
+	if !ok {
+		return nil, fmt.Errorf("project %s is not registered", name)
+	}
+	if u.DisplayName != nil {
+		p.DisplayName = *u.DisplayName
+	}
+	if u.HealthURL != nil {
+		p.HealthURL = *u.HealthURL
+	}
+	if u.Environments != nil {
+		p.Environments = *u.Environments
+	}
+	if u.Owners != nil {
+		p.Owners = *u.Owners
+	}
+	if u.Retention != nil {
+		if u.Retention.IsZero() {
+			p.Retention = nil
+		} else {
+			policy := *u.Retention
+			p.Retention = &policy
+		}
+	}
+	p.UpdatedAt = time.Now().UTC()
+
+	if err := s.save(projects); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,63 @@
+package store
+
+import "testing"
+
+func TestProjectStoreCreateUpdate(t *testing.T) {
+	s := NewProjectStore(t.TempDir())
+
+	p := &Project{
+		Name:         "app",
+		HealthURL:    "https://app.example.com",
+		Environments: []string{"staging", "production"},
+		Retention:    &RetentionPolicy{},
+	}
+	if err := s.Create(p); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := s.Create(&Project{Name: "app"}); err == nil {
+		t.Error("Create of a duplicate project succeeded")
+	}
+
+	got, err := s.Get("app")
+	if err != nil || got == nil {
+		t.Fatalf("Get: %v, %v", got, err)
+	}
+	if got.Retention != nil {
+		t.Errorf("empty retention policy was stored: %+v", got.Retention)
+	}
+	if !got.AllowsEnvironment("production") || got.AllowsEnvironment("dev") {
+		t.Errorf("AllowsEnvironment does not follow %v", got.Environments)
+	}
+
+	name := "App"
+	envs := []string{}
+	got, err = s.Update("app", ProjectUpdate{
+		DisplayName:  &name,
+		Environments: &envs,
+		Retention:    &RetentionPolicy{KeepLast: 5},
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got.DisplayName != "App" || got.HealthURL != "https://app.example.com" {
+		t.Errorf("Update changed the wrong fields: %+v", got)
+	}
+	if !got.AllowsEnvironment("dev") {
+		t.Error("clearing environments should allow all")
+	}
+	if got.Retention == nil || got.Retention.KeepLast != 5 {
+		t.Errorf("Retention = %+v, want keep_last 5", got.Retention)
+	}
+
+	got, err = s.Update("app", ProjectUpdate{Retention: &RetentionPolicy{}})
+	if err != nil || got.Retention != nil {
+		t.Errorf("zero retention should clear the override: %+v, %v", got.Retention, err)
+	}
+
+	if _, err := s.Update("missing", ProjectUpdate{}); err == nil {
+		t.Error("Update of an unregistered project succeeded")
+	}
+	if p, _ := s.Get("missing"); p != nil {
+		t.Errorf("Get(missing) = %+v, want nil", p)
+	}
+}
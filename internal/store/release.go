@@ -21,6 +21,42 @@ type Release struct {
 	ReleasedAt          time.Time `yaml:"released_at" json:"released_at"`
 	ReleaseNotes        string    `yaml:"release_notes,omitempty" json:"release_notes,omitempty"`
 	PreviousBuildNumber int       `yaml:"previous_build_number,omitempty" json:"previous_build_number,omitempty"`
+	RolledBackFrom      int       `yaml:"rolled_back_from,omitempty" json:"rolled_back_from,omitempty"`
+	PromotedFrom        string    `yaml:"promoted_from,omitempty" json:"promoted_from,omitempty"`
+	OverrideReason      string    `yaml:"override_reason,omitempty" json:"override_reason,omitempty"`
+	PreviousVersion     string    `yaml:"previous_version,omitempty" json:"previous_version,omitempty"`
+	Rollout             *Rollout  `yaml:"rollout,omitempty" json:"rollout,omitempty"`
+	// MinVersion is the oldest version still supported alongside this release.
+	MinVersion string `yaml:"min_version,omitempty" json:"min_version,omitempty"`
+	// Files lists the files of a release published without a build (legacy
+	// publish). Build releases take their files from the build.
+	Files []ReleaseFile `yaml:"files,omitempty" json:"files,omitempty"`
+}
+
+// ReleaseFile is a file published directly under a release.
+type ReleaseFile struct {
+	Name   string `yaml:"name" json:"name"`
+	Path   string `yaml:"path" json:"path"` // download path below /<project>/<channel>/
+	Size   int64  `yaml:"size" json:"size"`
+	SHA256 string `yaml:"sha256" json:"sha256"`
+}
+
+// Rollout tracks a staged rollout of a release. Clients outside the rollout
+// keep receiving the previous version. A release without a Rollout is live
+// for every client.
+type Rollout struct {
+	Percent   int       `yaml:"percent" json:"percent"`
+	Halted    bool      `yaml:"halted,omitempty" json:"halted,omitempty"`
+	UpdatedBy string    `yaml:"updated_by,omitempty" json:"updated_by,omitempty"`
+	UpdatedAt time.Time `yaml:"updated_at" json:"updated_at"`
+}
+
+// ServesNew reports whether a client in bucket (0-99) should get the new version.
+func (r *Rollout) ServesNew(bucket int) bool {
+	if r == nil {
+		return true
+	}
+	return !r.Halted && bucket < r.Percent
 }
 
 // ReleaseIndex is the YAML-persisted index of all release promotions.
@@ -30,34 +66,38 @@ type ReleaseIndex struct {
 
 // ReleaseIndexEntry is a summary entry in the releases index.
 type ReleaseIndexEntry struct {
-	Project     string    `yaml:"project"`
-	Environment string    `yaml:"environment"`
-	BuildNumber int       `yaml:"build_number"`
-	Version     string    `yaml:"version"`
-	ReleasedBy  string    `yaml:"released_by"`
-	ReleasedAt  time.Time `yaml:"released_at"`
+	Project     string    `yaml:"project" json:"project"`
+	Environment string    `yaml:"environment" json:"environment"`
+	BuildNumber int       `yaml:"build_number" json:"build_number"`
+	Version     string    `yaml:"version" json:"version"`
+	ReleasedBy  string    `yaml:"released_by" json:"released_by"`
+	ReleasedAt  time.Time `yaml:"released_at" json:"released_at"`
+
+	RolledBackFrom int    `yaml:"rolled_back_from,omitempty" json:"rolled_back_from,omitempty"` // build replaced by a rollback
+	PromotedFrom   string `yaml:"promoted_from,omitempty" json:"promoted_from,omitempty"`       // source environment of a promotion by reference
+	OverrideReason string `yaml:"override_reason,omitempty" json:"override_reason,omitempty"`   // why a pipeline gate was bypassed
 }
 
-// ReleaseStore manages release metadata with YAML persistence.
-type ReleaseStore struct {
+// YAMLReleaseStore manages release metadata with YAML persistence.
+type YAMLReleaseStore struct {
 	mu      sync.Mutex
 	dataDir string // root data directory
 }
 
-// NewReleaseStore creates a new ReleaseStore.
-func NewReleaseStore(dataDir string) *ReleaseStore {
-	return &ReleaseStore{dataDir: dataDir}
+// NewYAMLReleaseStore creates a new YAMLReleaseStore.
+func NewYAMLReleaseStore(dataDir string) *YAMLReleaseStore {
+	return &YAMLReleaseStore{dataDir: dataDir}
 }
 
-func (s *ReleaseStore) indexPath() string {
+func (s *YAMLReleaseStore) indexPath() string {
 	return filepath.Join(s.dataDir, "releases.yaml")
 }
 
-func (s *ReleaseStore) releasePath(project, env string) string {
+func (s *YAMLReleaseStore) releasePath(project, env string) string {
 	return filepath.Join(s.dataDir, "releases", project, env, "release.yaml")
 }
 
-func (s *ReleaseStore) loadIndex() (*ReleaseIndex, error) {
+func (s *YAMLReleaseStore) loadIndex() (*ReleaseIndex, error) {
 	data, err := os.ReadFile(s.indexPath())
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -72,7 +112,7 @@ func (s *ReleaseStore) loadIndex() (*ReleaseIndex, error) {
 	return &idx, nil
 }
 
-func (s *ReleaseStore) saveIndex(idx *ReleaseIndex) error {
+func (s *YAMLReleaseStore) saveIndex(idx *ReleaseIndex) error {
 	data, err := yaml.Marshal(idx)
 	if err != nil {
 		return fmt.Errorf("marshaling release index: %w", err)
@@ -83,7 +123,7 @@ func (s *ReleaseStore) saveIndex(idx *ReleaseIndex) error {
 	return atomicWriteFile(s.indexPath(), data, 0644)
 }
 
-func (s *ReleaseStore) loadRelease(project, env string) (*Release, error) {
+func (s *YAMLReleaseStore) loadRelease(project, env string) (*Release, error) {
 	data, err := os.ReadFile(s.releasePath(project, env))
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -98,7 +138,7 @@ func (s *ReleaseStore) loadRelease(project, env string) (*Release, error) {
 	return &rel, nil
 }
 
-func (s *ReleaseStore) saveRelease(rel *Release) error {
+func (s *YAMLReleaseStore) saveRelease(rel *Release) error {
 	path := s.releasePath(rel.Project, rel.Environment)
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return fmt.Errorf("creating release directory: %w", err)
@@ -112,16 +152,230 @@ func (s *ReleaseStore) saveRelease(rel *Release) error {
 
 // PromoteRequest contains the parameters for promoting a build.
 type PromoteRequest struct {
+	Project        string
+	Environment    string
+	BuildNumber    int
+	Version        string
+	ReleasedBy     string
+	ReleaseNotes   string
+	PromotedFrom   string // source environment when promoting by reference
+	OverrideReason string // set when a pipeline gate was explicitly bypassed
+	RolloutPercent int    // start a staged rollout at this percentage (0 or 100 = everyone)
+	MinVersion     string // oldest version still supported
+	Files          []ReleaseFile
+}
+
+// newRelease builds the release record for a promotion, chaining it to the
+// current release of the environment (if any).
+func newRelease(req PromoteRequest, current *Release, now time.Time) *Release {
+	var previousBuild int
+	var previousVersion string
+	if current != nil {
+		previousBuild = current.BuildNumber
+		previousVersion = current.Version
+	}
+	rel := &Release{
+		Project:             req.Project,
+		Environment:         req.Environment,
+		BuildNumber:         req.BuildNumber,
+		Version:             req.Version,
+		ReleasedBy:          req.ReleasedBy,
+		ReleasedAt:          now,
+		ReleaseNotes:        req.ReleaseNotes,
+		PreviousBuildNumber: previousBuild,
+		PromotedFrom:        req.PromotedFrom,
+		OverrideReason:      req.OverrideReason,
+		PreviousVersion:     previousVersion,
+		MinVersion:          req.MinVersion,
+		Files:               req.Files,
+	}
+	if req.RolloutPercent > 0 && req.RolloutPercent < 100 && previousVersion != "" {
+		rel.Rollout = &Rollout{Percent: req.RolloutPercent, UpdatedBy: req.ReleasedBy, UpdatedAt: now}
+	}
+	return rel
+}
+
+// RolloutRequest contains the parameters for changing a staged rollout.
+type RolloutRequest struct {
+	Project     string
+	Environment string
+	Percent     int  // new percentage; 100 completes the rollout
+	Halt        bool // stop serving the new version to anyone
+	UpdatedBy   string
+}
+
+// applyRollout updates the rollout state of the current release.
+func applyRollout(req RolloutRequest, current *Release, now time.Time) error {
+	if current == nil {
+		return fmt.Errorf("no release found for %s/%s", req.Project, req.Environment)
+	}
+	if req.Halt {
+		if current.Rollout == nil {
+			return fmt.Errorf("%s/%s has no rollout in progress", req.Project, req.Environment)
+		}
+		current.Rollout.Halted = true
+		current.Rollout.UpdatedBy = req.UpdatedBy
+		current.Rollout.UpdatedAt = now
+		return nil
+	}
+	// Zero is rejected rather than meaning "nobody": a promotion's zero
+	// means "everyone", and halting already serves nobody.
+	if req.Percent < 1 || req.Percent > 100 {
+		return fmt.Errorf("rollout percent must be between 1 and 100; use halt to stop serving the release")
+	}
+	if req.Percent == 100 {
+		current.Rollout = nil
+		return nil
+	}
+	if current.PreviousVersion == "" {
+		return fmt.Errorf("%s/%s has no previous version to stage a rollout against", req.Project, req.Environment)
+	}
+	current.Rollout = &Rollout{Percent: req.Percent, UpdatedBy: req.UpdatedBy, UpdatedAt: now}
+	return nil
+}
+
+// RollbackRequest contains the parameters for a rollback. At most one of
+// TargetBuild and Steps may be set; with neither, Steps defaults to 1.
+type RollbackRequest struct {
 	Project      string
 	Environment  string
-	BuildNumber  int
-	Version      string
-	ReleasedBy   string
-	ReleaseNotes string
+	RolledBackBy string
+	TargetBuild  int    // roll back to this build number
+	Steps        int    // or roll back this many releases
+	Force        bool   // allow a TargetBuild never released to this environment
+	Version      string // version for a forced target with no release history
+	Skip         []int  // builds a step rollback must pass over, e.g. yanked ones
+}
+
+// rollbackTarget walks the environment's history newest-first and returns
+// the build that is steps releases back. The live build, rollback entries,
+// builds that have since been rolled back away from, and skip are passed
+// over, so repeated rollbacks keep moving back instead of alternating
+// between two builds.
+func rollbackTarget(history []ReleaseIndexEntry, env string, current, steps int, skip []int) (ReleaseIndexEntry, bool) {
+	seen := map[int]bool{current: true}
+	for _, n := range skip {
+		seen[n] = true
+	}
+	abandoned := make(map[int]bool)
+	for i := len(history) - 1; i >= 0; i-- {
+		e := history[i]
+		if e.Environment != env {
+			continue
+		}
+		if e.RolledBackFrom != 0 {
+			abandoned[e.RolledBackFrom] = true
+			continue
+		}
+		if seen[e.BuildNumber] || abandoned[e.BuildNumber] {
+			continue
+		}
+		seen[e.BuildNumber] = true
+		steps--
+		if steps == 0 {
+			return e, true
+		}
+	}
+	return ReleaseIndexEntry{}, false
+}
+
+// PreviousRelease returns the release a one-step rollback of env from the
+// current build would go back to, passing over skip. history is the
+// project's release index in promotion order.
+func PreviousRelease(history []ReleaseIndexEntry, env string, current int, skip []int) (ReleaseIndexEntry, bool) {
+	return rollbackTarget(history, env, current, 1, skip)
+}
+
+// rollbackRelease builds the release record for a rollback. history is the
+// project's release index in promotion order.
+func rollbackRelease(req RollbackRequest, current *Release, history []ReleaseIndexEntry, now time.Time) (*Release, error) {
+	project, env := req.Project, req.Environment
+	if current == nil {
+		return nil, fmt.Errorf("no release found for %s/%s", project, env)
+	}
+	if req.TargetBuild > 0 && req.Steps > 0 {
+		return nil, fmt.Errorf("target build and steps are mutually exclusive")
+	}
+
+	var target int
+	var version string
+	if req.TargetBuild > 0 {
+		target = req.TargetBuild
+		if target == current.BuildNumber {
+			return nil, fmt.Errorf("build %d is already live in %s/%s", target, project, env)
+		}
+		// Newest version this build was released as, preferring this environment.
+		released := false
+		for i := len(history) - 1; i >= 0; i-- {
+			e := history[i]
+			if e.BuildNumber != target {
+				continue
+			}
+			if e.Environment == env {
+				released = true
+				version = e.Version
+				break
+			}
+			if version == "" {
+				version = e.Version
+			}
+		}
+		if !released && !req.Force {
+			return nil, fmt.Errorf("build %d was never released to %s/%s (use force to override)", target, project, env)
+		}
+		if req.Version != "" {
+			version = req.Version
+		}
+		if version == "" {
+			return nil, fmt.Errorf("no known version for build %d; a version is required", target)
+		}
+	} else {
+		steps := req.Steps
+		if steps <= 0 {
+			steps = 1
+		}
+		e, ok := rollbackTarget(history, env, current.BuildNumber, steps, req.Skip)
+		if !ok {
+			if steps == 1 {
+				return nil, fmt.Errorf("no previous build to roll back to for %s/%s", project, env)
+			}
+			return nil, fmt.Errorf("%s/%s has fewer than %d earlier releases to roll back to", project, env, steps)
+		}
+		target, version = e.BuildNumber, e.Version
+	}
+
+	return &Release{
+		Project:             project,
+		Environment:         env,
+		BuildNumber:         target,
+		Version:             version,
+		ReleasedBy:          req.RolledBackBy,
+		ReleasedAt:          now,
+		ReleaseNotes:        fmt.Sprintf("Rollback from build %d", current.BuildNumber),
+		PreviousBuildNumber: current.BuildNumber,
+		PreviousVersion:     current.Version,
+		RolledBackFrom:      current.BuildNumber,
+	}, nil
+}
+
+// releaseIndexEntry summarizes a release for the history index.
+func releaseIndexEntry(rel *Release) ReleaseIndexEntry {
+	return ReleaseIndexEntry{
+		Project:     rel.Project,
+		Environment: rel.Environment,
+		BuildNumber: rel.BuildNumber,
+		Version:     rel.Version,
+		ReleasedBy:  rel.ReleasedBy,
+		ReleasedAt:  rel.ReleasedAt,
+
+		RolledBackFrom: rel.RolledBackFrom,
+		PromotedFrom:   rel.PromotedFrom,
+		OverrideReason: rel.OverrideReason,
+	}
 }
 
 // Promote promotes a build to an environment, persists state, and writes latest.json.
-func (s *ReleaseStore) Promote(req PromoteRequest) (*Release, error) {
+func (s *YAMLReleaseStore) Promote(req PromoteRequest) (*Release, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -136,22 +390,7 @@ func (s *ReleaseStore) Promote(req PromoteRequest) (*Release, error) {
 		return nil, err
 	}
 
-	var previousBuild int
-	if current != nil {
-		previousBuild = current.BuildNumber
-	}
-
-	now := time.Now().UTC()
-	rel := &Release{
-		Project:             req.Project,
-		Environment:         req.Environment,
-		BuildNumber:         req.BuildNumber,
-		Version:             req.Version,
-		ReleasedBy:          req.ReleasedBy,
-		ReleasedAt:          now,
-		ReleaseNotes:        req.ReleaseNotes,
-		PreviousBuildNumber: previousBuild,
-	}
+	rel := newRelease(req, current, time.Now().UTC())
 
 	// Save per-env release state.
 	if err := s.saveRelease(rel); err != nil {
@@ -159,14 +398,7 @@ func (s *ReleaseStore) Promote(req PromoteRequest) (*Release, error) {
 	}
 
 	// Append to index.
-	idx.Releases = append(idx.Releases, ReleaseIndexEntry{
-		Project:     req.Project,
-		Environment: req.Environment,
-		BuildNumber: req.BuildNumber,
-		Version:     req.Version,
-		ReleasedBy:  req.ReleasedBy,
-		ReleasedAt:  now,
-	})
+	idx.Releases = append(idx.Releases, releaseIndexEntry(rel))
 	if err := s.saveIndex(idx); err != nil {
 		return nil, err
 	}
@@ -174,64 +406,38 @@ func (s *ReleaseStore) Promote(req PromoteRequest) (*Release, error) {
 	return rel, nil
 }
 
-// Rollback rolls back to the previous build in an environment.
-func (s *ReleaseStore) Rollback(project, env, rolledBackBy string) (*Release, error) {
+// Rollback rolls an environment back to an earlier build.
+func (s *YAMLReleaseStore) Rollback(req RollbackRequest) (*Release, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	current, err := s.loadRelease(project, env)
+	current, err := s.loadRelease(req.Project, req.Environment)
 	if err != nil {
 		return nil, err
 	}
-	if current == nil {
-		return nil, fmt.Errorf("no release found for %s/%s", project, env)
-	}
-	if current.PreviousBuildNumber == 0 {
-		return nil, fmt.Errorf("no previous build to roll back to for %s/%s", project, env)
-	}
 
 	idx, err := s.loadIndex()
 	if err != nil {
 		return nil, err
 	}
 
-	// Find the previous release entry to get its version.
-	var prevVersion string
-	for i := len(idx.Releases) - 1; i >= 0; i-- {
-		e := idx.Releases[i]
-		if e.Project == project && e.Environment == env && e.BuildNumber == current.PreviousBuildNumber {
-			prevVersion = e.Version
-			break
+	var history []ReleaseIndexEntry
+	for _, e := range idx.Releases {
+		if e.Project == req.Project {
+			history = append(history, e)
 		}
 	}
-	if prevVersion == "" {
-		prevVersion = current.Version // fallback
-	}
 
-	now := time.Now().UTC()
-	rel := &Release{
-		Project:             project,
-		Environment:         env,
-		BuildNumber:         current.PreviousBuildNumber,
-		Version:             prevVersion,
-		ReleasedBy:          rolledBackBy,
-		ReleasedAt:          now,
-		ReleaseNotes:        fmt.Sprintf("Rollback from build %d", current.BuildNumber),
-		PreviousBuildNumber: current.BuildNumber,
+	rel, err := rollbackRelease(req, current, history, time.Now().UTC())
+	if err != nil {
+		return nil, err
 	}
 
 	if err := s.saveRelease(rel); err != nil {
 		return nil, err
 	}
 
-	idx.Releases = append(idx.Releases, ReleaseIndexEntry{
-		Project:     project,
-		Environment: env,
-		BuildNumber: rel.BuildNumber,
-		Version:     prevVersion,
-		ReleasedBy:  rolledBackBy,
-		ReleasedAt:  now,
-	})
+	idx.Releases = append(idx.Releases, releaseIndexEntry(rel))
 	if err := s.saveIndex(idx); err != nil {
 		return nil, err
 	}
@@ -239,8 +445,26 @@ func (s *ReleaseStore) Rollback(project, env, rolledBackBy string) (*Release, er
 	return rel, nil
 }
 
+// SetRollout changes the staged rollout of the current release.
+func (s *YAMLReleaseStore) SetRollout(req RolloutRequest) (*Release, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.loadRelease(req.Project, req.Environment)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyRollout(req, current, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	if err := s.saveRelease(current); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
 // Get returns the current release for a project/environment.
-func (s *ReleaseStore) Get(project, env string) (*Release, error) {
+func (s *YAMLReleaseStore) Get(project, env string) (*Release, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -255,7 +479,7 @@ func (s *ReleaseStore) Get(project, env string) (*Release, error) {
 }
 
 // List returns all release history for a project (from the index).
-func (s *ReleaseStore) List(project string) ([]ReleaseIndexEntry, error) {
+func (s *YAMLReleaseStore) List(project string) ([]ReleaseIndexEntry, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -275,7 +499,7 @@ func (s *ReleaseStore) List(project string) ([]ReleaseIndexEntry, error) {
 
 // ListCurrentReleases returns the current release for every project/environment
 // by scanning the releases directory. Used to pre-populate the latest map on startup.
-func (s *ReleaseStore) ListCurrentReleases() ([]Release, error) {
+func (s *YAMLReleaseStore) ListCurrentReleases() ([]Release, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -313,7 +537,7 @@ func (s *ReleaseStore) ListCurrentReleases() ([]Release, error) {
 
 // Migrate renames any "prod" environment directories and entries to "production".
 // This is a one-time migration to normalize the channel naming.
-func (s *ReleaseStore) Migrate() error {
+func (s *YAMLReleaseStore) Migrate() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -390,7 +614,7 @@ func (s *ReleaseStore) Migrate() error {
 }
 
 // Stats returns the total number of release promotions.
-func (s *ReleaseStore) Stats() (releaseCount int, err error) {
+func (s *YAMLReleaseStore) Stats() (releaseCount int, err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
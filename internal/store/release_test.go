@@ -0,0 +1,123 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRollbackWalksBackThroughHistory(t *testing.T) {
+	now := time.Now().UTC()
+	var history []ReleaseIndexEntry
+	var current *Release
+	for _, n := range []int{1, 2, 3} {
+		current = newRelease(PromoteRequest{Project: "app", Environment: "production", BuildNumber: n, Version: fmt.Sprintf("1.0.%d", n)}, current, now)
+		history = append(history, releaseIndexEntry(current))
+	}
+
+	rollback := func(req RollbackRequest) (*Release, error) {
+		req.Project, req.Environment = "app", "production"
+		rel, err := rollbackRelease(req, current, history, now)
+		if err == nil {
+			current = rel
+			history = append(history, releaseIndexEntry(rel))
+		}
+		return rel, err
+	}
+
+	// Two rollbacks in a row move 3 → 2 → 1 instead of ping-ponging.
+	for _, want := range []int{2, 1} {
+		rel, err := rollback(RollbackRequest{})
+		if err != nil {
+			t.Fatalf("rollback: %v", err)
+		}
+		if rel.BuildNumber != want {
+			t.Fatalf("rolled back to %d, want %d", rel.BuildNumber, want)
+		}
+	}
+	if _, err := rollback(RollbackRequest{}); err == nil {
+		t.Error("rollback past the first release should fail")
+	}
+
+	// An explicit target may be any build released to the environment.
+	rel, err := rollback(RollbackRequest{TargetBuild: 3})
+	if err != nil || rel.BuildNumber != 3 || rel.Version != "1.0.3" {
+		t.Fatalf("rollback to 3 = %+v, %v", rel, err)
+	}
+
+	// Builds never released here need force and a version.
+	if _, err := rollback(RollbackRequest{TargetBuild: 7}); err == nil {
+		t.Error("unreleased target accepted without force")
+	}
+	if _, err := rollback(RollbackRequest{TargetBuild: 7, Force: true}); err == nil {
+		t.Error("forced target without known version accepted")
+	}
+	if rel, err := rollback(RollbackRequest{TargetBuild: 7, Force: true, Version: "0.9.0"}); err != nil || rel.BuildNumber != 7 {
+		t.Errorf("forced rollback = %+v, %v", rel, err)
+	}
+
+	if _, err := rollback(RollbackRequest{Steps: 5}); err == nil {
+		t.Error("rollback beyond history accepted")
+	}
+}
+
+func TestRollbackSkipsBuilds(t *testing.T) {
+	now := time.Now().UTC()
+	var history []ReleaseIndexEntry
+	var current *Release
+	for _, n := range []int{1, 2, 3} {
+		current = newRelease(PromoteRequest{Project: "app", Environment: "production", BuildNumber: n, Version: fmt.Sprintf("1.0.%d", n)}, current, now)
+		history = append(history, releaseIndexEntry(current))
+	}
+
+	rel, err := rollbackRelease(RollbackRequest{Project: "app", Environment: "production", Skip: []int{2}}, current, history, now)
+	if err != nil || rel.BuildNumber != 1 {
+		t.Fatalf("rollback skipping 2 = %+v, %v; want build 1", rel, err)
+	}
+	if _, err := rollbackRelease(RollbackRequest{Project: "app", Environment: "production", Skip: []int{1, 2}}, current, history, now); err == nil {
+		t.Error("rollback with every earlier build skipped succeeded")
+	}
+
+	if e, ok := PreviousRelease(history, "production", 3, []int{2}); !ok || e.BuildNumber != 1 {
+		t.Errorf("PreviousRelease skipping 2 = %+v, %v; want build 1", e, ok)
+	}
+	if _, ok := PreviousRelease(history, "staging", 3, nil); ok {
+		t.Error("PreviousRelease found a target in an environment without history")
+	}
+}
+
+func TestRolloutRampAndHalt(t *testing.T) {
+	now := time.Now().UTC()
+	first := newRelease(PromoteRequest{Project: "app", Environment: "production", BuildNumber: 1, Version: "1.0.0", RolloutPercent: 10}, nil, now)
+	if first.Rollout != nil {
+		t.Fatal("first release has nothing to stage against and should be live for everyone")
+	}
+
+	rel := newRelease(PromoteRequest{Project: "app", Environment: "production", BuildNumber: 2, Version: "1.1.0", RolloutPercent: 10}, first, now)
+	if rel.Rollout == nil || rel.Rollout.Percent != 10 || rel.PreviousVersion != "1.0.0" {
+		t.Fatalf("rollout = %+v, previous = %q", rel.Rollout, rel.PreviousVersion)
+	}
+	if !rel.Rollout.ServesNew(9) || rel.Rollout.ServesNew(10) {
+		t.Error("10% rollout should serve buckets 0-9 only")
+	}
+
+	if err := applyRollout(RolloutRequest{Project: "app", Environment: "production", Percent: 0}, rel, now); err == nil {
+		t.Error("0% accepted; halting is how a release is served to nobody")
+	}
+	if err := applyRollout(RolloutRequest{Project: "app", Environment: "production", Halt: true}, rel, now); err != nil {
+		t.Fatalf("halt: %v", err)
+	}
+	if rel.Rollout.ServesNew(0) {
+		t.Error("halted rollout still serves the new version")
+	}
+
+	if err := applyRollout(RolloutRequest{Project: "app", Environment: "production", Percent: 100}, rel, now); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if rel.Rollout != nil {
+		t.Error("100% should complete the rollout")
+	}
+	if err := applyRollout(RolloutRequest{Project: "app", Environment: "production", Halt: true}, rel, now); err == nil {
+		t.Error("halting a completed rollout should fail")
+	}
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RetentionPolicy controls which builds of a project survive garbage collection.
+// A build is kept if any rule matches; builds that were ever released to any
+// environment and the newest build of a project are always kept.
+type RetentionPolicy struct {
+	KeepLast int `yaml:"keep_last,omitempty" json:"keep_last,omitempty"` // keep the newest N builds
+	KeepDays int `yaml:"keep_days,omitempty" json:"keep_days,omitempty"` // keep builds uploaded within N days
+}
+
+// IsZero reports whether the policy has no rules, in which case nothing is collected.
+func (p RetentionPolicy) IsZero() bool {
+	return p.KeepLast <= 0 && p.KeepDays <= 0
+}
+
+// RetentionConfig is the YAML-persisted retention configuration.
+type RetentionConfig struct {
+	Default  RetentionPolicy            `yaml:"default,omitempty" json:"default,omitempty"`
+	Projects map[string]RetentionPolicy `yaml:"projects,omitempty" json:"projects,omitempty"`
+}
+
+// RetentionStore reads retention.yaml from the data directory. The file is
+// re-read on every call so edits apply without a restart.
+type RetentionStore struct {
+	dataDir string
+}
+
+// NewRetentionStore creates a new RetentionStore.
+func NewRetentionStore(dataDir string) *RetentionStore {
+	return &RetentionStore{dataDir: dataDir}
+}
+
+func (s *RetentionStore) path() string {
+	return filepath.Join(s.dataDir, "retention.yaml")
+}
+
+// Load returns the retention configuration, or an empty one if the file does not exist.
+func (s *RetentionStore) Load() (*RetentionConfig, error) {
+	data, err := os.ReadFile(s.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &RetentionConfig{}, nil
+		}
+		return nil, fmt.Errorf("reading retention config: %w", err)
+	}
+	var cfg RetentionConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing retention config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// PolicyFor returns the policy for a project: its own entry if present,
+// otherwise the default.
+func (c *RetentionConfig) PolicyFor(project string) RetentionPolicy {
+	if p, ok := c.Projects[project]; ok {
+		return p
+	}
+	return c.Default
+}
+
+// ExpiredBuilds returns the builds that policy no longer retains, oldest first.
+// released holds the build numbers that were ever promoted to any environment.
+func ExpiredBuilds(builds []BuildIndexEntry, released map[int]bool, policy RetentionPolicy, now time.Time) []BuildIndexEntry {
+	if policy.IsZero() || len(builds) == 0 {
+		return nil
+	}
+
+	sorted := make([]BuildIndexEntry, len(builds))
+	copy(sorted, builds)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].BuildNumber > sorted[j].BuildNumber })
+
+	var cutoff time.Time
+	if policy.KeepDays > 0 {
+		cutoff = now.AddDate(0, 0, -policy.KeepDays)
+	}
+
+	var expired []BuildIndexEntry
+	for i, b := range sorted {
+		switch {
+		case i == 0: // newest build
+		case policy.KeepLast > 0 && i < policy.KeepLast:
+		case policy.KeepDays > 0 && b.UploadedAt.After(cutoff):
+		case released[b.BuildNumber]:
+		default:
+			expired = append(expired, b)
+		}
+	}
+
+	sort.Slice(expired, func(i, j int) bool { return expired[i].BuildNumber < expired[j].BuildNumber })
+	return expired
+}
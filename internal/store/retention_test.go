@@ -0,0 +1,44 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiredBuilds(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	var builds []BuildIndexEntry
+	for i := 1; i <= 6; i++ {
+		builds = append(builds, BuildIndexEntry{
+			Project:     "app",
+			BuildNumber: i,
+			UploadedAt:  now.AddDate(0, 0, -10*(7-i)), // build 6 is 10 days old, build 1 is 60
+		})
+	}
+	released := map[int]bool{2: true}
+
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		want   []int
+	}{
+		{"no policy keeps everything", RetentionPolicy{}, nil},
+		{"keep last 2", RetentionPolicy{KeepLast: 2}, []int{1, 3, 4}},
+		{"keep 25 days", RetentionPolicy{KeepDays: 25}, []int{1, 3, 4}},
+		{"either rule keeps", RetentionPolicy{KeepLast: 1, KeepDays: 35}, []int{1, 3}},
+		{"newest always kept", RetentionPolicy{KeepDays: 1}, []int{1, 3, 4, 5}},
+	}
+
+	for _, tt := range tests {
+		got := ExpiredBuilds(builds, released, tt.policy, now)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %d expired builds, want %v", tt.name, len(got), tt.want)
+			continue
+		}
+		for i, b := range got {
+			if b.BuildNumber != tt.want[i] {
+				t.Errorf("%s: expired[%d] = %d, want %d", tt.name, i, b.BuildNumber, tt.want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// BuildStore persists builds and their per-project index.
+type BuildStore interface {
+	// Create registers a new build, assigns a build number, and persists it.
+	Create(p CreateParams) (*Build, error)
+	// Get retrieves a specific build by project and number.
+	Get(project string, number int) (*Build, error)
+	// List returns all builds for a project in upload order.
+	List(project string) ([]BuildIndexEntry, error)
+	// Projects returns the distinct projects that have builds, sorted by name.
+	Projects() ([]string, error)
+	// SetState moves a build to a new lifecycle state. Transitions that are
+	// not allowed return an error wrapping ErrInvalidTransition.
+	SetState(project string, number int, c StateChange) (*Build, error)
+	// Delete removes a build and its index entry.
+	Delete(project string, number int) error
+	// Stats returns total build count and distinct project count.
+	Stats() (buildCount int, projects int, err error)
+}
+
+// ReleaseStore persists the current release per project/environment and
+// the full promotion history.
+type ReleaseStore interface {
+	// Promote promotes a build to an environment.
+	Promote(req PromoteRequest) (*Release, error)
+	// Rollback rolls an environment back to an earlier build.
+	Rollback(req RollbackRequest) (*Release, error)
+	// SetRollout changes the staged rollout of the current release.
+	SetRollout(req RolloutRequest) (*Release, error)
+	// Get returns the current release for a project/environment.
+	Get(project, env string) (*Release, error)
+	// List returns all release history for a project in promotion order.
+	List(project string) ([]ReleaseIndexEntry, error)
+	// ListCurrentReleases returns the current release for every project/environment.
+	ListCurrentReleases() ([]Release, error)
+	// Stats returns the total number of release promotions.
+	Stats() (releaseCount int, err error)
+}
+
+// Storage backends accepted by Open.
+const (
+	BackendYAML = "yaml"
+	BackendDB   = "db"
+)
+
+// DBFileName is the name of the embedded database file inside the data directory.
+const DBFileName = "hydrarelease.db"
+
+// Stores bundles the build and release stores of one backend.
+type Stores struct {
+	Builds   BuildStore
+	Releases ReleaseStore
+
+	db *DB
+}
+
+// Open opens the stores for the given backend rooted at dataDir.
+// The YAML backend runs the channel migration before returning.
+func Open(backend, dataDir string) (*Stores, error) {
+	switch backend {
+	case BackendYAML, "":
+		releases := NewYAMLReleaseStore(dataDir)
+		if err := releases.Migrate(); err != nil {
+			log.Printf("Warning: channel migration failed: %v", err)
+		}
+		return &Stores{
+			Builds:   NewYAMLBuildStore(dataDir),
+			Releases: releases,
+		}, nil
+	case BackendDB:
+		if err := os.MkdirAll(dataDir, 0755); err != nil {
+			return nil, fmt.Errorf("creating data directory: %w", err)
+		}
+		db, err := OpenDB(filepath.Join(dataDir, DBFileName))
+		if err != nil {
+			return nil, err
+		}
+		return &Stores{
+			Builds:   NewDBBuildStore(db),
+			Releases: NewDBReleaseStore(db),
+			db:       db,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown store backend %q (must be %s or %s)", backend, BackendYAML, BackendDB)
+	}
+}
+
+// Close releases any resources held by the backend.
+func (s *Stores) Close() error {
+	if s.db != nil {
+		return s.db.Close()
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package store
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Actions a scoped API token can be granted.
+const (
+	ActionBuildSubmit = "build.submit"     // create builds, upload artifacts, mark pending builds complete or failed
+	ActionBuildYank   = "build.yank"       // yank builds and undo yanks
+	ActionPromote     = "release.promote"  // promote, publish and change rollouts
+	ActionRollback    = "release.rollback" // roll an environment back
+	ActionEventsRead  = "events.read"      // stream SSE events (needs project "*")
+)
+
+// ValidActions lists every action a scope may grant.
+var ValidActions = []string{ActionBuildSubmit, ActionBuildYank, ActionPromote, ActionRollback, ActionEventsRead}
+
+// TokenPrefix starts every scoped API token so they are easy to recognise
+// in logs and secret scanners.
+const TokenPrefix = "hrt_"
+
+// TokenScope grants actions on one project ("*" for all), optionally limited
+// to some environments.
+type TokenScope struct {
+	Project      string   `yaml:"project" json:"project"`
+	Environments []string `yaml:"environments,omitempty" json:"environments,omitempty"` // empty = all
+	Actions      []string `yaml:"actions" json:"actions"`
+}
+
+func (sc TokenScope) allows(action, project, env string) bool {
+	if sc.Project != "*" && project != "" && sc.Project != project {
+		return false
+	}
+	if project == "*" && sc.Project != "*" {
+		return false
+	}
+	if env != "" && len(sc.Environments) > 0 && !slices.Contains(sc.Environments, env) {
+		return false
+	}
+	return slices.Contains(sc.Actions, action)
+}
+
+// Validate checks that the scope names a project and known actions.
+func (sc TokenScope) Validate() error {
+	if sc.Project == "" {
+		return fmt.Errorf("scope needs a project (or \"*\")")
+	}
+	if len(sc.Actions) == 0 {
+		return fmt.Errorf("scope for %s grants no actions", sc.Project)
+	}
+	for _, a := range sc.Actions {
+		if !slices.Contains(ValidActions, a) {
+			return fmt.Errorf("unknown action %q (valid: %v)", a, ValidActions)
+		}
+	}
+	return nil
+}
+
+// APIToken is a server-side record of a scoped bearer token. Only the
+// SHA256 of the token is stored.
+type APIToken struct {
+	ID        string       `yaml:"id" json:"id"`
+	Name      string       `yaml:"name" json:"name"`
+	Hash      string       `yaml:"hash" json:"-"`
+	Scopes    []TokenScope `yaml:"scopes" json:"scopes"`
+	CreatedBy string       `yaml:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt time.Time    `yaml:"created_at" json:"created_at"`
+	ExpiresAt *time.Time   `yaml:"expires_at,omitempty" json:"expires_at,omitempty"`
+	RevokedAt *time.Time   `yaml:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+// Allows reports whether any scope grants action on project and env.
+// An empty project matches any scope; "*" only matches all-project scopes.
+func (t *APIToken) Allows(action, project, env string) bool {
+	for _, sc := range t.Scopes {
+		if sc.allows(action, project, env) {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the token is neither revoked nor expired at now.
+func (t *APIToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// TokenStore manages scoped API tokens with YAML persistence in tokens.yaml.
+type TokenStore struct {
+	mu      sync.Mutex
+	dataDir string
+}
+
+// NewTokenStore creates a new TokenStore.
+func NewTokenStore(dataDir string) *TokenStore {
+	return &TokenStore{dataDir: dataDir}
+}
+
+func (s *TokenStore) path() string {
+	return filepath.Join(s.dataDir, "tokens.yaml")
+}
+
+func (s *TokenStore) load() ([]*APIToken, error) {
+	data, err := os.ReadFile(s.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading tokens: %w", err)
+	}
+	var file struct {
+		Tokens []*APIToken `yaml:"tokens"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing tokens: %w", err)
+	}
+	return file.Tokens, nil
+}
+
+func (s *TokenStore) save(tokens []*APIToken) error {
+	file := struct {
+		Tokens []*APIToken `yaml:"tokens"`
+	}{Tokens: tokens}
+
+	data, err := yaml.Marshal(&file)
+	if err != nil {
+		return fmt.Errorf("marshaling tokens: %w", err)
+	}
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+	return atomicWriteFile(s.path(), data, 0600)
+}
+
+// Create issues a new token and returns its plaintext value, which is not
+// stored and cannot be recovered later.
+func (s *TokenStore) Create(name string, scopes []TokenScope, createdBy string, ttl time.Duration) (string, *APIToken, error) {
+	if name == "" {
+		return "", nil, fmt.Errorf("token name is required")
+	}
+	if len(scopes) == 0 {
+		return "", nil, fmt.Errorf("token needs at least one scope")
+	}
+	for _, sc := range scopes {
+		if err := sc.Validate(); err != nil {
+			return "", nil, err
+		}
+	}
+
+	secret := make([]byte, 32+6)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	plaintext := TokenPrefix + hex.EncodeToString(secret[:32])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.load()
+	if err != nil {
+		return "", nil, err
+	}
+	for _, t := range tokens {
+		if t.Name == name && t.RevokedAt == nil {
+			return "", nil, fmt.Errorf("an active token named %s already exists", name)
+		}
+	}
+
+	now := time.Now().UTC()
+	t := &APIToken{
+		ID:        hex.EncodeToString(secret[32:]),
+		Name:      name,
+		Hash:      hashToken(plaintext),
+		Scopes:    scopes,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+	}
+	if ttl > 0 {
+		expires := now.Add(ttl)
+		t.ExpiresAt = &expires
+	}
+	tokens = append(tokens, t)
+	if err := s.save(tokens); err != nil {
+		return "", nil, err
+	}
+	return plaintext, t, nil
+}
+
+// List returns all tokens, including revoked and expired ones, newest first.
+func (s *TokenStore) List() ([]APIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]APIToken, 0, len(tokens))
+	for _, t := range tokens {
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	return result, nil
+}
+
+// Revoke marks a token as revoked. Revoked tokens stay listed for auditing.
+func (s *TokenStore) Revoke(id string) (*APIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tokens {
+		if t.ID != id {
+			continue
+		}
+		if t.RevokedAt == nil {
+			now := time.Now().UTC()
+			t.RevokedAt = &now
+			if err := s.save(tokens); err != nil {
+				return nil, err
+			}
+		}
+		return t, nil
+	}
+	return nil, fmt.Errorf("no token with id %s", id)
+}
+
+// Authenticate returns the active token matching plaintext, or nil.
+func (s *TokenStore) Authenticate(plaintext string) (*APIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	hash := hashToken(plaintext)
+	now := time.Now()
+	for _, t := range tokens {
+		if t.Hash == hash && t.Active(now) {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
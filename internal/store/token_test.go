@@ -0,0 +1,102 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenStoreLifecycle(t *testing.T) {
+	s := NewTokenStore(t.TempDir())
+
+	scopes := []TokenScope{{Project: "app", Environments: []string{"staging"}, Actions: []string{ActionBuildSubmit, ActionPromote}}}
+	plaintext, tok, err := s.Create("ci-app", scopes, "ops", 0)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !strings.HasPrefix(plaintext, TokenPrefix) || tok.Hash == plaintext {
+		t.Fatalf("unexpected token %q / hash %q", plaintext, tok.Hash)
+	}
+	if _, _, err := s.Create("ci-app", scopes, "ops", 0); err == nil {
+		t.Error("Create of a duplicate active name succeeded")
+	}
+	if _, _, err := s.Create("bad", []TokenScope{{Project: "app", Actions: []string{"delete.everything"}}}, "", 0); err == nil {
+		t.Error("Create with an unknown action succeeded")
+	}
+
+	got, err := s.Authenticate(plaintext)
+	if err != nil || got == nil || got.ID != tok.ID {
+		t.Fatalf("Authenticate = %v, %v; want %s", got, err, tok.ID)
+	}
+	if got, _ := s.Authenticate(TokenPrefix + "nope"); got != nil {
+		t.Error("Authenticate accepted an unknown token")
+	}
+
+	if _, err := s.Revoke(tok.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if got, _ := s.Authenticate(plaintext); got != nil {
+		t.Error("Authenticate accepted a revoked token")
+	}
+	if _, err := s.Revoke("missing"); err == nil {
+		t.Error("Revoke of an unknown id succeeded")
+	}
+
+	// The name is free again once the old token is revoked.
+	if _, _, err := s.Create("ci-app", scopes, "ops", 0); err != nil {
+		t.Errorf("Create after revoke: %v", err)
+	}
+	list, err := s.List()
+	if err != nil || len(list) != 2 {
+		t.Fatalf("List = %d tokens, %v; want 2", len(list), err)
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	s := NewTokenStore(t.TempDir())
+
+	plaintext, tok, err := s.Create("short", []TokenScope{{Project: "*", Actions: []string{ActionEventsRead}}}, "", time.Hour)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if tok.ExpiresAt == nil || !tok.Active(time.Now()) {
+		t.Fatalf("new token should be active until %v", tok.ExpiresAt)
+	}
+	if tok.Active(time.Now().Add(2 * time.Hour)) {
+		t.Error("token still active after expiry")
+	}
+	if got, _ := s.Authenticate(plaintext); got == nil {
+		t.Error("Authenticate rejected an unexpired token")
+	}
+}
+
+func TestTokenAllows(t *testing.T) {
+	tok := &APIToken{Scopes: []TokenScope{
+		{Project: "app", Environments: []string{"dev", "staging"}, Actions: []string{ActionPromote}},
+		{Project: "*", Actions: []string{ActionBuildSubmit}},
+	}}
+
+	tests := []struct {
+		action, project, env string
+		want                 bool
+	}{
+		{ActionPromote, "app", "staging", true},
+		{ActionPromote, "app", "production", false},
+		{ActionPromote, "other", "staging", false},
+		{ActionRollback, "app", "staging", false},
+		{ActionBuildSubmit, "other", "", true},
+		{ActionBuildSubmit, "", "", true},
+		{ActionPromote, "", "", true},
+		{ActionEventsRead, "*", "", false},
+	}
+	for _, tt := range tests {
+		if got := tok.Allows(tt.action, tt.project, tt.env); got != tt.want {
+			t.Errorf("Allows(%s, %q, %q) = %v, want %v", tt.action, tt.project, tt.env, got, tt.want)
+		}
+	}
+
+	events := &APIToken{Scopes: []TokenScope{{Project: "app", Actions: []string{ActionEventsRead}}}}
+	if events.Allows(ActionEventsRead, "*", "") {
+		t.Error("a single-project scope must not stream events for all projects")
+	}
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxUpdateReports caps how many reports are kept; the oldest are dropped first.
+const maxUpdateReports = 1000
+
+// UpdateReport is a client's report that an update was reverted on a machine.
+type UpdateReport struct {
+	Project     string    `yaml:"project" json:"project"`
+	Channel     string    `yaml:"channel" json:"channel"`
+	FromVersion string    `yaml:"from_version" json:"from_version"`
+	ToVersion   string    `yaml:"to_version" json:"to_version"`
+	ClientID    string    `yaml:"client_id,omitempty" json:"client_id,omitempty"`
+	Status      string    `yaml:"status" json:"status"`
+	Reason      string    `yaml:"reason,omitempty" json:"reason,omitempty"`
+	OS          string    `yaml:"os,omitempty" json:"os,omitempty"`
+	Arch        string    `yaml:"arch,omitempty" json:"arch,omitempty"`
+	InstalledAt time.Time `yaml:"installed_at" json:"installed_at"`
+	ReportedAt  time.Time `yaml:"reported_at" json:"reported_at"`
+}
+
+// UpdateReportStore keeps the most recent update reports in update_reports.yaml.
+type UpdateReportStore struct {
+	mu      sync.Mutex
+	dataDir string
+}
+
+// NewUpdateReportStore creates a new UpdateReportStore.
+func NewUpdateReportStore(dataDir string) *UpdateReportStore {
+	return &UpdateReportStore{dataDir: dataDir}
+}
+
+func (s *UpdateReportStore) path() string {
+	return filepath.Join(s.dataDir, "update_reports.yaml")
+}
+
+func (s *UpdateReportStore) load() ([]UpdateReport, error) {
+	data, err := os.ReadFile(s.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading update reports: %w", err)
+	}
+	var file struct {
+		Reports []UpdateReport `yaml:"reports"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing update reports: %w", err)
+	}
+	return file.Reports, nil
+}
+
+// Add records a report, stamping ReportedAt.
+func (s *UpdateReportStore) Add(r *UpdateReport) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reports, err := s.load()
+	if err != nil {
+		return err
+	}
+	r.ReportedAt = time.Now().UTC()
+	reports = append(reports, *r)
+	if len(reports) > maxUpdateReports {
+		reports = reports[len(reports)-maxUpdateReports:]
+	}
+
+	file := struct {
+		Reports []UpdateReport `yaml:"reports"`
+	}{reports}
+	data, err := yaml.Marshal(&file)
+	if err != nil {
+		return fmt.Errorf("marshaling update reports: %w", err)
+	}
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+	return atomicWriteFile(s.path(), data, 0644)
+}
+
+// List returns reports for project (all projects if empty), optionally
+// limited to one target version, oldest first.
+func (s *UpdateReportStore) List(project, version string) ([]UpdateReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reports, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	result := []UpdateReport{}
+	for _, r := range reports {
+		if project != "" && r.Project != project {
+			continue
+		}
+		if version != "" && r.ToVersion != version {
+			continue
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
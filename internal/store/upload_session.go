@@ -0,0 +1,200 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UploadSession tracks the binaries uploaded for a legacy publish until it
+// is finalized, aborted or expires.
+type UploadSession struct {
+	Project   string            `yaml:"project" json:"project"`
+	Channel   string            `yaml:"channel" json:"channel"`
+	Version   string            `yaml:"version" json:"version"`
+	Files     map[string]string `yaml:"files" json:"files"`                     // file name → sha256
+	Sizes     map[string]int64  `yaml:"sizes,omitempty" json:"sizes,omitempty"` // file name → size in bytes
+	CreatedAt time.Time         `yaml:"created_at" json:"created_at"`
+	UpdatedAt time.Time         `yaml:"updated_at" json:"updated_at"`
+	ExpiresAt time.Time         `yaml:"expires_at" json:"expires_at"`
+}
+
+// Key identifies the session as "project/channel/version".
+func (u *UploadSession) Key() string {
+	return UploadSessionKey(u.Project, u.Channel, u.Version)
+}
+
+// UploadSessionKey builds the key of a session.
+func UploadSessionKey(project, channel, version string) string {
+	return project + "/" + channel + "/" + version
+}
+
+// UploadSessionStore persists upload sessions in upload_sessions.yaml so a
+// restart between upload and finalize loses nothing.
+type UploadSessionStore struct {
+	mu      sync.Mutex
+	dataDir string
+}
+
+// NewUploadSessionStore creates a new UploadSessionStore.
+func NewUploadSessionStore(dataDir string) *UploadSessionStore {
+	return &UploadSessionStore{dataDir: dataDir}
+}
+
+func (s *UploadSessionStore) path() string {
+	return filepath.Join(s.dataDir, "upload_sessions.yaml")
+}
+
+func (s *UploadSessionStore) load() (map[string]*UploadSession, error) {
+	data, err := os.ReadFile(s.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string]*UploadSession), nil
+		}
+		return nil, fmt.Errorf("reading upload sessions: %w", err)
+	}
+	var file struct {
+		Sessions []*UploadSession `yaml:"sessions"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing upload sessions: %w", err)
+	}
+	result := make(map[string]*UploadSession, len(file.Sessions))
+	for _, u := range file.Sessions {
+		result[u.Key()] = u
+	}
+	return result, nil
+}
+
+func (s *UploadSessionStore) save(sessions map[string]*UploadSession) error {
+	var file struct {
+		Sessions []*UploadSession `yaml:"sessions"`
+	}
+	for _, u := range sessions {
+		file.Sessions = append(file.Sessions, u)
+	}
+	sort.Slice(file.Sessions, func(i, j int) bool { return file.Sessions[i].Key() < file.Sessions[j].Key() })
+
+	data, err := yaml.Marshal(&file)
+	if err != nil {
+		return fmt.Errorf("marshaling upload sessions: %w", err)
+	}
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+	return atomicWriteFile(s.path(), data, 0644)
+}
+
+// AddFile records an uploaded file, creating the session if needed and
+// extending its expiry to now+ttl.
+func (s *UploadSessionStore) AddFile(project, channel, version, name, sha256 string, size int64, ttl time.Duration) (*UploadSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	key := UploadSessionKey(project, channel, version)
+	u := sessions[key]
+	if u == nil || now.After(u.ExpiresAt) {
+		u = &UploadSession{
+			Project:   project,
+			Channel:   channel,
+			Version:   version,
+			Files:     make(map[string]string),
+			CreatedAt: now,
+		}
+		sessions[key] = u
+	}
+	if u.Sizes == nil {
+		u.Sizes = make(map[string]int64)
+	}
+	u.Files[name] = sha256
+	u.Sizes[name] = size
+	u.UpdatedAt = now
+	u.ExpiresAt = now.Add(ttl)
+
+	if err := s.save(sessions); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Get returns an unexpired session, or nil if there is none.
+func (s *UploadSessionStore) Get(project, channel, version string) (*UploadSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	u := sessions[UploadSessionKey(project, channel, version)]
+	if u == nil || time.Now().After(u.ExpiresAt) {
+		return nil, nil
+	}
+	return u, nil
+}
+
+// List returns all sessions, including expired ones not yet collected.
+func (s *UploadSessionStore) List() ([]UploadSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]UploadSession, 0, len(sessions))
+	for _, u := range sessions {
+		result = append(result, *u)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result, nil
+}
+
+// Delete removes a session.
+func (s *UploadSessionStore) Delete(project, channel, version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.load()
+	if err != nil {
+		return err
+	}
+	key := UploadSessionKey(project, channel, version)
+	if _, ok := sessions[key]; !ok {
+		return fmt.Errorf("no upload session for %s", key)
+	}
+	delete(sessions, key)
+	return s.save(sessions)
+}
+
+// Expire removes sessions that expired before now and returns them.
+func (s *UploadSessionStore) Expire(now time.Time) ([]UploadSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	var expired []UploadSession
+	for key, u := range sessions {
+		if now.After(u.ExpiresAt) {
+			expired = append(expired, *u)
+			delete(sessions, key)
+		}
+	}
+	if len(expired) == 0 {
+		return nil, nil
+	}
+	return expired, s.save(sessions)
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUploadSessionSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewUploadSessionStore(dir).AddFile("app", "production", "1.0.0", "app-linux-amd64", "aa", 10, time.Hour); err != nil {
+		t.Fatalf("AddFile: %v", err)
+	}
+
+	// A second upload after a restart joins the same session.
+	s := NewUploadSessionStore(dir)
+	if _, err := s.AddFile("app", "production", "1.0.0", "app-darwin-arm64", "bb", 20, time.Hour); err != nil {
+		t.Fatalf("AddFile after reopen: %v", err)
+	}
+	u, err := NewUploadSessionStore(dir).Get("app", "production", "1.0.0")
+	if err != nil || u == nil {
+		t.Fatalf("Get = %+v, %v", u, err)
+	}
+	if len(u.Files) != 2 || u.Files["app-linux-amd64"] != "aa" || u.Sizes["app-darwin-arm64"] != 20 {
+		t.Errorf("session = %+v, want both files", u)
+	}
+}
+
+func TestUploadSessionExpiry(t *testing.T) {
+	s := NewUploadSessionStore(t.TempDir())
+	first, err := s.AddFile("app", "production", "1.0.0", "a", "aa", 1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.AddFile("app", "production", "1.0.0", "b", "bb", 1, 2*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !second.ExpiresAt.After(first.ExpiresAt) || !second.CreatedAt.Equal(first.CreatedAt) {
+		t.Errorf("second upload did not extend the session: %+v then %+v", first, second)
+	}
+
+	// An expired session is invisible and replaced by the next upload.
+	if _, err := s.AddFile("app", "staging", "1.0.0", "a", "aa", 1, -time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := s.Get("app", "staging", "1.0.0"); u != nil {
+		t.Errorf("Get returned expired session %+v", u)
+	}
+	u, err := s.AddFile("app", "staging", "1.0.0", "b", "bb", 1, time.Hour)
+	if err != nil || len(u.Files) != 1 {
+		t.Errorf("upload after expiry = %+v, %v; want a fresh session", u, err)
+	}
+
+	if _, err := s.AddFile("app", "staging", "2.0.0", "a", "aa", 1, -time.Second); err != nil {
+		t.Fatal(err)
+	}
+	expired, err := s.Expire(time.Now())
+	if err != nil || len(expired) != 1 || expired[0].Version != "2.0.0" {
+		t.Errorf("Expire = %+v, %v; want only 2.0.0", expired, err)
+	}
+	if list, _ := s.List(); len(list) != 2 {
+		t.Errorf("List after Expire = %+v, want 2 live sessions", list)
+	}
+}
+
+func TestUploadSessionDelete(t *testing.T) {
+	s := NewUploadSessionStore(t.TempDir())
+	if _, err := s.AddFile("app", "production", "1.0.0", "a", "aa", 1, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("app", "production", "1.0.0"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if u, _ := s.Get("app", "production", "1.0.0"); u != nil {
+		t.Errorf("Get after Delete = %+v", u)
+	}
+	if err := s.Delete("app", "production", "1.0.0"); err == nil {
+		t.Error("deleting a missing session succeeded")
+	}
+}
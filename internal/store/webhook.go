@@ -0,0 +1,342 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Webhook delivery states.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed" // gave up after the last retry
+)
+
+// maxFinishedDeliveries caps the delivered and failed deliveries kept per
+// webhook; the oldest are dropped first. Pending ones are never dropped.
+const maxFinishedDeliveries = 200
+
+// Webhook is a subscription that receives events as signed JSON POSTs.
+type Webhook struct {
+	ID        string    `yaml:"id" json:"id"`
+	URL       string    `yaml:"url" json:"url"`
+	Secret    string    `yaml:"secret" json:"-"`                              // HMAC-SHA256 key for X-Hydrarelease-Signature
+	Projects  []string  `yaml:"projects,omitempty" json:"projects,omitempty"` // empty = all
+	Events    []string  `yaml:"events,omitempty" json:"events,omitempty"`     // globs such as "release.*"; empty = all
+	CreatedBy string    `yaml:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt time.Time `yaml:"created_at" json:"created_at"`
+}
+
+// Matches reports whether the webhook subscribes to an event of the given
+// type for project. Events without a project only match unfiltered hooks.
+func (h *Webhook) Matches(eventType, project string) bool {
+	if len(h.Projects) > 0 && !slices.Contains(h.Projects, project) {
+		return false
+	}
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, pattern := range h.Events {
+		if ok, _ := path.Match(pattern, eventType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event queued for one webhook. Payload holds the
+// exact bytes that are signed and sent on every attempt.
+type WebhookDelivery struct {
+	ID          string     `yaml:"id" json:"id"`
+	WebhookID   string     `yaml:"webhook_id" json:"webhook_id"`
+	Event       string     `yaml:"event" json:"event"`
+	Payload     string     `yaml:"payload" json:"payload"`
+	Status      string     `yaml:"status" json:"status"`
+	Attempts    int        `yaml:"attempts" json:"attempts"`
+	NextAttempt time.Time  `yaml:"next_attempt,omitempty" json:"next_attempt,omitzero"`
+	LastStatus  int        `yaml:"last_status,omitempty" json:"last_status,omitempty"` // HTTP status of the last attempt
+	LastError   string     `yaml:"last_error,omitempty" json:"last_error,omitempty"`
+	CreatedAt   time.Time  `yaml:"created_at" json:"created_at"`
+	DeliveredAt *time.Time `yaml:"delivered_at,omitempty" json:"delivered_at,omitempty"`
+}
+
+// WebhookStore persists webhooks in webhooks.yaml and their delivery queue
+// in webhook_deliveries.yaml, so pending deliveries survive a restart.
+type WebhookStore struct {
+	mu      sync.Mutex
+	dataDir string
+}
+
+// NewWebhookStore creates a new WebhookStore.
+func NewWebhookStore(dataDir string) *WebhookStore {
+	return &WebhookStore{dataDir: dataDir}
+}
+
+func (s *WebhookStore) hooksPath() string {
+	return filepath.Join(s.dataDir, "webhooks.yaml")
+}
+
+func (s *WebhookStore) deliveriesPath() string {
+	return filepath.Join(s.dataDir, "webhook_deliveries.yaml")
+}
+
+func (s *WebhookStore) loadHooks() ([]*Webhook, error) {
+	data, err := os.ReadFile(s.hooksPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading webhooks: %w", err)
+	}
+	var file struct {
+		Webhooks []*Webhook `yaml:"webhooks"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing webhooks: %w", err)
+	}
+	return file.Webhooks, nil
+}
+
+func (s *WebhookStore) saveHooks(hooks []*Webhook) error {
+	file := struct {
+		Webhooks []*Webhook `yaml:"webhooks"`
+	}{Webhooks: hooks}
+
+	data, err := yaml.Marshal(&file)
+	if err != nil {
+		return fmt.Errorf("marshaling webhooks: %w", err)
+	}
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+	return atomicWriteFile(s.hooksPath(), data, 0600)
+}
+
+func (s *WebhookStore) loadDeliveries() ([]*WebhookDelivery, error) {
+	data, err := os.ReadFile(s.deliveriesPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading webhook deliveries: %w", err)
+	}
+	var file struct {
+		Deliveries []*WebhookDelivery `yaml:"deliveries"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing webhook deliveries: %w", err)
+	}
+	return file.Deliveries, nil
+}
+
+func (s *WebhookStore) saveDeliveries(deliveries []*WebhookDelivery) error {
+	// Keep every pending delivery and the newest finished ones per webhook.
+	finished := make(map[string]int)
+	kept := make([]*WebhookDelivery, 0, len(deliveries))
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		d := deliveries[i]
+		if d.Status != DeliveryPending {
+			finished[d.WebhookID]++
+			if finished[d.WebhookID] > maxFinishedDeliveries {
+				continue
+			}
+		}
+		kept = append(kept, d)
+	}
+	slices.Reverse(kept)
+
+	file := struct {
+		Deliveries []*WebhookDelivery `yaml:"deliveries"`
+	}{Deliveries: kept}
+
+	data, err := yaml.Marshal(&file)
+	if err != nil {
+		return fmt.Errorf("marshaling webhook deliveries: %w", err)
+	}
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+	return atomicWriteFile(s.deliveriesPath(), data, 0600)
+}
+
+func randomID(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Create registers a webhook, assigning its ID and, if none was given, a
+// random secret.
+func (s *WebhookStore) Create(h *Webhook) error {
+	id, err := randomID(6)
+	if err != nil {
+		return err
+	}
+	if h.Secret == "" {
+		if h.Secret, err = randomID(32); err != nil {
+			return err
+		}
+	}
+	h.ID = id
+	h.CreatedAt = time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hooks, err := s.loadHooks()
+	if err != nil {
+		return err
+	}
+	return s.saveHooks(append(hooks, h))
+}
+
+// List returns all webhooks in creation order.
+func (s *WebhookStore) List() ([]Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hooks, err := s.loadHooks()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Webhook, 0, len(hooks))
+	for _, h := range hooks {
+		result = append(result, *h)
+	}
+	return result, nil
+}
+
+// Get returns a webhook, or nil if it does not exist.
+func (s *WebhookStore) Get(id string) (*Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hooks, err := s.loadHooks()
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range hooks {
+		if h.ID == id {
+			return h, nil
+		}
+	}
+	return nil, nil
+}
+
+// Delete removes a webhook and drops its pending deliveries.
+func (s *WebhookStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hooks, err := s.loadHooks()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(hooks, func(h *Webhook) bool { return h.ID == id })
+	if i < 0 {
+		return fmt.Errorf("no webhook with id %s", id)
+	}
+	if err := s.saveHooks(slices.Delete(hooks, i, i+1)); err != nil {
+		return err
+	}
+
+	deliveries, err := s.loadDeliveries()
+	if err != nil {
+		return err
+	}
+	deliveries = slices.DeleteFunc(deliveries, func(d *WebhookDelivery) bool { return d.WebhookID == id })
+	return s.saveDeliveries(deliveries)
+}
+
+// Enqueue adds pending deliveries, due immediately.
+func (s *WebhookStore) Enqueue(deliveries []*WebhookDelivery) error {
+	now := time.Now().UTC()
+	for _, d := range deliveries {
+		id, err := randomID(8)
+		if err != nil {
+			return err
+		}
+		d.ID = id
+		d.Status = DeliveryPending
+		d.CreatedAt = now
+		d.NextAttempt = now
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, err := s.loadDeliveries()
+	if err != nil {
+		return err
+	}
+	return s.saveDeliveries(append(existing, deliveries...))
+}
+
+// Due returns pending deliveries whose next attempt is at or before now,
+// oldest first.
+func (s *WebhookStore) Due(now time.Time) ([]WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deliveries, err := s.loadDeliveries()
+	if err != nil {
+		return nil, err
+	}
+	var due []WebhookDelivery
+	for _, d := range deliveries {
+		if d.Status == DeliveryPending && !d.NextAttempt.After(now) {
+			due = append(due, *d)
+		}
+	}
+	return due, nil
+}
+
+// UpdateDelivery stores the outcome of a delivery attempt. Deliveries whose
+// webhook was deleted in the meantime are ignored.
+func (s *WebhookStore) UpdateDelivery(d *WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deliveries, err := s.loadDeliveries()
+	if err != nil {
+		return err
+	}
+	for i, existing := range deliveries {
+		if existing.ID == d.ID {
+			deliveries[i] = d
+			return s.saveDeliveries(deliveries)
+		}
+	}
+	return nil
+}
+
+// Deliveries returns the deliveries of a webhook, newest first.
+func (s *WebhookStore) Deliveries(webhookID string) ([]WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deliveries, err := s.loadDeliveries()
+	if err != nil {
+		return nil, err
+	}
+	result := []WebhookDelivery{}
+	for _, d := range deliveries {
+		if d.WebhookID == webhookID {
+			result = append(result, *d)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	return result, nil
+}
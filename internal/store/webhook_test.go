@@ -0,0 +1,85 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWebhookMatches(t *testing.T) {
+	h := &Webhook{Projects: []string{"app"}, Events: []string{"release.*", "build.uploaded"}}
+	tests := []struct {
+		event, project string
+		want           bool
+	}{
+		{"release.promoted", "app", true},
+		{"release.rolled-back", "app", true},
+		{"build.uploaded", "app", true},
+		{"build.deleted", "app", false},
+		{"release.promoted", "other", false},
+		{"token.created", "", false},
+	}
+	for _, tt := range tests {
+		if got := h.Matches(tt.event, tt.project); got != tt.want {
+			t.Errorf("Matches(%s, %q) = %v, want %v", tt.event, tt.project, got, tt.want)
+		}
+	}
+	if !(&Webhook{}).Matches("token.created", "") {
+		t.Error("an unfiltered webhook should match every event")
+	}
+}
+
+func TestWebhookQueue(t *testing.T) {
+	s := NewWebhookStore(t.TempDir())
+
+	h := &Webhook{URL: "https://example.com/hook"}
+	if err := s.Create(h); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if h.ID == "" || h.Secret == "" {
+		t.Fatalf("Create did not assign id and secret: %+v", h)
+	}
+
+	if err := s.Enqueue([]*WebhookDelivery{
+		{WebhookID: h.ID, Event: "build.uploaded", Payload: `{"n":1}`},
+		{WebhookID: h.ID, Event: "release.promoted", Payload: `{"n":2}`},
+	}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	due, err := s.Due(time.Now())
+	if err != nil || len(due) != 2 {
+		t.Fatalf("Due = %d, %v; want 2", len(due), err)
+	}
+
+	// First delivery succeeds, second is retried later.
+	now := time.Now().UTC()
+	due[0].Status, due[0].Attempts, due[0].DeliveredAt = DeliveryDelivered, 1, &now
+	due[1].Attempts, due[1].LastError, due[1].NextAttempt = 1, "connection refused", now.Add(time.Minute)
+	for i := range due {
+		if err := s.UpdateDelivery(&due[i]); err != nil {
+			t.Fatalf("UpdateDelivery: %v", err)
+		}
+	}
+
+	if due, _ := s.Due(time.Now()); len(due) != 0 {
+		t.Errorf("Due right after a retry was scheduled = %d, want 0", len(due))
+	}
+	if due, _ := s.Due(time.Now().Add(2 * time.Minute)); len(due) != 1 || due[0].Event != "release.promoted" {
+		t.Errorf("Due after backoff = %+v", due)
+	}
+
+	log, err := s.Deliveries(h.ID)
+	if err != nil || len(log) != 2 {
+		t.Fatalf("Deliveries = %d, %v", len(log), err)
+	}
+
+	if err := s.Delete(h.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if due, _ := s.Due(time.Now().Add(time.Hour)); len(due) != 0 {
+		t.Errorf("deleting a webhook should drop its pending deliveries, got %d", len(due))
+	}
+	if err := s.Delete(h.ID); err == nil {
+		t.Error("Delete of an unknown webhook succeeded")
+	}
+}
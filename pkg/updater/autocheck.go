@@ -0,0 +1,237 @@
+package updater
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Defaults for AutoCheckOptions.
+const (
+	defaultInterval   = time.Hour
+	defaultJitter     = 0.1
+	defaultRetryDelay = time.Minute
+)
+
+// AutoCheckHooks are called from the auto-check goroutine. Any may be nil.
+// Hooks must not block for long; the next check waits for them.
+type AutoCheckHooks struct {
+	// OnUpdateAvailable is called when a check finds a newer (or, with
+	// downgrades allowed, a rolled-back) version.
+	OnUpdateAvailable func(info *UpdateInfo)
+	// OnDownloadStarted is called before an update is downloaded and installed.
+	OnDownloadStarted func(info *UpdateInfo)
+	// OnApplied is called after an update was installed.
+	OnApplied func(info *UpdateInfo)
+	// OnFailed is called when a check or an update fails. info is nil for
+	// failed checks.
+	OnFailed func(info *UpdateInfo, err error)
+}
+
+// AutoCheckOptions configures StartAutoCheckContext.
+type AutoCheckOptions struct {
+	// Interval between successful checks. Default one hour.
+	Interval time.Duration
+	// AutoApply installs available updates instead of only reporting them.
+	AutoApply bool
+	// Jitter randomizes each delay by up to this fraction of itself, so a
+	// fleet started together does not check in lockstep. Default 0.1;
+	// negative disables it.
+	Jitter float64
+	// RetryDelay is the delay after the first failed check. It doubles with
+	// each consecutive failure, capped at Interval. Default one minute.
+	RetryDelay time.Duration
+	Hooks      AutoCheckHooks
+}
+
+// AutoCheckStatus is a snapshot of the auto-check loop, suitable for a
+// host application's health endpoint.
+type AutoCheckStatus struct {
+	LastCheck           time.Time   `json:"last_check,omitempty"`
+	LastError           string      `json:"last_error,omitempty"`
+	LastInfo            *UpdateInfo `json:"last_info,omitempty"`
+	ConsecutiveFailures int         `json:"consecutive_failures"`
+	NextCheck           time.Time   `json:"next_check"`
+	Running             bool        `json:"running"`
+}
+
+// AutoCheck is a handle to a running auto-check loop.
+type AutoCheck struct {
+	cancel  context.CancelFunc
+	done    chan struct{}
+	trigger chan struct{}
+
+	mu     sync.Mutex
+	status AutoCheckStatus
+}
+
+// StartAutoCheckContext starts checking for updates in the background until
+// ctx is cancelled or Stop is called.
+func (u *Updater) StartAutoCheckContext(ctx context.Context, opts AutoCheckOptions) *AutoCheck {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultInterval
+	}
+	if opts.Jitter == 0 {
+		opts.Jitter = defaultJitter
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = defaultRetryDelay
+	}
+	if opts.RetryDelay > opts.Interval {
+		opts.RetryDelay = opts.Interval
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	a := &AutoCheck{
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		trigger: make(chan struct{}, 1),
+	}
+	a.status.Running = true
+	go a.run(ctx, u, opts)
+	return a
+}
+
+// Stop ends the loop and waits for an in-flight check to finish.
+func (a *AutoCheck) Stop() {
+	a.cancel()
+	<-a.done
+}
+
+// CheckNow runs a check immediately instead of waiting for the next one.
+func (a *AutoCheck) CheckNow() {
+	select {
+	case a.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Status returns a snapshot of the loop's state.
+func (a *AutoCheck) Status() AutoCheckStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.status
+}
+
+func (a *AutoCheck) run(ctx context.Context, u *Updater, opts AutoCheckOptions) {
+	defer close(a.done)
+	defer func() {
+		a.mu.Lock()
+		a.status.Running = false
+		a.mu.Unlock()
+	}()
+
+	delay := opts.Interval
+	for {
+		wait := jittered(delay, opts.Jitter)
+		a.mu.Lock()
+		a.status.NextCheck = time.Now().Add(wait)
+		a.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-a.trigger:
+			timer.Stop()
+		case <-timer.C:
+		}
+
+		if a.check(ctx, u, opts) {
+			delay = opts.Interval
+		} else {
+			delay = backoff(opts.RetryDelay, opts.Interval, a.Status().ConsecutiveFailures)
+		}
+	}
+}
+
+// check runs one iteration and reports whether the check itself succeeded.
+func (a *AutoCheck) check(ctx context.Context, u *Updater, opts AutoCheckOptions) bool {
+	info, err := u.CheckForUpdate()
+
+	a.mu.Lock()
+	a.status.LastCheck = time.Now()
+	if err != nil {
+		a.status.LastError = err.Error()
+		a.status.ConsecutiveFailures++
+	} else {
+		a.status.LastError = ""
+		a.status.LastInfo = info
+		a.status.ConsecutiveFailures = 0
+	}
+	a.mu.Unlock()
+
+	if err != nil {
+		log.Printf("[updater] check failed: %v", err)
+		if opts.Hooks.OnFailed != nil {
+			opts.Hooks.OnFailed(nil, err)
+		}
+		return false
+	}
+
+	if !info.Available {
+		return true
+	}
+	if opts.Hooks.OnUpdateAvailable != nil {
+		opts.Hooks.OnUpdateAvailable(info)
+	}
+
+	if !opts.AutoApply {
+		if info.Downgrade {
+			log.Printf("[updater] server rolled back: %s -> %s (run '%s update' to downgrade)", info.CurrentVersion, info.LatestVersion, u.project)
+		} else {
+			log.Printf("[updater] update available: %s -> %s (run '%s update' to install)", info.CurrentVersion, info.LatestVersion, u.project)
+		}
+		return true
+	}
+	if ctx.Err() != nil {
+		return true
+	}
+
+	if info.Downgrade {
+		log.Printf("[updater] rolling back %s -> %s (server rollback)", info.CurrentVersion, info.LatestVersion)
+	} else {
+		log.Printf("[updater] updating %s -> %s", info.CurrentVersion, info.LatestVersion)
+	}
+	if opts.Hooks.OnDownloadStarted != nil {
+		opts.Hooks.OnDownloadStarted(info)
+	}
+	if err := u.PerformUpdate(); err != nil {
+		log.Printf("[updater] auto-update failed: %v", err)
+		a.mu.Lock()
+		a.status.LastError = err.Error()
+		a.mu.Unlock()
+		if opts.Hooks.OnFailed != nil {
+			opts.Hooks.OnFailed(info, err)
+		}
+		return true
+	}
+	if opts.Hooks.OnApplied != nil {
+		opts.Hooks.OnApplied(info)
+	}
+	return true
+}
+
+// jittered spreads d by up to ±fraction of itself.
+func jittered(d time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 || d <= 0 {
+		return d
+	}
+	offset := (rand.Float64()*2 - 1) * fraction * float64(d)
+	return d + time.Duration(offset)
+}
+
+// backoff returns base doubled for each failure after the first, capped at max.
+func backoff(base, max time.Duration, failures int) time.Duration {
+	d := base
+	for i := 1; i < failures && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
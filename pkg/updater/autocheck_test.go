@@ -0,0 +1,104 @@
+package updater
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAutoCheckHooksAndStop(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"version": "1.1.0"}`))
+	}))
+	defer srv.Close()
+
+	u := NewProductionUpdater("app", "1.0.0")
+	u.SetBaseURL(srv.URL)
+	u.SetClientID("test")
+
+	failed := make(chan error, 1)
+	available := make(chan *UpdateInfo, 1)
+	a := u.StartAutoCheckContext(context.Background(), AutoCheckOptions{
+		Interval:   time.Hour,
+		RetryDelay: time.Millisecond,
+		Jitter:     -1,
+		Hooks: AutoCheckHooks{
+			OnFailed:          func(_ *UpdateInfo, err error) { failed <- err },
+			OnUpdateAvailable: func(info *UpdateInfo) { available <- info },
+		},
+	})
+
+	a.CheckNow()
+	select {
+	case <-failed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnFailed not called for the failing check")
+	}
+
+	// The retry after the failure uses RetryDelay, not Interval.
+	select {
+	case info := <-available:
+		if info.LatestVersion != "1.1.0" {
+			t.Errorf("LatestVersion = %q, want 1.1.0", info.LatestVersion)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnUpdateAvailable not called after retry")
+	}
+
+	a.Stop()
+	st := a.Status()
+	if st.Running || st.ConsecutiveFailures != 0 || st.LastInfo == nil {
+		t.Errorf("status after stop = %+v", st)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{4, 8 * time.Minute},
+		{10, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := backoff(time.Minute, time.Hour, tt.failures); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestAutoCheckDefaultsNonPositiveInterval(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(`{"version": "1.0.0"}`))
+	}))
+	defer srv.Close()
+
+	u := NewProductionUpdater("app", "1.0.0")
+	u.SetBaseURL(srv.URL)
+	a := u.StartAutoCheckContext(context.Background(), AutoCheckOptions{Interval: 0, Jitter: -1})
+	defer a.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for a.Status().NextCheck.IsZero() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := requests.Load(); n != 0 {
+		t.Errorf("%d checks with a zero interval, want none before the default interval", n)
+	}
+	if wait := time.Until(a.Status().NextCheck); wait < defaultInterval-time.Minute {
+		t.Errorf("next check in %s, want about %s", wait, defaultInterval)
+	}
+}
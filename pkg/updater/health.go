@@ -0,0 +1,227 @@
+package updater
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// maxUnconfirmedStarts is how many times an updated binary may start without
+// confirming health before it is considered crash-looping.
+const maxUnconfirmedStarts = 3
+
+// pendingUpdate is the marker written next to the binary after an update.
+// It is removed by ConfirmHealthy; if it is still present at the deadline the
+// backup is restored and the marker is renamed to .failed for reporting.
+type pendingUpdate struct {
+	Project     string    `json:"project"`
+	Channel     string    `json:"channel"`
+	FromVersion string    `json:"from_version"`
+	ToVersion   string    `json:"to_version"`
+	ExecPath    string    `json:"exec_path"`
+	BackupPath  string    `json:"backup_path"`
+	ServiceName string    `json:"service_name,omitempty"`
+	InstalledAt time.Time `json:"installed_at"`
+	Deadline    time.Time `json:"deadline"`
+	Starts      int       `json:"starts"`
+	Reason      string    `json:"reason,omitempty"`
+}
+
+// UpdateReport is sent to the release server when an update was reverted.
+type UpdateReport struct {
+	Project     string    `json:"project"`
+	Channel     string    `json:"channel"`
+	FromVersion string    `json:"from_version"`
+	ToVersion   string    `json:"to_version"`
+	ClientID    string    `json:"client_id,omitempty"`
+	Status      string    `json:"status"`
+	Reason      string    `json:"reason,omitempty"`
+	OS          string    `json:"os"`
+	Arch        string    `json:"arch"`
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+// SetHealthCheck makes PerformUpdate require the restarted process to call
+// ConfirmHealthy within deadline. Otherwise the .backup binary is restored
+// and the service restarted. Requires a service name and systemd.
+func (u *Updater) SetHealthCheck(deadline time.Duration) {
+	u.healthDeadline = deadline
+}
+
+func pendingPath(execPath string) string { return execPath + ".pending" }
+func failedPath(execPath string) string  { return execPath + ".failed" }
+
+func readMarker(path string) (*pendingUpdate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p pendingUpdate
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+func writeMarker(path string, p *pendingUpdate) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// armHealthCheck records the pending update and schedules the revert
+// watchdog. It runs after the new binary is installed and before restart.
+func (u *Updater) armHealthCheck(execPath, backupPath, toVersion string) error {
+	now := time.Now().UTC()
+	p := &pendingUpdate{
+		Project:     u.project,
+		Channel:     string(u.channel),
+		FromVersion: strings.TrimPrefix(u.currentVersion, "v"),
+		ToVersion:   toVersion,
+		ExecPath:    execPath,
+		BackupPath:  backupPath,
+		ServiceName: u.serviceName,
+		InstalledAt: now,
+		Deadline:    now.Add(u.healthDeadline),
+	}
+	if err := writeMarker(pendingPath(execPath), p); err != nil {
+		return fmt.Errorf("writing pending-update marker: %w", err)
+	}
+	return scheduleRevert(p, u.healthDeadline)
+}
+
+// ConfirmHealthy tells the updater that the running binary started correctly,
+// cancelling the pending revert. Call it once the service is serving.
+func (u *Updater) ConfirmHealthy() error {
+	execPath, err := currentExecPath()
+	if err != nil {
+		return err
+	}
+	return confirmHealthy(execPath)
+}
+
+func confirmHealthy(execPath string) error {
+	p, err := readMarker(pendingPath(execPath))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(pendingPath(execPath)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	log.Printf("[updater] update %s -> %s confirmed healthy", p.FromVersion, p.ToVersion)
+	return nil
+}
+
+// CheckPendingUpdate should be called early at startup. In an updated binary
+// it counts unconfirmed starts and reverts to the backup if the binary is
+// crash-looping or past its deadline. In a reverted binary it reports the
+// failed update to the release server.
+func (u *Updater) CheckPendingUpdate() error {
+	execPath, err := currentExecPath()
+	if err != nil {
+		return err
+	}
+	return u.checkPendingUpdate(execPath)
+}
+
+func (u *Updater) checkPendingUpdate(execPath string) error {
+	if p, err := readMarker(failedPath(execPath)); err == nil {
+		if p.Reason == "" {
+			p.Reason = "not confirmed healthy before deadline"
+		}
+		log.Printf("[updater] update %s -> %s was reverted: %s", p.FromVersion, p.ToVersion, p.Reason)
+		if err := u.reportFailure(p); err != nil {
+			log.Printf("[updater] reporting reverted update failed (will retry next start): %v", err)
+		} else {
+			os.Remove(failedPath(execPath))
+		}
+	}
+
+	p, err := readMarker(pendingPath(execPath))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimPrefix(u.currentVersion, "v") != p.ToVersion {
+		// Left over from an update this binary is not part of.
+		return os.Remove(pendingPath(execPath))
+	}
+
+	p.Starts++
+	switch {
+	case p.Starts > maxUnconfirmedStarts:
+		p.Reason = fmt.Sprintf("crash loop: %d starts without confirming health", p.Starts-1)
+	case time.Now().After(p.Deadline):
+		p.Reason = "not confirmed healthy before deadline"
+	default:
+		return writeMarker(pendingPath(execPath), p)
+	}
+	return revertUpdate(p)
+}
+
+// revertUpdate restores the backup binary, leaves a .failed marker for the
+// restored process to report, and restarts the service. If the backup can't
+// be restored the markers are left as they were, so the next start retries.
+func revertUpdate(p *pendingUpdate) error {
+	log.Printf("[updater] reverting %s -> %s: %s", p.ToVersion, p.FromVersion, p.Reason)
+	if err := writeMarker(failedPath(p.ExecPath), p); err != nil {
+		return fmt.Errorf("writing failed-update marker: %w", err)
+	}
+	if err := os.Rename(p.BackupPath, p.ExecPath); err != nil {
+		os.Remove(failedPath(p.ExecPath))
+		return fmt.Errorf("restoring backup: %w", err)
+	}
+	os.Remove(pendingPath(p.ExecPath))
+	if p.ServiceName != "" {
+		return restartService(p.ServiceName)
+	}
+	return nil
+}
+
+func (u *Updater) reportFailure(p *pendingUpdate) error {
+	report := UpdateReport{
+		Project:     p.Project,
+		Channel:     p.Channel,
+		FromVersion: p.FromVersion,
+		ToVersion:   p.ToVersion,
+		ClientID:    u.clientIDOrDefault(),
+		Status:      "reverted",
+		Reason:      p.Reason,
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		InstalledAt: p.InstalledAt,
+	}
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	base := defaultReleaseBaseURL
+	if u.baseURL != "" {
+		base = u.baseURL
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(base+"/api/v1/update-reports", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("release server returned status %d", resp.StatusCode)
+	}
+	return nil
+}
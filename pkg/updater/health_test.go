@@ -0,0 +1,163 @@
+package updater
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// installPending lays out an updated binary, its backup and a pending
+// marker in a temp dir, as PerformUpdate leaves them before restarting.
+func installPending(t *testing.T, p pendingUpdate) *pendingUpdate {
+	t.Helper()
+	dir := t.TempDir()
+	p.ExecPath = filepath.Join(dir, "app")
+	p.BackupPath = p.ExecPath + ".backup"
+	if err := os.WriteFile(p.ExecPath, []byte("new"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p.BackupPath, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeMarker(pendingPath(p.ExecPath), &p); err != nil {
+		t.Fatal(err)
+	}
+	return &p
+}
+
+func assertBinary(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != want {
+		t.Errorf("binary = %q, %v; want %q", data, err, want)
+	}
+}
+
+func TestCheckPendingUpdateRevertsCrashLoop(t *testing.T) {
+	p := installPending(t, pendingUpdate{FromVersion: "1.0.0", ToVersion: "1.1.0", Deadline: time.Now().Add(time.Hour)})
+	u := NewProductionUpdater("app", "1.1.0")
+
+	for i := 1; i <= maxUnconfirmedStarts; i++ {
+		if err := u.checkPendingUpdate(p.ExecPath); err != nil {
+			t.Fatalf("start %d: %v", i, err)
+		}
+		m, err := readMarker(pendingPath(p.ExecPath))
+		if err != nil || m.Starts != i {
+			t.Fatalf("after start %d marker = %+v, %v", i, m, err)
+		}
+	}
+	assertBinary(t, p.ExecPath, "new")
+
+	if err := u.checkPendingUpdate(p.ExecPath); err != nil {
+		t.Fatalf("crash-looping start: %v", err)
+	}
+	assertBinary(t, p.ExecPath, "old")
+	if _, err := os.Stat(pendingPath(p.ExecPath)); !os.IsNotExist(err) {
+		t.Errorf("pending marker left after revert: %v", err)
+	}
+	f, err := readMarker(failedPath(p.ExecPath))
+	if err != nil || f.Reason == "" {
+		t.Errorf("failed marker = %+v, %v", f, err)
+	}
+}
+
+func TestCheckPendingUpdateRevertsAfterDeadline(t *testing.T) {
+	p := installPending(t, pendingUpdate{FromVersion: "1.0.0", ToVersion: "1.1.0", Deadline: time.Now().Add(-time.Minute)})
+	u := NewProductionUpdater("app", "v1.1.0")
+
+	if err := u.checkPendingUpdate(p.ExecPath); err != nil {
+		t.Fatalf("checkPendingUpdate: %v", err)
+	}
+	assertBinary(t, p.ExecPath, "old")
+	if f, err := readMarker(failedPath(p.ExecPath)); err != nil || f.Reason != "not confirmed healthy before deadline" {
+		t.Errorf("failed marker = %+v, %v", f, err)
+	}
+}
+
+func TestCheckPendingUpdateKeepsMarkersWhenRestoreFails(t *testing.T) {
+	p := installPending(t, pendingUpdate{FromVersion: "1.0.0", ToVersion: "1.1.0", Deadline: time.Now().Add(-time.Minute)})
+	os.Remove(p.BackupPath)
+	u := NewProductionUpdater("app", "1.1.0")
+
+	if err := u.checkPendingUpdate(p.ExecPath); err == nil {
+		t.Fatal("revert without a backup succeeded")
+	}
+	if _, err := os.Stat(pendingPath(p.ExecPath)); err != nil {
+		t.Errorf("pending marker removed although the backup was not restored: %v", err)
+	}
+	if _, err := os.Stat(failedPath(p.ExecPath)); !os.IsNotExist(err) {
+		t.Errorf("failed marker written although nothing was reverted: %v", err)
+	}
+}
+
+func TestCheckPendingUpdateDropsStaleMarker(t *testing.T) {
+	p := installPending(t, pendingUpdate{FromVersion: "1.0.0", ToVersion: "1.1.0", Deadline: time.Now().Add(-time.Minute)})
+	u := NewProductionUpdater("app", "1.2.0")
+
+	if err := u.checkPendingUpdate(p.ExecPath); err != nil {
+		t.Fatalf("checkPendingUpdate: %v", err)
+	}
+	if _, err := os.Stat(pendingPath(p.ExecPath)); !os.IsNotExist(err) {
+		t.Errorf("stale pending marker kept: %v", err)
+	}
+	assertBinary(t, p.ExecPath, "new")
+}
+
+func TestCheckPendingUpdateReportsFailure(t *testing.T) {
+	var reports []UpdateReport
+	fail := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var rep UpdateReport
+		json.NewDecoder(r.Body).Decode(&rep)
+		reports = append(reports, rep)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	execPath := filepath.Join(t.TempDir(), "app")
+	if err := writeMarker(failedPath(execPath), &pendingUpdate{Project: "app", FromVersion: "1.0.0", ToVersion: "1.1.0"}); err != nil {
+		t.Fatal(err)
+	}
+	u := NewProductionUpdater("app", "1.0.0")
+	u.SetBaseURL(srv.URL)
+
+	// A failed report is kept for the next start.
+	if err := u.checkPendingUpdate(execPath); err != nil {
+		t.Fatalf("checkPendingUpdate: %v", err)
+	}
+	if _, err := os.Stat(failedPath(execPath)); err != nil {
+		t.Fatalf("failed marker dropped although the report failed: %v", err)
+	}
+
+	fail = false
+	if err := u.checkPendingUpdate(execPath); err != nil {
+		t.Fatalf("checkPendingUpdate: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Status != "reverted" || reports[0].ToVersion != "1.1.0" || reports[0].Reason == "" {
+		t.Errorf("reports = %+v", reports)
+	}
+	if _, err := os.Stat(failedPath(execPath)); !os.IsNotExist(err) {
+		t.Errorf("failed marker kept after reporting: %v", err)
+	}
+}
+
+func TestConfirmHealthyClearsMarker(t *testing.T) {
+	p := installPending(t, pendingUpdate{FromVersion: "1.0.0", ToVersion: "1.1.0", Deadline: time.Now().Add(time.Hour)})
+	if err := confirmHealthy(p.ExecPath); err != nil {
+		t.Fatalf("confirmHealthy: %v", err)
+	}
+	if _, err := os.Stat(pendingPath(p.ExecPath)); !os.IsNotExist(err) {
+		t.Errorf("pending marker kept after confirmation: %v", err)
+	}
+	if err := confirmHealthy(p.ExecPath); err != nil {
+		t.Errorf("confirmHealthy without a pending update: %v", err)
+	}
+}
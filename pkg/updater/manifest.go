@@ -0,0 +1,95 @@
+package updater
+
+import (
+	"path"
+	"strings"
+	"time"
+)
+
+// ManifestSchema is the latest.json schema version this package understands.
+// Servers that predate it send no schema and only version fields.
+const ManifestSchema = 1
+
+// Manifest is the latest.json document served for a project/channel.
+type Manifest struct {
+	Schema       int                `json:"schema,omitempty"`
+	Version      string             `json:"version"`
+	BuildNumber  int                `json:"build_number,omitempty"`
+	Rollback     bool               `json:"rollback,omitempty"`
+	ReleasedAt   time.Time          `json:"released_at,omitzero"`
+	ReleaseNotes string             `json:"release_notes,omitempty"`
+	MinVersion   string             `json:"min_version,omitempty"` // oldest version still supported
+	Artifacts    []ManifestArtifact `json:"artifacts,omitempty"`
+}
+
+// ManifestArtifact describes one downloadable file of a release. OS and
+// Arch are empty for files that are not platform-specific.
+type ManifestArtifact struct {
+	Name   string `json:"name"`
+	OS     string `json:"os,omitempty"`
+	Arch   string `json:"arch,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+	SHA256 string `json:"sha256"`
+	URL    string `json:"url,omitempty"`
+}
+
+var knownOS = map[string]bool{
+	"linux": true, "darwin": true, "windows": true, "freebsd": true,
+	"openbsd": true, "netbsd": true, "android": true, "ios": true,
+	"illumos": true, "solaris": true, "aix": true, "dragonfly": true,
+}
+
+var knownArch = map[string]bool{
+	"amd64": true, "arm64": true, "386": true, "arm": true,
+	"riscv64": true, "ppc64": true, "ppc64le": true, "s390x": true,
+	"mips": true, "mipsle": true, "mips64": true, "mips64le": true,
+	"loong64": true,
+}
+
+// PlatformFromName extracts GOOS and GOARCH from a file name following the
+// "<project>-<goos>-<goarch>[.exe]" convention. Both are empty if the name
+// does not follow it.
+func PlatformFromName(name string) (goos, goarch string) {
+	base := strings.TrimSuffix(path.Base(name), ".exe")
+	parts := strings.Split(base, "-")
+	if len(parts) < 3 {
+		return "", ""
+	}
+	goos, goarch = parts[len(parts)-2], parts[len(parts)-1]
+	if !knownOS[goos] || !knownArch[goarch] {
+		return "", ""
+	}
+	return goos, goarch
+}
+
+// ArtifactFor returns the artifact for a platform. The conventional binary
+// name wins; otherwise the platform's only artifact is used. It returns nil
+// if there is no match or the match is ambiguous.
+func (m *Manifest) ArtifactFor(project, goos, goarch string) *ManifestArtifact {
+	want := binaryName(project, goos, goarch)
+	var match *ManifestArtifact
+	matches := 0
+	for i := range m.Artifacts {
+		a := &m.Artifacts[i]
+		if path.Base(a.Name) == want {
+			return a
+		}
+		if a.OS == goos && a.Arch == goarch {
+			match = a
+			matches++
+		}
+	}
+	if matches != 1 {
+		return nil
+	}
+	return match
+}
+
+// binaryName is the conventional release file name for a platform.
+func binaryName(project, goos, goarch string) string {
+	name := project + "-" + goos + "-" + goarch
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
@@ -0,0 +1,116 @@
+package updater
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPlatformFromName(t *testing.T) {
+	tests := []struct {
+		name, goos, goarch string
+	}{
+		{"hydrarelease-linux-amd64", "linux", "amd64"},
+		{"hydra-agent-windows-arm64.exe", "windows", "arm64"},
+		{"bin/app-darwin-arm64", "darwin", "arm64"},
+		{"SHA256SUMS", "", ""},
+		{"app-linux", "", ""},
+		{"app-plan-nine", "", ""},
+	}
+	for _, tt := range tests {
+		goos, goarch := PlatformFromName(tt.name)
+		if goos != tt.goos || goarch != tt.goarch {
+			t.Errorf("PlatformFromName(%q) = %q, %q; want %q, %q", tt.name, goos, goarch, tt.goos, tt.goarch)
+		}
+	}
+}
+
+func TestArtifactFor(t *testing.T) {
+	m := &Manifest{Artifacts: []ManifestArtifact{
+		{Name: "app-linux-amd64", OS: "linux", Arch: "amd64"},
+		{Name: "helper-linux-amd64", OS: "linux", Arch: "amd64"},
+		{Name: "bundle-darwin-arm64", OS: "darwin", Arch: "arm64"},
+	}}
+
+	if a := m.ArtifactFor("app", "linux", "amd64"); a == nil || a.Name != "app-linux-amd64" {
+		t.Errorf("conventional name: got %+v", a)
+	}
+	if a := m.ArtifactFor("app", "darwin", "arm64"); a == nil || a.Name != "bundle-darwin-arm64" {
+		t.Errorf("single platform match: got %+v", a)
+	}
+	if a := m.ArtifactFor("other", "linux", "amd64"); a != nil {
+		t.Errorf("ambiguous platform match: got %+v, want nil", a)
+	}
+	if a := m.ArtifactFor("app", "windows", "amd64"); a != nil {
+		t.Errorf("missing platform: got %+v, want nil", a)
+	}
+}
+
+func TestCheckForUpdateReadsManifest(t *testing.T) {
+	binary := []byte("new binary")
+	sum := sha256.Sum256(binary)
+	name := binaryName("app", "linux", "amd64")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"schema": 1,
+			"version": "1.2.0",
+			"release_notes": "Fixes",
+			"min_version": "1.1.0",
+			"artifacts": [{"name": "` + name + `", "os": "linux", "arch": "amd64",
+				"size": 10, "sha256": "` + hex.EncodeToString(sum[:]) + `", "url": "https://cdn.example/app"}]
+		}`))
+	}))
+	defer srv.Close()
+
+	u := NewProductionUpdater("app", "1.0.0")
+	u.SetBaseURL(srv.URL)
+	u.SetClientID("test")
+
+	info, err := u.CheckForUpdate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Available || !info.Required || info.ReleaseNotes != "Fixes" {
+		t.Errorf("info = %+v", info)
+	}
+
+	m := Manifest{Artifacts: []ManifestArtifact{{Name: name, OS: "linux", Arch: "amd64", SHA256: hex.EncodeToString(sum[:])}}}
+	a := m.ArtifactFor("app", "linux", "amd64")
+	path := t.TempDir() + "/app"
+	if err := os.WriteFile(path, binary, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.verifyDownload(path, name, "v1.2.0", a); err != nil {
+		t.Errorf("verifyDownload: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("tampered!!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.verifyDownload(path, name, "v1.2.0", a); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("verifyDownload of tampered file: %v", err)
+	}
+}
+
+func TestCheckForUpdateLegacyServer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"version": "1.2.0", "build_number": 7}`))
+	}))
+	defer srv.Close()
+
+	u := NewProductionUpdater("app", "1.0.0")
+	u.SetBaseURL(srv.URL)
+	u.SetClientID("test")
+
+	info, err := u.CheckForUpdate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Available || info.Artifact != nil || info.Required {
+		t.Errorf("info = %+v", info)
+	}
+}
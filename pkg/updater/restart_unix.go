@@ -5,6 +5,8 @@ package updater
 import (
 	"fmt"
 	"os/exec"
+	"strings"
+	"time"
 )
 
 func restartService(name string) error {
@@ -14,3 +16,30 @@ func restartService(name string) error {
 	}
 	return err
 }
+
+// scheduleRevert starts a transient systemd timer, outside the service's
+// cgroup so it survives the restart, that restores the backup if the
+// pending-update marker is still present after deadline.
+func scheduleRevert(p *pendingUpdate, deadline time.Duration) error {
+	if p.ServiceName == "" {
+		return fmt.Errorf("health check requires a service name")
+	}
+	script := fmt.Sprintf("if [ -f %s ]; then mv -f %s %s && mv -f %s %s && systemctl restart %s; fi",
+		shellQuote(pendingPath(p.ExecPath)),
+		shellQuote(pendingPath(p.ExecPath)), shellQuote(failedPath(p.ExecPath)),
+		shellQuote(p.BackupPath), shellQuote(p.ExecPath),
+		shellQuote(p.ServiceName))
+	unit := fmt.Sprintf("%s-update-watchdog-%d", p.ServiceName, time.Now().Unix())
+	output, err := exec.Command("systemd-run",
+		"--unit="+unit,
+		fmt.Sprintf("--on-active=%ds", int(deadline.Seconds())),
+		"/bin/sh", "-c", script).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("scheduling revert watchdog: %w\nOutput: %s", err, string(output))
+	}
+	return nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
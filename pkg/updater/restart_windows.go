@@ -5,6 +5,7 @@ package updater
 import (
 	"fmt"
 	"os/exec"
+	"time"
 )
 
 func restartService(name string) error {
@@ -15,3 +16,9 @@ func restartService(name string) error {
 	}
 	return nil
 }
+
+// scheduleRevert is not supported on Windows; CheckPendingUpdate still
+// reverts a binary that starts past its deadline or keeps restarting.
+func scheduleRevert(p *pendingUpdate, deadline time.Duration) error {
+	return nil
+}
@@ -0,0 +1,55 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"testing"
+)
+
+func TestVerifyWithRotatedKeys(t *testing.T) {
+	oldPub, oldPriv, _ := GenerateKey()
+	newPub, newPriv, _ := GenerateKey()
+	otherPub, _, _ := GenerateKey()
+	sums := []byte("abc  app-linux-amd64\n")
+
+	// During rotation the release is signed by both keys.
+	sig := append(Sign(oldPriv, sums), Sign(newPriv, sums)...)
+
+	tests := []struct {
+		name    string
+		trusted []ed25519.PublicKey
+		data    []byte
+		want    error
+	}{
+		{"old key only", []ed25519.PublicKey{oldPub}, sums, nil},
+		{"new key only", []ed25519.PublicKey{newPub}, sums, nil},
+		{"untrusted key", []ed25519.PublicKey{otherPub}, sums, ErrUnsigned},
+		{"tampered sums", []ed25519.PublicKey{oldPub, newPub}, []byte("def  app-linux-amd64\n"), ErrBadSignature},
+	}
+	for _, tt := range tests {
+		if err := Verify(tt.data, sig, tt.trusted); err != tt.want {
+			t.Errorf("%s: Verify = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	if err := Verify(sums, nil, []ed25519.PublicKey{oldPub}); err != ErrUnsigned {
+		t.Errorf("empty signature file: Verify = %v, want %v", err, ErrUnsigned)
+	}
+}
+
+func TestKeyRoundTrip(t *testing.T) {
+	pub, priv, _ := GenerateKey()
+
+	gotPub, err := ParsePublicKey(EncodePublicKey(pub))
+	if err != nil || !gotPub.Equal(pub) {
+		t.Fatalf("public key round trip failed: %v", err)
+	}
+	gotPriv, err := ParsePrivateKey(EncodePrivateKey(priv))
+	if err != nil || !gotPriv.Equal(priv) {
+		t.Fatalf("private key round trip failed: %v", err)
+	}
+
+	keys, err := ParsePublicKeys([]byte("# release keys\n" + EncodePublicKey(pub) + "\n\n"))
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("ParsePublicKeys = %d keys, %v", len(keys), err)
+	}
+}
@@ -1,28 +1,31 @@
 package updater
 
 import (
+	"context"
+	"crypto/ed25519"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
 
+	"github.com/cederikdotcom/hydrarelease/pkg/updater/signing"
 	"github.com/cederikdotcom/hydrarelease/pkg/updater/version"
 )
 
 const defaultReleaseBaseURL = "https://releases.experiencenet.com"
 
-type latestManifest struct {
-	Version string `json:"version"`
-}
+// ClientIDHeader carries a stable per-machine identifier on latest.json
+// requests so the release server can place the client in a staged rollout.
+const ClientIDHeader = "X-Hydrarelease-Client-ID"
 
 // Channel represents a release channel.
 type Channel string
@@ -36,6 +39,21 @@ type UpdateInfo struct {
 	CurrentVersion string
 	LatestVersion  string
 	Available      bool
+	// Excluded is set when LatestVersion falls outside the updater's
+	// version constraint and is therefore not offered.
+	Excluded bool
+	// Downgrade is set when the server rolled back to an older version and
+	// the updater allows downgrades. Available is true in that case too.
+	Downgrade bool
+	// Required is set when CurrentVersion is below the release's minimum
+	// supported version.
+	Required     bool
+	MinVersion   string
+	ReleaseNotes string
+	ReleasedAt   time.Time
+	// Artifact is this platform's file from the release manifest, or nil
+	// when the server predates manifests and the file name is guessed.
+	Artifact *ManifestArtifact
 }
 
 type Updater struct {
@@ -44,6 +62,11 @@ type Updater struct {
 	serviceName    string
 	channel        Channel
 	baseURL        string
+	clientID       string
+	allowDowngrade bool
+	trustedKeys    []ed25519.PublicKey
+	healthDeadline time.Duration
+	constraint     *version.Constraint
 }
 
 // SetServiceName sets the systemd service to restart after a successful update.
@@ -56,6 +79,63 @@ func (u *Updater) SetBaseURL(url string) {
 	u.baseURL = url
 }
 
+// SetAllowDowngrade lets the updater install an older version when the
+// release server marks it as a rollback target. Off by default.
+func (u *Updater) SetAllowDowngrade(allow bool) {
+	u.allowDowngrade = allow
+}
+
+// SetTrustedKeys requires every release to carry a SHA256SUMS.sig signed by
+// one of keys. Releases that are unsigned or signed by other keys are refused.
+// Passing several keys allows rotation without breaking deployed updaters.
+func (u *Updater) SetTrustedKeys(keys ...ed25519.PublicKey) {
+	u.trustedKeys = keys
+}
+
+// SetVersionConstraint limits updates to versions matching expr, e.g.
+// "~1.5" to stay on 1.5.x or ">=1.4.0 <2.0.0". See version.ParseConstraint.
+func (u *Updater) SetVersionConstraint(expr string) error {
+	c, err := version.ParseConstraint(expr)
+	if err != nil {
+		return err
+	}
+	u.constraint = c
+	return nil
+}
+
+// SetClientID overrides the identifier sent with update checks. By default
+// it is derived from the machine ID, falling back to the hostname.
+func (u *Updater) SetClientID(id string) {
+	u.clientID = id
+}
+
+func (u *Updater) clientIDOrDefault() string {
+	if u.clientID != "" {
+		return u.clientID
+	}
+	return defaultClientID()
+}
+
+// defaultClientID hashes the machine ID (or hostname) so the raw value never
+// leaves the machine.
+func defaultClientID() string {
+	var seed string
+	for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		if data, err := os.ReadFile(path); err == nil {
+			seed = strings.TrimSpace(string(data))
+			break
+		}
+	}
+	if seed == "" {
+		seed, _ = os.Hostname()
+	}
+	if seed == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(seed))
+	return hex.EncodeToString(sum[:16])
+}
+
 // NewProductionUpdater creates an updater that tracks the production release channel.
 func NewProductionUpdater(project, currentVersion string) *Updater {
 	return &Updater{project: project, currentVersion: currentVersion, channel: Production}
@@ -77,7 +157,15 @@ func (u *Updater) channelURL() string {
 func (u *Updater) CheckForUpdate() (*UpdateInfo, error) {
 	client := &http.Client{Timeout: 30 * time.Second}
 
-	resp, err := client.Get(u.channelURL() + "/latest.json")
+	req, err := http.NewRequest("GET", u.channelURL()+"/latest.json", nil)
+	if err != nil {
+		return nil, fmt.Errorf("checking for updates: %w", err)
+	}
+	if id := u.clientIDOrDefault(); id != "" {
+		req.Header.Set(ClientIDHeader, id)
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("checking for updates: %w", err)
 	}
@@ -92,19 +180,38 @@ func (u *Updater) CheckForUpdate() (*UpdateInfo, error) {
 		return nil, fmt.Errorf("reading response: %w", err)
 	}
 
-	var manifest latestManifest
+	var manifest Manifest
 	if err := json.Unmarshal(body, &manifest); err != nil {
 		return nil, fmt.Errorf("parsing response: %w", err)
 	}
 
-	latestVersion := strings.TrimPrefix(manifest.Version, "v")
+	latest, err := version.Parse(manifest.Version)
+	if err != nil {
+		return nil, fmt.Errorf("release server returned %w", err)
+	}
+	latestVersion := latest.String()
 	currentVersion := strings.TrimPrefix(u.currentVersion, "v")
 
-	return &UpdateInfo{
+	// Development builds ("dev") sort below every release.
+	cmp := version.Compare(latestVersion, currentVersion)
+	downgrade := u.allowDowngrade && manifest.Rollback && cmp < 0
+	excluded := u.constraint != nil && !u.constraint.Check(latest)
+
+	info := &UpdateInfo{
 		CurrentVersion: currentVersion,
 		LatestVersion:  latestVersion,
-		Available:      version.Compare(latestVersion, currentVersion) > 0,
-	}, nil
+		Available:      (cmp > 0 || downgrade) && !excluded,
+		Excluded:       excluded,
+		Downgrade:      downgrade && !excluded,
+		MinVersion:     manifest.MinVersion,
+		ReleaseNotes:   manifest.ReleaseNotes,
+		ReleasedAt:     manifest.ReleasedAt,
+		Artifact:       manifest.ArtifactFor(u.project, runtime.GOOS, runtime.GOARCH),
+	}
+	if manifest.MinVersion != "" {
+		info.Required = version.Compare(currentVersion, manifest.MinVersion) < 0
+	}
+	return info, nil
 }
 
 func (u *Updater) PerformUpdate() error {
@@ -118,22 +225,27 @@ func (u *Updater) PerformUpdate() error {
 		return nil
 	}
 
-	execPath, err := os.Executable()
+	execPath, err := currentExecPath()
 	if err != nil {
-		return fmt.Errorf("getting executable path: %w", err)
-	}
-	execPath, err = filepath.EvalSymlinks(execPath)
-	if err != nil {
-		return fmt.Errorf("resolving symlink: %w", err)
+		return err
 	}
 
-	binaryName := fmt.Sprintf("%s-%s-%s", u.project, runtime.GOOS, runtime.GOARCH)
-	if runtime.GOOS == "windows" {
-		binaryName += ".exe"
-	}
+	// Servers with a manifest name the file and its hash; older ones follow
+	// the "<project>-<goos>-<goarch>" convention with a SHA256SUMS file.
 	ver := "v" + updateInfo.LatestVersion
-	downloadURL := fmt.Sprintf("%s/%s/%s", u.channelURL(), ver, binaryName)
+	fileName := binaryName(u.project, runtime.GOOS, runtime.GOARCH)
+	downloadURL := fmt.Sprintf("%s/%s/%s", u.channelURL(), ver, fileName)
+	artifact := updateInfo.Artifact
+	if artifact != nil {
+		fileName = path.Base(artifact.Name)
+		if artifact.URL != "" {
+			downloadURL = artifact.URL
+		}
+	}
 
+	if updateInfo.Downgrade {
+		fmt.Printf("Release server rolled back %s: downgrading v%s -> %s\n", u.project, updateInfo.CurrentVersion, ver)
+	}
 	fmt.Printf("Downloading %s %s for %s/%s...\n", u.project, ver, runtime.GOOS, runtime.GOARCH)
 
 	// Download to the same directory as the target binary so os.Rename works
@@ -151,10 +263,18 @@ func (u *Updater) PerformUpdate() error {
 		os.Remove(tmpFile)
 		return fmt.Errorf("downloaded file is empty or missing")
 	}
+	if artifact != nil && artifact.Size > 0 && info.Size() != artifact.Size {
+		os.Remove(tmpFile)
+		return fmt.Errorf("downloaded %d bytes, manifest lists %d", info.Size(), artifact.Size)
+	}
 
 	// Verify checksum
-	fmt.Println("Verifying checksum...")
-	if err := u.verifyChecksum(tmpFile, binaryName, ver); err != nil {
+	if len(u.trustedKeys) > 0 {
+		fmt.Println("Verifying signature and checksum...")
+	} else {
+		fmt.Println("Verifying checksum...")
+	}
+	if err := u.verifyDownload(tmpFile, fileName, ver, artifact); err != nil {
 		os.Remove(tmpFile)
 		return err
 	}
@@ -193,6 +313,14 @@ func (u *Updater) PerformUpdate() error {
 	fmt.Println("\nUpdate completed successfully!")
 	fmt.Printf("Backup saved at: %s\n", backupPath)
 
+	if u.healthDeadline > 0 && u.serviceName != "" {
+		if err := u.armHealthCheck(execPath, backupPath, updateInfo.LatestVersion); err != nil {
+			fmt.Printf("Warning: %s\n", err)
+		} else {
+			fmt.Printf("New version must confirm healthy within %s or %s is restored.\n", u.healthDeadline, backupPath)
+		}
+	}
+
 	if u.serviceName != "" {
 		fmt.Printf("\nRestarting service %s...\n", u.serviceName)
 		if err := restartService(u.serviceName); err != nil {
@@ -210,52 +338,46 @@ func (u *Updater) PerformUpdate() error {
 // StartAutoCheck runs a background goroutine that periodically checks for
 // updates. If autoApply is true, updates are downloaded and installed
 // automatically, and the systemd service is restarted. If false, it only logs
-// that an update is available.
+// that an update is available. Use StartAutoCheckContext to stop it or to
+// observe its progress.
 func (u *Updater) StartAutoCheck(interval time.Duration, autoApply bool) {
-	go func() {
-		for {
-			time.Sleep(interval)
-
-			info, err := u.CheckForUpdate()
-			if err != nil {
-				log.Printf("[updater] check failed: %v", err)
-				continue
-			}
-
-			if !info.Available {
-				continue
-			}
-
-			if !autoApply {
-				log.Printf("[updater] update available: %s -> %s (run '%s update' to install)", info.CurrentVersion, info.LatestVersion, u.project)
-				continue
-			}
-
-			log.Printf("[updater] updating %s -> %s", info.CurrentVersion, info.LatestVersion)
-			if err := u.PerformUpdate(); err != nil {
-				log.Printf("[updater] auto-update failed: %v", err)
-			}
-		}
-	}()
+	u.StartAutoCheckContext(context.Background(), AutoCheckOptions{
+		Interval:  interval,
+		AutoApply: autoApply,
+	})
 }
 
-func (u *Updater) verifyChecksum(filePath, binaryName, ver string) error {
-	sumsURL := fmt.Sprintf("%s/%s/SHA256SUMS", u.channelURL(), ver)
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Get(sumsURL)
+// verifyDownload checks the downloaded file against the manifest hash. When
+// trusted keys are set, or the server sent no manifest, it is checked against
+// the release's SHA256SUMS instead, since only that file is signed.
+func (u *Updater) verifyDownload(filePath, binaryName, ver string, artifact *ManifestArtifact) error {
+	if len(u.trustedKeys) > 0 || artifact == nil || artifact.SHA256 == "" {
+		return u.verifyChecksum(filePath, binaryName, ver)
+	}
+	actual, err := hashFile(filePath)
 	if err != nil {
-		return fmt.Errorf("fetching SHA256SUMS: %w", err)
+		return fmt.Errorf("hashing downloaded file: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("SHA256SUMS not found (status %d)", resp.StatusCode)
+	if actual != artifact.SHA256 {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", artifact.SHA256, actual)
 	}
+	return nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+func (u *Updater) verifyChecksum(filePath, binaryName, ver string) error {
+	body, err := fetchReleaseFile(fmt.Sprintf("%s/%s/SHA256SUMS", u.channelURL(), ver))
 	if err != nil {
-		return fmt.Errorf("reading SHA256SUMS: %w", err)
+		return fmt.Errorf("fetching SHA256SUMS: %w", err)
+	}
+
+	if len(u.trustedKeys) > 0 {
+		sig, err := fetchReleaseFile(fmt.Sprintf("%s/%s/%s", u.channelURL(), ver, signing.SignatureFile))
+		if err != nil {
+			return fmt.Errorf("release %s is not signed: %w", ver, err)
+		}
+		if err := signing.Verify(body, sig, u.trustedKeys); err != nil {
+			return fmt.Errorf("refusing release %s: %w", ver, err)
+		}
 	}
 
 	// Parse "hash  filename" lines
@@ -283,6 +405,32 @@ func (u *Updater) verifyChecksum(filePath, binaryName, ver string) error {
 	return nil
 }
 
+func currentExecPath() (string, error) {
+	execPath, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("getting executable path: %w", err)
+	}
+	execPath, err = filepath.EvalSymlinks(execPath)
+	if err != nil {
+		return "", fmt.Errorf("resolving symlink: %w", err)
+	}
+	return execPath, nil
+}
+
+func fetchReleaseFile(url string) ([]byte, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("not found (status %d)", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
 func hashFile(path string) (string, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -0,0 +1,50 @@
+package updater
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckForUpdateDowngrade(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		allow      bool
+		constraint string
+		body       string
+		available  bool
+		downgrade  bool
+		excluded   bool
+	}{
+		{"rollback allowed", true, "", `{"version": "1.1.0", "rollback": true}`, true, true, false},
+		{"rollback disallowed", false, "", `{"version": "1.1.0", "rollback": true}`, false, false, false},
+		{"older without rollback flag", true, "", `{"version": "1.1.0"}`, false, false, false},
+		{"rollback outside constraint", true, "~1.2", `{"version": "1.1.0", "rollback": true}`, false, false, true},
+	}
+	for _, tt := range tests {
+		body = tt.body
+		u := NewProductionUpdater("app", "1.2.0")
+		u.SetBaseURL(srv.URL)
+		u.SetClientID("test")
+		u.SetAllowDowngrade(tt.allow)
+		if tt.constraint != "" {
+			if err := u.SetVersionConstraint(tt.constraint); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		info, err := u.CheckForUpdate()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if info.Available != tt.available || info.Downgrade != tt.downgrade || info.Excluded != tt.excluded {
+			t.Errorf("%s: info = %+v", tt.name, info)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package version
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Constraint is a set of version ranges, e.g. ">=1.4.0 <2.0.0" or
+// "~1.5 || ^2.1".
+//
+// Alternatives are separated by "||"; within one alternative, comparators
+// separated by spaces or commas must all match. Supported comparators:
+//
+//	=1.2.3  1.2.3     exactly (a partial version such as 1.2 means 1.2.x)
+//	!=1.2.3           anything else
+//	>1.2.3  >=1.2.3   above / at least (missing parts are 0)
+//	<1.2.3  <=1.2.3   below / at most (missing parts are 0)
+//	~1.5  ~1.5.3      patch updates within 1.5
+//	~1                anything 1.x
+//	^1.2.3            anything below 2.0.0 (below 0.3.0 for ^0.2.3)
+//	*                 any version
+//
+// Pre-release versions only match an alternative that names a pre-release of
+// the same MAJOR.MINOR.PATCH, so ">=1.4.0" does not select "2.0.0-rc.1".
+type Constraint struct {
+	raw    string
+	groups [][]comparator
+}
+
+type comparator struct {
+	op string // one of = != > >= < <=
+	v  Version
+}
+
+// ParseConstraint parses a constraint expression.
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{raw: s}
+	for _, alt := range strings.Split(s, "||") {
+		fields := strings.FieldsFunc(alt, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' })
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("invalid constraint %q: empty alternative", s)
+		}
+		var group []comparator
+		for i := 0; i < len(fields); i++ {
+			f := fields[i]
+			// Allow a space between operator and version: ">= 1.4.0".
+			if isOperator(f) && i+1 < len(fields) {
+				i++
+				f += fields[i]
+			}
+			cmps, err := parseComparator(f)
+			if err != nil {
+				return nil, fmt.Errorf("invalid constraint %q: %w", s, err)
+			}
+			group = append(group, cmps...)
+		}
+		c.groups = append(c.groups, group)
+	}
+	return c, nil
+}
+
+// MustParseConstraint is like ParseConstraint but panics on error.
+func MustParseConstraint(s string) *Constraint {
+	c, err := ParseConstraint(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// String returns the expression the constraint was parsed from.
+func (c *Constraint) String() string {
+	return c.raw
+}
+
+// Check reports whether v satisfies the constraint.
+func (c *Constraint) Check(v Version) bool {
+	for _, group := range c.groups {
+		if groupMatches(group, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckString parses s and checks it; unparseable versions never match.
+func (c *Constraint) CheckString(s string) bool {
+	v, err := Parse(s)
+	return err == nil && c.Check(v)
+}
+
+func groupMatches(group []comparator, v Version) bool {
+	prereleaseAllowed := !v.IsPrerelease()
+	for _, cmp := range group {
+		if !cmp.matches(v) {
+			return false
+		}
+		if v.IsPrerelease() && cmp.v.IsPrerelease() &&
+			cmp.v.Major == v.Major && cmp.v.Minor == v.Minor && cmp.v.Patch == v.Patch {
+			prereleaseAllowed = true
+		}
+	}
+	return prereleaseAllowed
+}
+
+func (c comparator) matches(v Version) bool {
+	r := v.Compare(c.v)
+	switch c.op {
+	case "=":
+		return r == 0
+	case "!=":
+		return r != 0
+	case ">":
+		return r > 0
+	case ">=":
+		return r >= 0
+	case "<":
+		return r < 0
+	case "<=":
+		return r <= 0
+	}
+	return false
+}
+
+var operators = []string{">=", "<=", "!=", "==", ">", "<", "=", "~", "^"}
+
+func isOperator(s string) bool {
+	for _, op := range operators {
+		if s == op {
+			return true
+		}
+	}
+	return false
+}
+
+func parseComparator(s string) ([]comparator, error) {
+	if s == "*" || s == "x" || s == "X" {
+		return []comparator{{op: ">=", v: Version{}}}, nil
+	}
+
+	op := ""
+	for _, o := range operators {
+		if strings.HasPrefix(s, o) {
+			op = o
+			break
+		}
+	}
+	rest := strings.TrimPrefix(s, op)
+	if op == "==" {
+		op = "="
+	}
+
+	v, parts, err := parsePartial(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "~":
+		upper := Version{Major: v.Major + 1}
+		if parts >= 2 {
+			upper = Version{Major: v.Major, Minor: v.Minor + 1}
+		}
+		return bounded(v, upper), nil
+	case "^":
+		var upper Version
+		switch {
+		case v.Major > 0 || parts == 1:
+			upper = Version{Major: v.Major + 1}
+		case v.Minor > 0 || parts == 2:
+			upper = Version{Minor: v.Minor + 1}
+		default:
+			upper = Version{Patch: v.Patch + 1}
+		}
+		return bounded(v, upper), nil
+	case "", "=":
+		switch parts {
+		case 1:
+			return bounded(v, Version{Major: v.Major + 1}), nil
+		case 2:
+			return bounded(v, Version{Major: v.Major, Minor: v.Minor + 1}), nil
+		}
+		return []comparator{{op: "=", v: v}}, nil
+	}
+	return []comparator{{op: op, v: v}}, nil
+}
+
+// bounded returns >=lower <upper. The upper bound gets a "0" pre-release so
+// pre-releases of the next version are excluded too.
+func bounded(lower, upper Version) []comparator {
+	upper.Prerelease = []string{"0"}
+	return []comparator{{op: ">=", v: lower}, {op: "<", v: upper}}
+}
+
+// parsePartial parses a version that may omit MINOR and PATCH, returning how
+// many numeric parts were given.
+func parsePartial(s string) (Version, int, error) {
+	v, err := parseLoose(s)
+	if err != nil {
+		return v, 0, err
+	}
+	core := strings.TrimPrefix(s, "v")
+	if i := strings.IndexAny(core, "-+"); i >= 0 {
+		core = core[:i]
+	}
+	parts := strings.Count(core, ".") + 1
+	if parts < 3 && (v.IsPrerelease() || len(v.Build) > 0) {
+		return v, 0, fmt.Errorf("invalid version %q: pre-release needs MAJOR.MINOR.PATCH", s)
+	}
+	return v, parts, nil
+}
@@ -0,0 +1,56 @@
+package version
+
+import "testing"
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{">=1.4.0 <2.0.0", "1.4.0", true},
+		{">=1.4.0 <2.0.0", "1.9.9", true},
+		{">=1.4.0 <2.0.0", "2.0.0", false},
+		{">=1.4.0, <2.0.0", "1.3.9", false},
+		{">= 1.4.0", "1.5.0", true},
+		{"~1.5", "1.5.7", true},
+		{"~1.5", "1.6.0", false},
+		{"~1.5.3", "1.5.2", false},
+		{"~1", "1.9.0", true},
+		{"^1.2.3", "1.9.0", true},
+		{"^1.2.3", "2.0.0", false},
+		{"^0.2.3", "0.2.9", true},
+		{"^0.2.3", "0.3.0", false},
+		{"^0.0.3", "0.0.4", false},
+		{"1.2", "1.2.5", true},
+		{"1.2.3", "1.2.3+build.1", true},
+		{"!=1.2.3", "1.2.3", false},
+		{"~1.5 || ^2.1", "2.4.0", true},
+		{"~1.5 || ^2.1", "2.0.0", false},
+		{"*", "0.0.1", true},
+
+		// Pre-releases only match when a comparator names one of the same version.
+		{">=1.4.0", "2.0.0-rc.1", false},
+		{"~1.5", "1.6.0-rc.1", false},
+		{">=2.0.0-rc.1", "2.0.0-rc.2", true},
+		{">=2.0.0-rc.1", "2.1.0-rc.1", false},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Fatalf("ParseConstraint(%q): %v", tt.constraint, err)
+		}
+		if got := c.CheckString(tt.version); got != tt.want {
+			t.Errorf("%q.Check(%q) = %v, want %v", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestParseConstraintErrors(t *testing.T) {
+	for _, bad := range []string{"", ">=", "~1.x", "1.2.3 ||", ">=1.2-rc.1"} {
+		if _, err := ParseConstraint(bad); err == nil {
+			t.Errorf("ParseConstraint(%q) succeeded, want error", bad)
+		}
+	}
+}
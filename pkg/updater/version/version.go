@@ -1,44 +1,218 @@
+// Package version implements Semantic Versioning 2.0.0 parsing, ordering
+// and constraint matching.
 package version
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 )
 
-// Compare compares two semantic version strings (e.g., "1.2.3" vs "1.2.4").
-// Returns 1 if v1 > v2, 0 if equal, -1 if v1 < v2.
-func Compare(v1, v2 string) int {
-	v1 = strings.TrimPrefix(v1, "v")
-	v2 = strings.TrimPrefix(v2, "v")
+// Version is a parsed semantic version. Build metadata is kept for display
+// but ignored for ordering, as the spec requires.
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease []string // dot-separated identifiers after "-"
+	Build      []string // dot-separated identifiers after "+"
+}
+
+// Parse parses a SemVer 2.0.0 string. A leading "v" is accepted.
+func Parse(s string) (Version, error) {
+	return parse(s, false)
+}
+
+// MustParse is like Parse but panics on error.
+func MustParse(s string) Version {
+	v, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// parseLoose also accepts "1" and "1.2", filling the missing parts with 0.
+func parseLoose(s string) (Version, error) {
+	return parse(s, true)
+}
+
+func parse(s string, loose bool) (Version, error) {
+	var v Version
+	orig := s
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if s == "" {
+		return v, fmt.Errorf("invalid version %q: empty", orig)
+	}
+
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		build := s[i+1:]
+		s = s[:i]
+		ids, err := parseIdentifiers(build, false)
+		if err != nil {
+			return v, fmt.Errorf("invalid version %q: build metadata: %w", orig, err)
+		}
+		v.Build = ids
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		pre := s[i+1:]
+		s = s[:i]
+		ids, err := parseIdentifiers(pre, true)
+		if err != nil {
+			return v, fmt.Errorf("invalid version %q: pre-release: %w", orig, err)
+		}
+		v.Prerelease = ids
+	}
 
-	parts1 := strings.Split(v1, ".")
-	parts2 := strings.Split(v2, ".")
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 || (!loose && len(parts) != 3) {
+		return v, fmt.Errorf("invalid version %q: want MAJOR.MINOR.PATCH", orig)
+	}
+	nums := make([]uint64, 3)
+	for i, p := range parts {
+		n, err := parseNumeric(p)
+		if err != nil {
+			return v, fmt.Errorf("invalid version %q: %w", orig, err)
+		}
+		nums[i] = n
+	}
+	v.Major, v.Minor, v.Patch = nums[0], nums[1], nums[2]
+	return v, nil
+}
 
-	maxLen := len(parts1)
-	if len(parts2) > maxLen {
-		maxLen = len(parts2)
+func parseNumeric(s string) (uint64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty numeric part")
 	}
+	if len(s) > 1 && s[0] == '0' {
+		return 0, fmt.Errorf("numeric part %q has a leading zero", s)
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("numeric part %q is not a number", s)
+	}
+	return n, nil
+}
 
-	for i := 0; i < maxLen; i++ {
-		var num1, num2 int
-		if i < len(parts1) {
-			numStr := strings.Split(parts1[i], "-")[0]
-			num1, _ = strconv.Atoi(numStr)
+func parseIdentifiers(s string, prerelease bool) ([]string, error) {
+	ids := strings.Split(s, ".")
+	for _, id := range ids {
+		if id == "" {
+			return nil, fmt.Errorf("empty identifier")
 		}
-		if i < len(parts2) {
-			numStr := strings.Split(parts2[i], "-")[0]
-			num2, _ = strconv.Atoi(numStr)
+		for _, c := range id {
+			if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '-') {
+				return nil, fmt.Errorf("identifier %q contains %q", id, c)
+			}
 		}
-		if num1 > num2 {
-			return 1
+		if prerelease && isNumeric(id) && len(id) > 1 && id[0] == '0' {
+			return nil, fmt.Errorf("numeric identifier %q has a leading zero", id)
+		}
+	}
+	return ids, nil
+}
+
+func isNumeric(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
 		}
-		if num1 < num2 {
-			return -1
+	}
+	return s != ""
+}
+
+// String returns the canonical form without a "v" prefix.
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	if len(v.Build) > 0 {
+		s += "+" + strings.Join(v.Build, ".")
+	}
+	return s
+}
+
+// IsPrerelease reports whether v has pre-release identifiers.
+func (v Version) IsPrerelease() bool {
+	return len(v.Prerelease) > 0
+}
+
+// Compare returns -1, 0 or 1 by SemVer precedence. Build metadata is ignored.
+func (v Version) Compare(o Version) int {
+	if c := cmpUint(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := cmpUint(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := cmpUint(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+
+	// A version without pre-release has higher precedence than one with.
+	switch {
+	case len(v.Prerelease) == 0 && len(o.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(o.Prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.Prerelease) && i < len(o.Prerelease); i++ {
+		if c := cmpIdentifier(v.Prerelease[i], o.Prerelease[i]); c != 0 {
+			return c
 		}
 	}
+	return cmpUint(uint64(len(v.Prerelease)), uint64(len(o.Prerelease)))
+}
+
+// cmpIdentifier orders numeric identifiers numerically and below
+// alphanumeric ones, which are ordered lexically in ASCII.
+func cmpIdentifier(a, b string) int {
+	an, bn := isNumeric(a), isNumeric(b)
+	switch {
+	case an && bn:
+		x, _ := strconv.ParseUint(a, 10, 64)
+		y, _ := strconv.ParseUint(b, 10, 64)
+		return cmpUint(x, y)
+	case an:
+		return -1
+	case bn:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func cmpUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
 	return 0
 }
 
+// Compare compares two version strings by SemVer precedence (e.g. "1.2.3"
+// vs "1.2.4-rc.1"). Returns 1 if v1 > v2, 0 if equal, -1 if v1 < v2.
+// "1.2" is read as "1.2.0". Strings that are not versions sort below all
+// versions and are compared lexically among themselves.
+func Compare(v1, v2 string) int {
+	a, errA := parseLoose(v1)
+	b, errB := parseLoose(v2)
+	switch {
+	case errA == nil && errB == nil:
+		return a.Compare(b)
+	case errA == nil:
+		return 1
+	case errB == nil:
+		return -1
+	}
+	return strings.Compare(v1, v2)
+}
+
 // IsNewer returns true if newVersion is newer than currentVersion.
 func IsNewer(newVersion, currentVersion string) bool {
 	return Compare(newVersion, currentVersion) > 0
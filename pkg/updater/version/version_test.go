@@ -14,7 +14,16 @@ func TestCompare(t *testing.T) {
 		{"v1.0.0", "1.0.0", 0},
 		{"v1.1.0", "v1.0.0", 1},
 		{"1.0", "1.0.0", 0},
-		{"1.0.0-beta", "1.0.0", 0},
+		{"1.0.0-beta", "1.0.0", -1},
+		{"1.2.0-rc.1", "1.2.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-alpha.beta", "1.0.0-beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-beta.11", "1.0.0-rc.1", -1},
+		{"1.0.0+build.5", "1.0.0+build.9", 0},
+		{"1.10.0", "1.9.0", 1},
+		{"dev", "0.0.1", -1},
 	}
 
 	for _, tt := range tests {
@@ -36,3 +45,19 @@ func TestIsNewer(t *testing.T) {
 		t.Error("0.9.0 should not be newer than 1.0.0")
 	}
 }
+
+func TestParse(t *testing.T) {
+	v, err := Parse("v1.2.3-rc.1+sha.abc")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if v.Major != 1 || v.Minor != 2 || v.Patch != 3 || v.String() != "1.2.3-rc.1+sha.abc" {
+		t.Errorf("Parse = %+v (%s)", v, v)
+	}
+
+	for _, bad := range []string{"", "1.2", "1.2.3.4", "01.2.3", "1.2.3-", "1.2.3-01", "1.2.3+", "1.2.x", "dev", "1.2.3-a_b"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", bad)
+		}
+	}
+}